@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/go-tools-agent/internal/config"
+	"github.com/go-tools-agent/internal/redact"
+	"github.com/go-tools-agent/internal/tools/docsearch"
+)
+
+func main() {
+	dir := flag.String("dir", "", "directory of documents to index (default docsearch.dir)")
+	indexPath := flag.String("index", "", "index file to write (default docsearch.index)")
+	rebuild := flag.Bool("rebuild", false, "extract and embed every document again instead of reusing unchanged ones")
+	flag.Parse()
+
+	toolsConfig, err := config.LoadToolsConfig()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	cfg := toolsConfig.DocSearch
+	if *dir != "" {
+		cfg.Dir = *dir
+	}
+	if *indexPath != "" {
+		cfg.Index = *indexPath
+	}
+	if cfg.Dir == "" || cfg.Index == "" {
+		log.Fatalf("Set docsearch.dir and docsearch.index in the tools config, or pass -dir and -index")
+	}
+
+	log.SetOutput(redact.New(docsearch.Secrets(cfg)...).Writer(os.Stderr))
+
+	embedder, err := docsearch.NewEmbedder(cfg.Embedder)
+	if err != nil {
+		log.Fatalf("Failed to create embedder: %v", err)
+	}
+
+	// Unchanged documents keep their chunks from the previous index
+	var previous *docsearch.Index
+	if !*rebuild {
+		previous, err = docsearch.LoadIndex(cfg.Index)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("⚠️  Ignoring the previous index: %v", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	start := time.Now()
+	index, stats, err := docsearch.Build(ctx, cfg, embedder, previous)
+	if err != nil {
+		log.Fatalf("Failed to index %s: %v", cfg.Dir, err)
+	}
+	if err := index.Save(cfg.Index); err != nil {
+		log.Fatalf("Failed to save index: %v", err)
+	}
+	log.Printf("📚 Indexed %d documents (%d unchanged) into %d chunks in %s, %d skipped; wrote %s",
+		stats.Documents, stats.Unchanged, stats.Chunks, time.Since(start).Round(time.Millisecond), len(stats.Skipped), cfg.Index)
+}
@@ -5,26 +5,37 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"os"
 	"time"
 
 	"github.com/go-tools-agent/internal/agent"
 	"github.com/go-tools-agent/internal/config"
+	"github.com/go-tools-agent/internal/mcp"
 	"github.com/go-tools-agent/internal/memory"
 	"github.com/go-tools-agent/internal/parser"
-	calculator "github.com/go-tools-agent/internal/tools/calculator"
-	"github.com/go-tools-agent/internal/tools/code"
-	"github.com/go-tools-agent/internal/tools/http"
-	"github.com/go-tools-agent/internal/tools/wikipedia"
+	"github.com/go-tools-agent/internal/redact"
+	"github.com/go-tools-agent/internal/sandbox"
+	"github.com/go-tools-agent/internal/tools/data"
+	"github.com/go-tools-agent/internal/toolset"
 	"github.com/sashabaranov/go-openai"
 )
 
 func main() {
+	// Must run first: sandboxed code execution and jq queries re-execute
+	// this binary
+	sandbox.Init()
+	data.Init()
+
 	// Load configuration
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	// Keep credential secrets out of logs, steps and model context
+	redactor := redact.New(toolset.Secrets(cfg.Tools)...)
+	log.SetOutput(redactor.Writer(os.Stderr))
+
 	// Initialize OpenAI client
 	client := openai.NewClient(cfg.OpenAIAPIKey)
 
@@ -44,51 +55,30 @@ func main() {
 	}
 	parser := parser.NewJSONOutputParser(outputSchema)
 
-	// Create tools
-	var tools []agent.Tool
-
-	// Add calculator tool
-	name, desc, schema, handler := calculator.NewCalculatorTool()
-	tools = append(tools, agent.Tool{
-		Name:        name,
-		Description: desc,
-		Schema:      schema,
-		Handler:     handler,
-	})
-
-	// Add HTTP request tool
-	name, desc, schema, handler = http.NewHTTPRequestTool()
-	tools = append(tools, agent.Tool{
-		Name:        name,
-		Description: desc,
-		Schema:      schema,
-		Handler:     handler,
-	})
-
-	// Add Wikipedia tool
-	name, desc, schema, handler = wikipedia.NewWikipediaTool()
-	tools = append(tools, agent.Tool{
-		Name:        name,
-		Description: desc,
-		Schema:      schema,
-		Handler:     handler,
-	})
-
-	// Add code execution tool
-	name, desc, schema, handler = code.NewCodeExecutionTool()
-	tools = append(tools, agent.Tool{
-		Name:        name,
-		Description: desc,
-		Schema:      schema,
-		Handler:     handler,
-	})
+	// Create tools. Artifacts are only collected by the HTTP server, which
+	// can serve them
+	tools, err := toolset.Build(cfg.Tools, nil)
+	if err != nil {
+		log.Fatalf("Failed to create tools: %v", err)
+	}
+	defer tools.Close()
+
+	// Import tools from the configured MCP servers. Servers that are slow
+	// to start keep connecting in the background.
+	startCtx, cancelStart := context.WithTimeout(context.Background(), 30*time.Second)
+	mcpServers := mcp.Start(startCtx, cfg.Tools.MCP)
+	cancelStart()
+	defer mcpServers.Close()
 
 	// Configure the agent
 	agentConfig := agent.AgentConfig{
 		SystemMessage:           "You are a helpful assistant that can perform calculations, make HTTP requests, search Wikipedia, and execute code.",
 		MaxIterations:           5,
 		ReturnIntermediateSteps: true,
-		Tools:                   tools,
+		Tools:                   tools.Tools,
+		DynamicTools:            mcpServers.Tools,
+		RunEndHooks:             []func(runID string){tools.Sessions.EndRun},
+		Redact:                  redactor.String,
 	}
 
 	// Create the agent
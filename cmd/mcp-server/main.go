@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/go-tools-agent/internal/config"
+	"github.com/go-tools-agent/internal/mcp"
+	"github.com/go-tools-agent/internal/redact"
+	"github.com/go-tools-agent/internal/sandbox"
+	"github.com/go-tools-agent/internal/tools/data"
+	"github.com/go-tools-agent/internal/toolset"
+)
+
+func main() {
+	// Must run first: sandboxed code execution and jq queries re-execute
+	// this binary
+	sandbox.Init()
+	data.Init()
+
+	transport := flag.String("transport", "stdio", "MCP transport: stdio or http")
+	addr := flag.String("addr", "127.0.0.1:8090", "listen address for the http transport")
+	path := flag.String("path", "/mcp", "endpoint path for the http transport")
+	callTimeout := flag.Duration("call-timeout", mcp.DefaultCallTimeout, "longest a tool call may run")
+	flag.Parse()
+
+	// Only tool settings are needed; the agent and its model are not used
+	toolsConfig, err := config.LoadToolsConfig()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// Keep credential secrets out of logs and tool results. Logs go to
+	// stderr, since stdout carries the protocol on the stdio transport.
+	redactor := redact.New(toolset.Secrets(toolsConfig)...)
+	log.SetOutput(redactor.Writer(os.Stderr))
+
+	// Create tools; code execution sessions are scoped to each MCP session.
+	// Artifacts are only collected by the HTTP API server
+	tools, err := toolset.Build(toolsConfig, nil)
+	if err != nil {
+		log.Fatalf("Failed to create tools: %v", err)
+	}
+	defer tools.Close()
+
+	server := mcp.NewServer(tools.Tools, mcp.ServerOptions{
+		Info:               mcp.Implementation{Name: "go-tools-agent", Version: "1.0"},
+		Redact:             redactor.String,
+		SessionEndHooks:    []func(sessionID string){tools.Sessions.EndRun},
+		SessionIdleTimeout: 30 * time.Minute,
+		CallTimeout:        *callTimeout,
+		BearerToken:        os.Getenv("MCP_SERVER_TOKEN"),
+	})
+	defer server.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	switch *transport {
+	case "stdio":
+		log.Printf("🔌 MCP server serving %d tools on stdio", len(tools.Tools))
+		if err := server.ServeStdio(ctx, os.Stdin, os.Stdout); err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("MCP server failed: %v", err)
+		}
+
+	case "http":
+		mux := http.NewServeMux()
+		mux.Handle(*path, server)
+		httpServer := &http.Server{Addr: *addr, Handler: mux}
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			httpServer.Shutdown(shutdownCtx)
+		}()
+		log.Printf("🔌 MCP server serving %d tools on http://%s%s", len(tools.Tools), *addr, *path)
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("MCP server failed: %v", err)
+		}
+
+	default:
+		log.Fatalf("Unknown transport %q: use stdio or http", *transport)
+	}
+}
@@ -5,16 +5,19 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/go-tools-agent/internal/agent"
+	"github.com/go-tools-agent/internal/artifacts"
 	"github.com/go-tools-agent/internal/config"
+	"github.com/go-tools-agent/internal/mcp"
 	"github.com/go-tools-agent/internal/memory"
 	"github.com/go-tools-agent/internal/parser"
-	calculator "github.com/go-tools-agent/internal/tools/calculator"
-	"github.com/go-tools-agent/internal/tools/code"
-	httpTool "github.com/go-tools-agent/internal/tools/http"
-	"github.com/go-tools-agent/internal/tools/wikipedia"
+	"github.com/go-tools-agent/internal/redact"
+	"github.com/go-tools-agent/internal/sandbox"
+	"github.com/go-tools-agent/internal/tools/data"
+	"github.com/go-tools-agent/internal/toolset"
 	"github.com/sashabaranov/go-openai"
 	httpSwagger "github.com/swaggo/http-swagger"
 )
@@ -60,12 +63,21 @@ func (w *logWriter) Write(p []byte) (n int, err error) {
 }
 
 func main() {
+	// Must run first: sandboxed code execution and jq queries re-execute
+	// this binary
+	sandbox.Init()
+	data.Init()
+
 	// Load configuration
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	// Keep credential secrets out of logs, steps and model context
+	redactor := redact.New(toolset.Secrets(cfg.Tools)...)
+	log.SetOutput(redactor.Writer(os.Stderr))
+
 	// Initialize OpenAI client
 	client := openai.NewClient(cfg.OpenAIAPIKey)
 
@@ -85,51 +97,31 @@ func main() {
 	}
 	parser := parser.NewJSONOutputParser(outputSchema)
 
-	// Create tools
-	var tools []agent.Tool
-
-	// Add calculator tool
-	name, desc, schema, handler := calculator.NewCalculatorTool()
-	tools = append(tools, agent.Tool{
-		Name:        name,
-		Description: desc,
-		Schema:      schema,
-		Handler:     handler,
-	})
-
-	// Add HTTP request tool
-	name, desc, schema, handler = httpTool.NewHTTPRequestTool()
-	tools = append(tools, agent.Tool{
-		Name:        name,
-		Description: desc,
-		Schema:      schema,
-		Handler:     handler,
-	})
-
-	// Add Wikipedia tool
-	name, desc, schema, handler = wikipedia.NewWikipediaTool()
-	tools = append(tools, agent.Tool{
-		Name:        name,
-		Description: desc,
-		Schema:      schema,
-		Handler:     handler,
-	})
+	// Create tools. Files the code execution tool writes are kept as
+	// artifacts served under /artifacts/
+	artifactStore := artifacts.NewStore(cfg.Tools.Artifacts)
+	tools, err := toolset.Build(cfg.Tools, artifactStore)
+	if err != nil {
+		log.Fatalf("Failed to create tools: %v", err)
+	}
+	defer tools.Close()
 
-	// Add code execution tool
-	name, desc, schema, handler = code.NewCodeExecutionTool()
-	tools = append(tools, agent.Tool{
-		Name:        name,
-		Description: desc,
-		Schema:      schema,
-		Handler:     handler,
-	})
+	// Import tools from the configured MCP servers. Servers that are slow
+	// to start keep connecting in the background.
+	startCtx, cancelStart := context.WithTimeout(context.Background(), 30*time.Second)
+	mcpServers := mcp.Start(startCtx, cfg.Tools.MCP)
+	cancelStart()
+	defer mcpServers.Close()
 
 	// Configure the agent
 	agentConfig := agent.AgentConfig{
 		SystemMessage:           cfg.SystemMessage,
 		MaxIterations:           cfg.MaxIterations,
 		ReturnIntermediateSteps: true,
-		Tools:                   tools,
+		Tools:                   tools.Tools,
+		DynamicTools:            mcpServers.Tools,
+		RunEndHooks:             []func(runID string){tools.Sessions.EndRun},
+		Redact:                  redactor.String,
 	}
 
 	// Create the agent
@@ -143,6 +135,9 @@ func main() {
 		httpSwagger.URL("/swagger/doc.json"),
 	))
 
+	// Serve artifacts produced during runs
+	http.Handle("/artifacts/", artifactStore)
+
 	// Create HTTP handler for execute endpoint
 	http.HandleFunc("/execute", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -162,12 +157,11 @@ func main() {
 
 		// Set up debug logging if requested
 		var logCapture *logWriter
-		var originalOutput *log.Logger
 		if req.Debug {
 			logCapture = &logWriter{}
-			originalOutput = log.Default()
-			log.SetOutput(logCapture)
-			defer log.SetOutput(originalOutput.Writer())
+			originalOutput := log.Writer()
+			log.SetOutput(redactor.Writer(logCapture))
+			defer log.SetOutput(originalOutput)
 		}
 
 		// Execute the agent
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/sashabaranov/go-openai"
@@ -16,6 +17,9 @@ type ToolsAgent struct {
 	client *openai.Client
 	memory Memory
 	parser OutputParser
+
+	// skipped holds the dynamic tool names already reported as taken
+	skipped sync.Map
 }
 
 // NewToolsAgent creates a new instance of ToolsAgent
@@ -32,6 +36,11 @@ func NewToolsAgent(config AgentConfig, client *openai.Client, memory Memory, par
 func (a *ToolsAgent) Execute(ctx context.Context, input string) (*AgentResponse, error) {
 	log.Printf("\n🤖 Agent received input: %s\n", input)
 
+	// Tag the context with a run ID so tools can scope state to this run
+	runID := newRunID()
+	ctx = WithRunID(ctx, runID)
+	defer a.endRun(runID)
+
 	var steps []AgentStep
 	var finalOutput json.RawMessage
 
@@ -82,8 +91,9 @@ func (a *ToolsAgent) Execute(ctx context.Context, input string) (*AgentResponse,
 		}
 
 		// Prepare tool choices for the model
-		tools := make([]openai.Tool, len(a.config.Tools))
-		for i, tool := range a.config.Tools {
+		available := a.tools()
+		tools := make([]openai.Tool, len(available))
+		for i, tool := range available {
 			tools[i] = openai.Tool{
 				Type: openai.ToolTypeFunction,
 				Function: openai.FunctionDefinition{
@@ -116,24 +126,26 @@ func (a *ToolsAgent) Execute(ctx context.Context, input string) (*AgentResponse,
 		if len(resp.Choices) > 0 && len(resp.Choices[0].Message.ToolCalls) > 0 {
 			log.Printf("\n🛠️  Model selected tools to use:")
 			for _, toolCall := range resp.Choices[0].Message.ToolCalls {
+				arguments := a.redact(toolCall.Function.Arguments)
 				log.Printf("  - Tool: %s", toolCall.Function.Name)
-				log.Printf("    Arguments: %s\n", toolCall.Function.Arguments)
+				log.Printf("    Arguments: %s\n", arguments)
 
 				step := AgentStep{
 					Action:    toolCall.Function.Name,
-					Input:     json.RawMessage(toolCall.Function.Arguments),
+					Input:     json.RawMessage(arguments),
 					Timestamp: time.Now().Unix(),
 				}
 
 				// Find and execute the tool
 				var toolOutput json.RawMessage
-				for _, tool := range a.config.Tools {
+				for _, tool := range available {
 					if tool.Name == toolCall.Function.Name {
-						output, err := tool.Handler(ctx, step.Input)
+						output, err := tool.Handler(ctx, json.RawMessage(toolCall.Function.Arguments))
 						if err != nil {
-							step.Error = err.Error()
-							log.Printf("❌ Tool execution failed: %s\n", err)
+							step.Error = a.redact(err.Error())
+							log.Printf("❌ Tool execution failed: %s\n", step.Error)
 						} else {
+							output = json.RawMessage(a.redact(string(output)))
 							step.Output = output
 							toolOutput = output
 							log.Printf("✅ Tool output: %s\n", string(output))
@@ -206,6 +218,7 @@ func (a *ToolsAgent) Execute(ctx context.Context, input string) (*AgentResponse,
 	}
 
 	response := &AgentResponse{
+		RunID:       runID,
 		FinalOutput: finalOutput,
 	}
 
@@ -215,3 +228,43 @@ func (a *ToolsAgent) Execute(ctx context.Context, input string) (*AgentResponse,
 
 	return response, nil
 }
+
+// tools returns the static tools followed by the current dynamic ones.
+// Dynamic tools whose name is taken are left out, since the model API
+// rejects a request with duplicate function names.
+func (a *ToolsAgent) tools() []Tool {
+	if a.config.DynamicTools == nil {
+		return a.config.Tools
+	}
+	tools := append([]Tool(nil), a.config.Tools...)
+	names := make(map[string]bool, len(tools))
+	for _, tool := range tools {
+		names[tool.Name] = true
+	}
+	for _, tool := range a.config.DynamicTools() {
+		if names[tool.Name] {
+			if _, reported := a.skipped.LoadOrStore(tool.Name, true); !reported {
+				log.Printf("⚠️  Skipping dynamic tool %s: the name is already used by another tool", tool.Name)
+			}
+			continue
+		}
+		names[tool.Name] = true
+		tools = append(tools, tool)
+	}
+	return tools
+}
+
+// redact removes configured secrets from s
+func (a *ToolsAgent) redact(s string) string {
+	if a.config.Redact == nil {
+		return s
+	}
+	return a.config.Redact(s)
+}
+
+// endRun notifies the configured hooks that a run has finished
+func (a *ToolsAgent) endRun(runID string) {
+	for _, hook := range a.config.RunEndHooks {
+		hook(runID)
+	}
+}
@@ -0,0 +1,31 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// runIDKey is the context key for the current run ID
+type runIDKey struct{}
+
+// WithRunID returns a context carrying the ID of the current agent run
+func WithRunID(ctx context.Context, runID string) context.Context {
+	return context.WithValue(ctx, runIDKey{}, runID)
+}
+
+// RunIDFromContext returns the ID of the agent run ctx belongs to, or an
+// empty string when a tool is called outside of a run
+func RunIDFromContext(ctx context.Context) string {
+	runID, _ := ctx.Value(runIDKey{}).(string)
+	return runID
+}
+
+// newRunID generates a random identifier for a single Execute call
+func newRunID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -3,6 +3,7 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"time"
 )
 
 // Tool represents a callable function that the agent can use
@@ -11,6 +12,12 @@ type Tool struct {
 	Description string          `json:"description"`
 	Schema      json.RawMessage `json:"schema"`
 	Handler     ToolHandler
+	// Cacheable marks tools without side effects whose output depends only
+	// on their input, so repeated calls may be answered from a cache
+	Cacheable bool `json:"cacheable,omitempty"`
+	// CacheTTL optionally decides per call whether an output may be cached
+	// and for how long; a zero duration means the cache's default TTL
+	CacheTTL func(input, output json.RawMessage) (time.Duration, bool)
 }
 
 // ToolHandler is a function that executes a tool's functionality
@@ -19,9 +26,19 @@ type ToolHandler func(ctx context.Context, input json.RawMessage) (json.RawMessa
 // AgentConfig holds the configuration for the Tools Agent
 type AgentConfig struct {
 	SystemMessage           string
-	MaxIterations          int
+	MaxIterations           int
 	ReturnIntermediateSteps bool
-	Tools                  []Tool
+	Tools                   []Tool
+	// DynamicTools, if set, is called before every model request and its
+	// tools are offered alongside Tools. It serves tools that can change
+	// while the agent runs, such as those imported from MCP servers.
+	DynamicTools func() []Tool
+	// RunEndHooks are called with the run ID when Execute returns, so tools
+	// can release resources scoped to a run
+	RunEndHooks []func(runID string)
+	// Redact, if set, removes secrets from tool arguments, outputs and
+	// errors before they are logged, recorded in steps or sent to the model
+	Redact func(string) string
 }
 
 // AgentStep represents a single step in the agent's execution
@@ -35,6 +52,7 @@ type AgentStep struct {
 
 // AgentResponse represents the final response from the agent
 type AgentResponse struct {
+	RunID       string          `json:"run_id,omitempty"`
 	FinalOutput json.RawMessage `json:"final_output"`
 	Steps       []AgentStep     `json:"steps,omitempty"`
 	Error       string          `json:"error,omitempty"`
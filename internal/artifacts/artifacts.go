@@ -0,0 +1,218 @@
+// Package artifacts stores files produced by tools during an agent run so
+// they can be downloaded through the HTTP API after the run completes.
+package artifacts
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config holds the artifact store limits
+type Config struct {
+	// TTLSeconds is how long an artifact is kept after it was created
+	TTLSeconds int `json:"ttlSeconds"`
+	// MaxTotalBytes caps the memory used by all stored artifacts; the
+	// oldest artifacts are evicted first
+	MaxTotalBytes int64 `json:"maxTotalBytes"`
+}
+
+// DefaultConfig returns the default artifact store limits
+func DefaultConfig() Config {
+	return Config{
+		TTLSeconds:    3600,
+		MaxTotalBytes: 256 << 20,
+	}
+}
+
+// Artifact describes a stored file. It is what tools return to the model and
+// what the HTTP API lists; the content is only available through the store.
+type Artifact struct {
+	ID        string    `json:"id"`
+	RunID     string    `json:"runId"`
+	Name      string    `json:"name"`
+	MIMEType  string    `json:"mimeType"`
+	Size      int64     `json:"size"`
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type entry struct {
+	Artifact
+	data []byte
+}
+
+// Store is an in-memory artifact store keyed by run ID
+type Store struct {
+	config Config
+
+	mu    sync.Mutex
+	runs  map[string][]*entry
+	total int64
+}
+
+// NewStore creates an empty artifact store
+func NewStore(config Config) *Store {
+	return &Store{
+		config: config,
+		runs:   make(map[string][]*entry),
+	}
+}
+
+// defaultRunID groups artifacts created outside of an agent run
+const defaultRunID = "default"
+
+// Put stores data as an artifact of runID and returns its description
+func (s *Store) Put(runID, name, mimeType string, data []byte) (*Artifact, error) {
+	if runID == "" {
+		runID = defaultRunID
+	}
+	size := int64(len(data))
+	if size > s.config.MaxTotalBytes {
+		return nil, fmt.Errorf("artifact %s is %d bytes, larger than the store limit of %d bytes", name, size, s.config.MaxTotalBytes)
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	e := &entry{
+		Artifact: Artifact{
+			ID:        id,
+			RunID:     runID,
+			Name:      name,
+			MIMEType:  mimeType,
+			Size:      size,
+			URL:       fmt.Sprintf("/artifacts/%s/%s", runID, id),
+			CreatedAt: time.Now(),
+		},
+		data: append([]byte(nil), data...),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.evictExpired()
+	for s.total+size > s.config.MaxTotalBytes {
+		s.evictOldest()
+	}
+	s.runs[runID] = append(s.runs[runID], e)
+	s.total += size
+
+	artifact := e.Artifact
+	return &artifact, nil
+}
+
+// List returns the artifacts of a run in creation order
+func (s *Store) List(runID string) []Artifact {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.evictExpired()
+	list := make([]Artifact, 0, len(s.runs[runID]))
+	for _, e := range s.runs[runID] {
+		list = append(list, e.Artifact)
+	}
+	return list
+}
+
+// Get returns an artifact and its content
+func (s *Store) Get(runID, id string) (*Artifact, []byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.evictExpired()
+	for _, e := range s.runs[runID] {
+		if e.ID == id {
+			artifact := e.Artifact
+			return &artifact, e.data, true
+		}
+	}
+	return nil, nil, false
+}
+
+func (s *Store) evictExpired() {
+	cutoff := time.Now().Add(-time.Duration(s.config.TTLSeconds) * time.Second)
+	for runID, entries := range s.runs {
+		kept := entries[:0]
+		for _, e := range entries {
+			if e.CreatedAt.After(cutoff) {
+				kept = append(kept, e)
+			} else {
+				s.total -= e.Size
+			}
+		}
+		if len(kept) == 0 {
+			delete(s.runs, runID)
+		} else {
+			s.runs[runID] = kept
+		}
+	}
+}
+
+func (s *Store) evictOldest() {
+	var oldestRun string
+	var oldest *entry
+	for runID, entries := range s.runs {
+		if len(entries) > 0 && (oldest == nil || entries[0].CreatedAt.Before(oldest.CreatedAt)) {
+			oldestRun, oldest = runID, entries[0]
+		}
+	}
+	if oldest == nil {
+		return
+	}
+	s.total -= oldest.Size
+	s.runs[oldestRun] = s.runs[oldestRun][1:]
+	if len(s.runs[oldestRun]) == 0 {
+		delete(s.runs, oldestRun)
+	}
+}
+
+// ServeHTTP serves GET /artifacts/{runID} (a JSON list) and
+// GET /artifacts/{runID}/{artifactID} (the file content)
+func (s *Store) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/artifacts/"), "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] != "":
+		list := s.List(parts[0])
+		sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"artifacts": list})
+
+	case len(parts) == 2:
+		artifact, data, ok := s.Get(parts[0], parts[1])
+		if !ok {
+			http.Error(w, "Artifact not found", http.StatusNotFound)
+			return
+		}
+		// Artifacts are written by untrusted code; browsers must use the
+		// stored type rather than sniff one and render the file as HTML
+		w.Header().Set("Content-Type", artifact.MIMEType)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", artifact.Name))
+		w.Write(data)
+
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate artifact ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,173 @@
+// Package cache answers repeated calls of read-only tools from a cache
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-tools-agent/internal/agent"
+)
+
+// Backend types
+const (
+	BackendMemory = "memory"
+	BackendDisk   = "disk"
+)
+
+// Config holds the settings for the tool cache
+type Config struct {
+	// Enabled turns caching of cacheable tools on
+	Enabled bool `json:"enabled"`
+	// Backend is "memory" or "disk"
+	Backend string `json:"backend"`
+	// Dir is where the disk backend keeps its entries
+	Dir string `json:"dir"`
+	// TTLSeconds is how long outputs are kept unless the tool says otherwise
+	TTLSeconds int `json:"ttlSeconds"`
+	// MaxEntries caps the number of entries; the least recently used are
+	// evicted first
+	MaxEntries int `json:"maxEntries"`
+}
+
+// DefaultConfig caches in memory for five minutes
+func DefaultConfig() Config {
+	return Config{
+		Enabled:    true,
+		Backend:    BackendMemory,
+		TTLSeconds: 300,
+		MaxEntries: 1000,
+	}
+}
+
+// Validate checks the backend and limits
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.TTLSeconds <= 0 || c.MaxEntries <= 0 {
+		return fmt.Errorf("ttlSeconds and maxEntries must be positive")
+	}
+	switch c.Backend {
+	case BackendMemory:
+	case BackendDisk:
+		if c.Dir == "" {
+			return fmt.Errorf("the disk backend needs a dir")
+		}
+	default:
+		return fmt.Errorf("unknown backend %q", c.Backend)
+	}
+	return nil
+}
+
+// Entry is a cached tool output
+type Entry struct {
+	Key     string          `json:"key"`
+	Output  json.RawMessage `json:"output"`
+	Expires time.Time       `json:"expires"`
+}
+
+// Backend stores entries. Implementations must be safe for concurrent use
+// and evict the least recently used entries beyond their capacity.
+type Backend interface {
+	Get(key string) (Entry, bool)
+	Set(entry Entry) error
+}
+
+// Cache stores tool outputs in a backend
+type Cache struct {
+	backend Backend
+	ttl     time.Duration
+}
+
+// New creates the cache described by config. It returns nil when caching
+// is disabled; a nil cache leaves tools unchanged.
+func New(config Config) (*Cache, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+	var backend Backend
+	switch config.Backend {
+	case BackendDisk:
+		disk, err := NewDiskBackend(config.Dir, config.MaxEntries)
+		if err != nil {
+			return nil, err
+		}
+		backend = disk
+	default:
+		backend = NewMemoryBackend(config.MaxEntries)
+	}
+	return &Cache{backend: backend, ttl: time.Duration(config.TTLSeconds) * time.Second}, nil
+}
+
+// Wrap returns tool with its handler served from the cache where possible.
+// Tools that are not cacheable are returned unchanged. Outputs that are
+// JSON objects get a "cache" field set to "hit" or "miss".
+func (c *Cache) Wrap(tool agent.Tool) agent.Tool {
+	if c == nil || !tool.Cacheable {
+		return tool
+	}
+
+	handler := tool.Handler
+	tool.Handler = func(ctx context.Context, input json.RawMessage) (json.RawMessage, error) {
+		key, err := cacheKey(tool.Name, input)
+		if err != nil {
+			// Let the tool report the invalid input
+			return handler(ctx, input)
+		}
+		if entry, ok := c.backend.Get(key); ok && time.Now().Before(entry.Expires) {
+			return annotate(entry.Output, "hit"), nil
+		}
+
+		output, err := handler(ctx, input)
+		if err != nil {
+			return output, err
+		}
+
+		ttl, ok := c.ttl, true
+		if tool.CacheTTL != nil {
+			var custom time.Duration
+			if custom, ok = tool.CacheTTL(input, output); ok && custom > 0 {
+				ttl = custom
+			}
+		}
+		if ok {
+			// A failing backend only costs the cache, not the call
+			_ = c.backend.Set(Entry{Key: key, Output: output, Expires: time.Now().Add(ttl)})
+		}
+		return annotate(output, "miss"), nil
+	}
+	return tool
+}
+
+// cacheKey identifies a call by tool name and canonical input: the input is
+// re-encoded so that key order and whitespace do not matter
+func cacheKey(name string, input json.RawMessage) (string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(input))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return "", err
+	}
+	canonical, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return name + ":" + string(canonical), nil
+}
+
+// annotate adds the cache status to an output object
+func annotate(output json.RawMessage, status string) json.RawMessage {
+	trimmed := bytes.TrimSpace(output)
+	if len(trimmed) < 2 || trimmed[0] != '{' {
+		return output
+	}
+	rest := bytes.TrimSpace(trimmed[1:])
+	field := `{"cache":"` + status + `"`
+	if rest[0] != '}' {
+		field += ","
+	}
+	return append([]byte(field), rest...)
+}
@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DiskBackend keeps one JSON file per entry in a directory, so the cache
+// survives restarts. A file's modification time records when it was last
+// used, and the least recently used files are removed when full.
+type DiskBackend struct {
+	mu         sync.Mutex
+	dir        string
+	maxEntries int
+	count      int
+}
+
+// NewDiskBackend creates a disk backend in dir holding at most maxEntries
+func NewDiskBackend(dir string, maxEntries int) (*DiskBackend, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create cache dir: %w", err)
+	}
+	b := &DiskBackend{dir: dir, maxEntries: maxEntries}
+	files, err := b.files()
+	if err != nil {
+		return nil, err
+	}
+	b.count = len(files)
+	return b, nil
+}
+
+func (b *DiskBackend) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(b.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get returns the entry for key and marks it as recently used
+func (b *DiskBackend) Get(key string) (Entry, bool) {
+	path := b.path(key)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Entry{}, false
+	}
+	var entry Entry
+	if err := json.Unmarshal(content, &entry); err != nil || entry.Key != key {
+		return Entry{}, false
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return entry, true
+}
+
+// Set writes entry, evicting the least recently used entries when full
+func (b *DiskBackend) Set(entry Entry) error {
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	path := b.path(entry.Key)
+	_, statErr := os.Stat(path)
+
+	// Write to a temporary file first so readers never see a partial entry
+	tmp, err := os.CreateTemp(b.dir, ".entry-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	tmp.Close()
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if os.IsNotExist(statErr) {
+		b.count++
+	}
+	if b.count > b.maxEntries {
+		return b.evict()
+	}
+	return nil
+}
+
+// evict removes the least recently used files until the backend is within
+// its capacity
+func (b *DiskBackend) evict() error {
+	files, err := b.files()
+	if err != nil {
+		return err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	for len(files) > b.maxEntries {
+		os.Remove(files[0].path)
+		files = files[1:]
+	}
+	b.count = len(files)
+	return nil
+}
+
+type cacheFile struct {
+	path    string
+	modTime time.Time
+}
+
+func (b *DiskBackend) files() ([]cacheFile, error) {
+	entries, err := os.ReadDir(b.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache dir: %w", err)
+	}
+	var files []cacheFile
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, cacheFile{path: filepath.Join(b.dir, entry.Name()), modTime: info.ModTime()})
+	}
+	return files, nil
+}
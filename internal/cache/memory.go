@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// MemoryBackend keeps entries in memory, evicting the least recently used
+type MemoryBackend struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List // front is most recently used
+	entries    map[string]*list.Element
+}
+
+// NewMemoryBackend creates a memory backend holding at most maxEntries
+func NewMemoryBackend(maxEntries int) *MemoryBackend {
+	return &MemoryBackend{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// Get returns the entry for key and marks it as recently used
+func (b *MemoryBackend) Get(key string) (Entry, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	element, ok := b.entries[key]
+	if !ok {
+		return Entry{}, false
+	}
+	b.order.MoveToFront(element)
+	return element.Value.(Entry), true
+}
+
+// Set stores entry, evicting the least recently used entries when full
+func (b *MemoryBackend) Set(entry Entry) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if element, ok := b.entries[entry.Key]; ok {
+		element.Value = entry
+		b.order.MoveToFront(element)
+		return nil
+	}
+	b.entries[entry.Key] = b.order.PushFront(entry)
+	for b.order.Len() > b.maxEntries {
+		oldest := b.order.Back()
+		b.order.Remove(oldest)
+		delete(b.entries, oldest.Value.(Entry).Key)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+// Package capped collects output from processes and modules the agent does
+// not control, keeping only as much as it may use.
+package capped
+
+// Buffer keeps the first limit bytes written to it and counts the rest, so
+// a runaway writer can neither exhaust memory nor block on a full pipe
+type Buffer struct {
+	data    []byte
+	limit   int
+	dropped int64
+}
+
+// NewBuffer creates a buffer that keeps at most limit bytes
+func NewBuffer(limit int) *Buffer {
+	return &Buffer{limit: limit}
+}
+
+// Write keeps what fits and discards the rest. It never fails, so the
+// writer is not stopped by a full buffer.
+func (b *Buffer) Write(p []byte) (int, error) {
+	room := max(b.limit-len(b.data), 0)
+	if len(p) <= room {
+		b.data = append(b.data, p...)
+	} else {
+		b.data = append(b.data, p[:room]...)
+		b.dropped += int64(len(p) - room)
+	}
+	return len(p), nil
+}
+
+// Bytes returns the retained output
+func (b *Buffer) Bytes() []byte {
+	return b.data
+}
+
+// String returns the retained output as a string
+func (b *Buffer) String() string {
+	return string(b.data)
+}
+
+// Limit returns the most bytes the buffer keeps
+func (b *Buffer) Limit() int {
+	return b.limit
+}
+
+// Dropped returns the number of bytes discarded
+func (b *Buffer) Dropped() int64 {
+	return b.dropped
+}
+
+// Truncated reports whether any output was discarded
+func (b *Buffer) Truncated() bool {
+	return b.dropped > 0
+}
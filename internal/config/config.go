@@ -13,6 +13,7 @@ type Config struct {
 	OpenAIAPIKey  string
 	SystemMessage string
 	MaxIterations int
+	Tools         ToolsConfig
 }
 
 // LoadConfig loads configuration from environment variables and .env file
@@ -43,13 +44,28 @@ func LoadConfig() (*Config, error) {
 		}
 	}
 
+	// Load structured tool settings if a config file is given
+	tools, err := loadToolsConfig(os.Getenv("TOOLS_CONFIG"))
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
 		OpenAIAPIKey:  apiKey,
 		SystemMessage: systemMessage,
 		MaxIterations: maxIterations,
+		Tools:         tools,
 	}, nil
 }
 
+// LoadToolsConfig loads only the tool settings, for programs that serve
+// the tools without running the agent and so need no OpenAI API key. A
+// missing .env file is ignored silently, since stdout may carry a protocol.
+func LoadToolsConfig() (ToolsConfig, error) {
+	loadEnvFile()
+	return loadToolsConfig(os.Getenv("TOOLS_CONFIG"))
+}
+
 // loadEnvFile loads environment variables from .env file
 func loadEnvFile() error {
 	// Get the current working directory
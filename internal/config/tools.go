@@ -0,0 +1,119 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/go-tools-agent/internal/artifacts"
+	"github.com/go-tools-agent/internal/cache"
+	"github.com/go-tools-agent/internal/mcp"
+	"github.com/go-tools-agent/internal/plugin"
+	"github.com/go-tools-agent/internal/tools/code"
+	"github.com/go-tools-agent/internal/tools/data"
+	"github.com/go-tools-agent/internal/tools/datetime"
+	"github.com/go-tools-agent/internal/tools/docsearch"
+	"github.com/go-tools-agent/internal/tools/filesystem"
+	httptool "github.com/go-tools-agent/internal/tools/http"
+	"github.com/go-tools-agent/internal/tools/openapi"
+	"github.com/go-tools-agent/internal/tools/sql"
+	"github.com/go-tools-agent/internal/tools/wikipedia"
+	"github.com/go-tools-agent/internal/wasm"
+)
+
+// ToolsConfig holds per-tool settings. Structured settings that do not fit in
+// environment variables are read from the JSON file named by TOOLS_CONFIG.
+type ToolsConfig struct {
+	Datetime   datetime.Config   `json:"datetime"`
+	Data       data.Config       `json:"data"`
+	Code       code.Config       `json:"code"`
+	Filesystem filesystem.Config `json:"filesystem"`
+	SQL        sql.Config        `json:"sql"`
+	DocSearch  docsearch.Config  `json:"docsearch"`
+	Artifacts  artifacts.Config  `json:"artifacts"`
+	HTTP       httptool.Config   `json:"http"`
+	Wikipedia  wikipedia.Config  `json:"wikipedia"`
+	Cache      cache.Config      `json:"cache"`
+	MCP        mcp.Config        `json:"mcp"`
+	OpenAPI    openapi.Config    `json:"openapi"`
+	Plugins    plugin.Config     `json:"plugins"`
+	WASM       wasm.Config       `json:"wasm"`
+}
+
+// DefaultToolsConfig returns the settings used when no tools config file is given
+func DefaultToolsConfig() ToolsConfig {
+	return ToolsConfig{
+		Datetime:   datetime.DefaultConfig(),
+		Data:       data.DefaultConfig(),
+		Code:       code.DefaultConfig(),
+		Filesystem: filesystem.DefaultConfig(),
+		SQL:        sql.DefaultConfig(),
+		DocSearch:  docsearch.DefaultConfig(),
+		Artifacts:  artifacts.DefaultConfig(),
+		HTTP:       httptool.DefaultConfig(),
+		Wikipedia:  wikipedia.DefaultConfig(),
+		Cache:      cache.DefaultConfig(),
+		Plugins:    plugin.DefaultConfig(),
+		WASM:       wasm.DefaultConfig(),
+	}
+}
+
+// loadToolsConfig layers the JSON file at path over the defaults
+func loadToolsConfig(path string) (ToolsConfig, error) {
+	cfg := DefaultToolsConfig()
+	if path == "" {
+		return cfg, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read tools config: %w", err)
+	}
+	if err := json.Unmarshal(content, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse tools config %s: %w", path, err)
+	}
+	if err := cfg.Datetime.Validate(); err != nil {
+		return cfg, fmt.Errorf("invalid datetime tool config: %w", err)
+	}
+	if err := cfg.Data.Validate(); err != nil {
+		return cfg, fmt.Errorf("invalid data tool config: %w", err)
+	}
+	if err := cfg.Code.Validate(); err != nil {
+		return cfg, fmt.Errorf("invalid code tool config: %w", err)
+	}
+	if err := cfg.Filesystem.Validate(); err != nil {
+		return cfg, fmt.Errorf("invalid filesystem tool config: %w", err)
+	}
+	if err := cfg.SQL.Validate(); err != nil {
+		return cfg, fmt.Errorf("invalid sql tool config: %w", err)
+	}
+	if err := cfg.DocSearch.Validate(); err != nil {
+		return cfg, fmt.Errorf("invalid docsearch tool config: %w", err)
+	}
+	if err := cfg.HTTP.Validate(); err != nil {
+		return cfg, fmt.Errorf("invalid http tool config: %w", err)
+	}
+	if err := cfg.Wikipedia.Validate(); err != nil {
+		return cfg, fmt.Errorf("invalid wikipedia tool config: %w", err)
+	}
+	if err := cfg.Cache.Validate(); err != nil {
+		return cfg, fmt.Errorf("invalid cache config: %w", err)
+	}
+	if err := cfg.MCP.Validate(); err != nil {
+		return cfg, fmt.Errorf("invalid mcp config: %w", err)
+	}
+	if err := cfg.OpenAPI.Validate(); err != nil {
+		return cfg, fmt.Errorf("invalid openapi config: %w", err)
+	}
+	if err := cfg.Plugins.Validate(); err != nil {
+		return cfg, fmt.Errorf("invalid plugins config: %w", err)
+	}
+	if err := cfg.WASM.Validate(); err != nil {
+		return cfg, fmt.Errorf("invalid wasm config: %w", err)
+	}
+	if cfg.Artifacts.TTLSeconds <= 0 || cfg.Artifacts.MaxTotalBytes <= 0 {
+		return cfg, fmt.Errorf("invalid artifacts config: ttlSeconds and maxTotalBytes must be positive")
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,480 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-tools-agent/internal/agent"
+)
+
+// clientInfo identifies the agent to servers
+var clientInfo = Implementation{Name: "go-tools-agent", Version: "1.0"}
+
+// defaultTimeout bounds requests to servers without a configured timeout
+const defaultTimeout = 60 * time.Second
+
+// connection is one initialized session with a server
+type connection struct {
+	client    *Client
+	transport transport
+	nextID    atomic.Int64
+
+	mu      sync.Mutex
+	pending map[string]chan *Message
+}
+
+// request sends a request and decodes its result into result
+func (conn *connection) request(ctx context.Context, method string, params, result interface{}) error {
+	id := conn.nextID.Add(1)
+	msg, err := newRequest(id, method, params)
+	if err != nil {
+		return err
+	}
+	key := string(msg.ID)
+	reply := make(chan *Message, 1)
+	conn.mu.Lock()
+	conn.pending[key] = reply
+	conn.mu.Unlock()
+	defer func() {
+		conn.mu.Lock()
+		delete(conn.pending, key)
+		conn.mu.Unlock()
+	}()
+
+	if err := conn.transport.send(ctx, msg); err != nil {
+		return err
+	}
+
+	select {
+	case resp := <-reply:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil {
+			return nil
+		}
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("invalid %s result: %w", method, err)
+		}
+		return nil
+	case <-conn.transport.done():
+		return fmt.Errorf("connection lost: %w", conn.transport.err())
+	case <-ctx.Done():
+		// Tell the server to stop working on it
+		if cancel, err := newNotification(MethodCancelled, map[string]interface{}{
+			"requestId": id,
+			"reason":    ctx.Err().Error(),
+		}); err == nil {
+			conn.transport.send(context.Background(), cancel)
+		}
+		return ctx.Err()
+	}
+}
+
+// notify sends a notification
+func (conn *connection) notify(ctx context.Context, method string, params interface{}) error {
+	msg, err := newNotification(method, params)
+	if err != nil {
+		return err
+	}
+	return conn.transport.send(ctx, msg)
+}
+
+// dispatch handles a message from the server
+func (conn *connection) dispatch(msg *Message) {
+	switch {
+	case msg.IsResponse():
+		conn.mu.Lock()
+		reply, ok := conn.pending[strings.TrimSpace(string(msg.ID))]
+		conn.mu.Unlock()
+		if ok {
+			// The channel holds one reply; a duplicate response from the
+			// server is dropped rather than blocking the reader
+			select {
+			case reply <- msg:
+			default:
+			}
+		}
+
+	case msg.IsRequest():
+		// Answer off the reading goroutine so a slow send cannot stall it
+		go func() {
+			var resp *Message
+			switch msg.Method {
+			case MethodPing:
+				resp = newResult(msg.ID, struct{}{})
+			default:
+				resp = newError(msg.ID, CodeMethodNotFound, "method not supported by client: "+msg.Method)
+			}
+			conn.transport.send(context.Background(), resp)
+		}()
+
+	case msg.IsNotification():
+		switch msg.Method {
+		case MethodToolsListChanged:
+			go conn.client.refreshTools(conn)
+		case MethodLogMessage:
+			var params struct {
+				Level string          `json:"level"`
+				Data  json.RawMessage `json:"data"`
+			}
+			json.Unmarshal(msg.Params, &params)
+			log.Printf("🔌 MCP %s [%s]: %s", conn.client.config.Name, params.Level, string(params.Data))
+		}
+	}
+}
+
+// Client keeps a session with one MCP server and tracks its tools. When the
+// connection is lost, because a stdio server exited or an HTTP session
+// expired, the client reconnects with backoff; calls made meanwhile wait
+// for the new session.
+type Client struct {
+	config  ServerConfig
+	timeout time.Duration
+
+	mu      sync.Mutex
+	conn    *connection
+	ready   chan struct{} // closed while conn is set
+	lastErr error
+	tools   []agent.Tool
+
+	stop    chan struct{}
+	stopped chan struct{}
+	started chan struct{} // closed after the first connection attempt
+}
+
+// NewClient creates a client for the server; Start connects it
+func NewClient(config ServerConfig) *Client {
+	timeout := defaultTimeout
+	if config.TimeoutSeconds > 0 {
+		timeout = time.Duration(config.TimeoutSeconds) * time.Second
+	}
+	return &Client{
+		config:  config,
+		timeout: timeout,
+		ready:   make(chan struct{}),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		started: make(chan struct{}),
+	}
+}
+
+// Start connects to the server in the background and keeps reconnecting
+// until Close. It returns once the first attempt has finished or ctx is
+// done, whichever comes first.
+func (c *Client) Start(ctx context.Context) {
+	go c.run()
+	select {
+	case <-c.started:
+	case <-ctx.Done():
+	}
+}
+
+// Close ends the session and stops reconnecting
+func (c *Client) Close() {
+	close(c.stop)
+	<-c.stopped
+}
+
+// Tools returns the server's tools as agent tools, as last listed
+func (c *Client) Tools() []agent.Tool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]agent.Tool(nil), c.tools...)
+}
+
+// run connects, waits for the connection to be lost and reconnects, with
+// a delay that doubles after every failure
+func (c *Client) run() {
+	defer close(c.stopped)
+	var startOnce sync.Once
+	delay := time.Second
+	for {
+		began := time.Now()
+		conn, err := c.connect()
+		startOnce.Do(func() { close(c.started) })
+		if err != nil {
+			log.Printf("⚠️  MCP %s: failed to connect: %v (retrying in %s)", c.config.Name, err, delay)
+			c.setLastErr(err)
+		} else {
+			log.Printf("🔌 MCP %s: connected with %d tools", c.config.Name, len(c.Tools()))
+			c.mu.Lock()
+			c.conn = conn
+			close(c.ready)
+			c.mu.Unlock()
+
+			select {
+			case <-conn.transport.done():
+				err := conn.transport.err()
+				log.Printf("⚠️  MCP %s: connection lost: %v (reconnecting in %s)", c.config.Name, err, delay)
+				c.setLastErr(err)
+			case <-c.stop:
+			}
+
+			c.mu.Lock()
+			c.conn = nil
+			c.ready = make(chan struct{})
+			c.mu.Unlock()
+			conn.transport.close()
+
+			// A session that lasted a while was healthy; start over with
+			// short delays
+			if time.Since(began) > time.Minute {
+				delay = time.Second
+			}
+		}
+
+		select {
+		case <-c.stop:
+			return
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > time.Minute {
+			delay = time.Minute
+		}
+	}
+}
+
+func (c *Client) setLastErr(err error) {
+	c.mu.Lock()
+	c.lastErr = err
+	c.mu.Unlock()
+}
+
+// connect opens a transport, performs the initialize handshake and lists
+// the server's tools
+func (c *Client) connect() (*connection, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	conn := &connection{client: c, pending: make(map[string]chan *Message)}
+	var err error
+	switch c.config.Transport {
+	case TransportStdio:
+		conn.transport, err = startStdio(c.config, conn.dispatch)
+	case TransportHTTP:
+		conn.transport = newHTTPTransport(c.config, conn.dispatch)
+	case TransportSSE:
+		conn.transport, err = startSSE(ctx, c.config, conn.dispatch)
+	default:
+		err = fmt.Errorf("unknown transport %q", c.config.Transport)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.initialize(ctx, conn); err != nil {
+		conn.transport.close()
+		return nil, err
+	}
+	if err := c.refreshTools(conn); err != nil {
+		conn.transport.close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (c *Client) initialize(ctx context.Context, conn *connection) error {
+	var result InitializeResult
+	err := conn.request(ctx, MethodInitialize, InitializeParams{
+		ProtocolVersion: ProtocolVersion,
+		Capabilities:    map[string]interface{}{},
+		ClientInfo:      clientInfo,
+	}, &result)
+	if err != nil {
+		return fmt.Errorf("initialize failed: %w", err)
+	}
+	if !SupportedVersion(result.ProtocolVersion) {
+		return fmt.Errorf("server speaks unsupported protocol version %q", result.ProtocolVersion)
+	}
+	if result.Capabilities.Tools == nil {
+		return fmt.Errorf("server does not offer tools")
+	}
+	if t, ok := conn.transport.(*httpTransport); ok {
+		t.setProtocolVersion(result.ProtocolVersion)
+	}
+	if err := conn.notify(ctx, MethodInitialized, nil); err != nil {
+		return fmt.Errorf("initialized notification failed: %w", err)
+	}
+	if t, ok := conn.transport.(*httpTransport); ok {
+		t.listen()
+	}
+	return nil
+}
+
+// refreshTools lists the server's tools and replaces the client's tools.
+// It runs on connect and whenever the server reports that its tools
+// changed.
+func (c *Client) refreshTools(conn *connection) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	var listed []Tool
+	cursor := ""
+	for {
+		var page ListToolsResult
+		if err := conn.request(ctx, MethodToolsList, ListToolsParams{Cursor: cursor}, &page); err != nil {
+			err = fmt.Errorf("tools/list failed: %w", err)
+			log.Printf("⚠️  MCP %s: %v", c.config.Name, err)
+			return err
+		}
+		listed = append(listed, page.Tools...)
+		if cursor = page.NextCursor; cursor == "" {
+			break
+		}
+	}
+
+	var tools []agent.Tool
+	for _, tool := range listed {
+		if len(c.config.Tools) > 0 && !contains(c.config.Tools, tool.Name) {
+			continue
+		}
+		tools = append(tools, c.agentTool(tool))
+	}
+
+	c.mu.Lock()
+	changed := c.tools != nil
+	c.tools = tools
+	c.mu.Unlock()
+	if changed {
+		log.Printf("🔌 MCP %s: tool list updated, %d tools", c.config.Name, len(tools))
+	}
+	return nil
+}
+
+// toolNameInvalid matches characters not allowed in model tool names
+var toolNameInvalid = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// agentTool wraps a server tool so the agent can call it
+func (c *Client) agentTool(tool Tool) agent.Tool {
+	name := toolNameInvalid.ReplaceAllString(c.config.prefix()+tool.Name, "_")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	description := tool.Description
+	if description == "" {
+		description = tool.Title
+	}
+	schema := tool.InputSchema
+	if len(schema) == 0 || string(schema) == "null" {
+		schema = json.RawMessage(`{"type":"object","properties":{}}`)
+	}
+
+	remoteName := tool.Name
+	return agent.Tool{
+		Name:        name,
+		Description: description,
+		Schema:      schema,
+		Handler: func(ctx context.Context, input json.RawMessage) (json.RawMessage, error) {
+			return c.CallTool(ctx, remoteName, input)
+		},
+	}
+}
+
+// CallTool calls a tool on the server, waiting for a reconnect if the
+// server is restarting. Failures the tool reports itself are returned as
+// output with isError set, so the model can see them.
+func (c *Client) CallTool(ctx context.Context, name string, arguments json.RawMessage) (json.RawMessage, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	if len(arguments) == 0 {
+		arguments = json.RawMessage("{}")
+	}
+
+	// A call that never reached the server, because the connection had
+	// just been lost, is retried once on the next connection. Calls lost
+	// after sending are not, since the tool may already have run.
+	var result CallToolResult
+	for attempt := 1; ; attempt++ {
+		conn, err := c.waitConnection(ctx)
+		if err != nil {
+			return nil, err
+		}
+		err = conn.request(ctx, MethodToolsCall, CallToolParams{Name: name, Arguments: arguments}, &result)
+		if err == nil {
+			break
+		}
+		if attempt > 1 || !errors.Is(err, errNotSent) {
+			return nil, fmt.Errorf("MCP server %s: %s failed: %w", c.config.Name, name, err)
+		}
+		<-conn.transport.done()
+	}
+	return json.Marshal(toolOutput(result))
+}
+
+// waitConnection returns the current session, waiting while the client
+// reconnects
+func (c *Client) waitConnection(ctx context.Context) (*connection, error) {
+	for {
+		c.mu.Lock()
+		conn, ready, lastErr := c.conn, c.ready, c.lastErr
+		c.mu.Unlock()
+		if conn != nil {
+			return conn, nil
+		}
+		select {
+		case <-ready:
+		case <-c.stop:
+			return nil, fmt.Errorf("MCP server %s is shut down", c.config.Name)
+		case <-ctx.Done():
+			if lastErr != nil {
+				return nil, fmt.Errorf("MCP server %s is not connected: %v", c.config.Name, lastErr)
+			}
+			return nil, fmt.Errorf("MCP server %s is not connected: %w", c.config.Name, ctx.Err())
+		}
+	}
+}
+
+// ToolOutput is what the agent sees of an MCP tool result
+type ToolOutput struct {
+	// Content joins the result's text blocks; other blocks are summarized
+	Content string `json:"content"`
+	// StructuredContent is the result's structured data, if any
+	StructuredContent json.RawMessage `json:"structuredContent,omitempty"`
+	// IsError is set when the tool reports a failure
+	IsError bool `json:"isError,omitempty"`
+}
+
+// toolOutput converts a tool result for the model
+func toolOutput(result CallToolResult) ToolOutput {
+	parts := make([]string, 0, len(result.Content))
+	for _, block := range result.Content {
+		switch block.Type {
+		case "text":
+			parts = append(parts, block.Text)
+		case "image", "audio":
+			parts = append(parts, fmt.Sprintf("[%s: %s, %d bytes]", block.Type, block.MIMEType, len(block.Data)*3/4))
+		case "resource":
+			if block.Resource != nil && block.Resource.Text != "" {
+				parts = append(parts, block.Resource.Text)
+			} else if block.Resource != nil {
+				parts = append(parts, fmt.Sprintf("[resource: %s]", block.Resource.URI))
+			}
+		case "resource_link":
+			parts = append(parts, fmt.Sprintf("[link: %s %s]", block.Name, block.URI))
+		}
+	}
+	return ToolOutput{
+		Content:           strings.Join(parts, "\n\n"),
+		StructuredContent: result.StructuredContent,
+		IsError:           result.IsError,
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
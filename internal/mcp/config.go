@@ -0,0 +1,117 @@
+package mcp
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+
+	"github.com/go-tools-agent/internal/redact"
+)
+
+// Transports for connecting to MCP servers
+const (
+	// TransportStdio launches the server and talks over its stdin and stdout
+	TransportStdio = "stdio"
+	// TransportHTTP is the streamable HTTP transport
+	TransportHTTP = "http"
+	// TransportSSE is the older HTTP transport with a separate event stream
+	TransportSSE = "sse"
+)
+
+// Config lists the MCP servers whose tools the agent may use
+type Config struct {
+	Servers []ServerConfig `json:"servers"`
+}
+
+// ServerConfig describes one MCP server
+type ServerConfig struct {
+	// Name identifies the server in logs and, by default, prefixes its
+	// tool names
+	Name string `json:"name"`
+	// Transport is "stdio", "http" or "sse"
+	Transport string `json:"transport"`
+
+	// Command, Args, Env and Dir launch a stdio server. Env adds to the
+	// agent's environment; values may reference it as ${NAME}.
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	Dir     string            `json:"dir,omitempty"`
+
+	// URL and Headers reach an http or sse server. Header values may
+	// reference environment variables as ${NAME}.
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// ToolPrefix is put before the server's tool names so they cannot
+	// clash with other tools; it defaults to the server name and "_"
+	ToolPrefix *string `json:"toolPrefix,omitempty"`
+	// Tools, when set, lists the only tools imported from the server
+	Tools []string `json:"tools,omitempty"`
+	// TimeoutSeconds bounds each request to the server
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+}
+
+// namePattern restricts server names to what tool names allow
+var namePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,32}$`)
+
+// Validate checks that every server can be started
+func (c Config) Validate() error {
+	seen := make(map[string]bool)
+	for _, server := range c.Servers {
+		if err := server.Validate(); err != nil {
+			return err
+		}
+		if seen[server.Name] {
+			return fmt.Errorf("duplicate MCP server name %q", server.Name)
+		}
+		seen[server.Name] = true
+	}
+	return nil
+}
+
+// Validate checks the server's transport settings
+func (s ServerConfig) Validate() error {
+	if !namePattern.MatchString(s.Name) {
+		return fmt.Errorf("MCP server name %q must be 1-32 letters, digits, '_' or '-'", s.Name)
+	}
+	if s.TimeoutSeconds < 0 {
+		return fmt.Errorf("MCP server %s: timeoutSeconds must not be negative", s.Name)
+	}
+	switch s.Transport {
+	case TransportStdio:
+		if s.Command == "" {
+			return fmt.Errorf("MCP server %s: stdio transport needs a command", s.Name)
+		}
+	case TransportHTTP, TransportSSE:
+		u, err := url.Parse(s.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("MCP server %s: %s transport needs an http or https url", s.Name, s.Transport)
+		}
+	default:
+		return fmt.Errorf("MCP server %s: unknown transport %q", s.Name, s.Transport)
+	}
+	return nil
+}
+
+// prefix returns the string put before the server's tool names
+func (s ServerConfig) prefix() string {
+	if s.ToolPrefix != nil {
+		return *s.ToolPrefix
+	}
+	return s.Name + "_"
+}
+
+// Secrets returns the values of the environment variables that headers
+// and env reference, so callers can redact them
+func Secrets(config Config) []string {
+	var secrets []string
+	for _, server := range config.Servers {
+		for _, values := range []map[string]string{server.Headers, server.Env} {
+			for _, value := range values {
+				secrets = append(secrets, redact.EnvValues(value)...)
+			}
+		}
+	}
+	return secrets
+}
@@ -0,0 +1,395 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// readSSE calls fn for every event of a server-sent event stream until the
+// stream ends
+func readSSE(r io.Reader, fn func(event, data string)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), maxMessageBytes)
+	var event string
+	var data []string
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" {
+			if data != nil {
+				if event == "" {
+					event = "message"
+				}
+				fn(event, strings.Join(data, "\n"))
+			}
+			event, data = "", nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+	return scanner.Err()
+}
+
+// decodeMessages parses a JSON-RPC message or batch
+func decodeMessages(body []byte) ([]*Message, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var batch []*Message
+		err := json.Unmarshal(body, &batch)
+		return batch, err
+	}
+	var msg Message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, err
+	}
+	return []*Message{&msg}, nil
+}
+
+// setHeaders adds the configured headers, expanding environment references
+func setHeaders(req *http.Request, headers map[string]string) {
+	for key, value := range headers {
+		req.Header.Set(key, os.ExpandEnv(value))
+	}
+}
+
+// httpTransport is the streamable HTTP transport: every message is POSTed
+// to one endpoint, and responses come back as JSON or as an event stream.
+// A GET on the same endpoint opens a stream for server notifications.
+type httpTransport struct {
+	*connState
+	config ServerConfig
+	client *http.Client
+	handle func(*Message)
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu              sync.Mutex
+	sessionID       string
+	protocolVersion string
+}
+
+func newHTTPTransport(config ServerConfig, handle func(*Message)) *httpTransport {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &httpTransport{
+		connState: newConnState(),
+		config:    config,
+		client:    &http.Client{},
+		handle:    handle,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+func (t *httpTransport) newRequest(ctx context.Context, method string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, t.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	setHeaders(req, t.config.Headers)
+	t.mu.Lock()
+	if t.sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", t.sessionID)
+	}
+	if t.protocolVersion != "" {
+		req.Header.Set("MCP-Protocol-Version", t.protocolVersion)
+	}
+	t.mu.Unlock()
+	return req, nil
+}
+
+func (t *httpTransport) send(ctx context.Context, msg *Message) error {
+	select {
+	case <-t.closed:
+		return fmt.Errorf("%w: connection lost: %v", errNotSent, t.err())
+	default:
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := t.newRequest(ctx, http.MethodPost, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		if ctx.Err() == nil {
+			t.lose(fmt.Errorf("server unreachable: %w", err))
+		}
+		return fmt.Errorf("request failed: %w", err)
+	}
+
+	if id := resp.Header.Get("Mcp-Session-Id"); id != "" {
+		t.mu.Lock()
+		t.sessionID = id
+		t.mu.Unlock()
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusAccepted || resp.StatusCode == http.StatusNoContent:
+		resp.Body.Close()
+		return nil
+	case resp.StatusCode == http.StatusNotFound && req.Header.Get("Mcp-Session-Id") != "":
+		resp.Body.Close()
+		t.lose(fmt.Errorf("session expired"))
+		return fmt.Errorf("%w: session expired", errNotSent)
+	case resp.StatusCode >= 300:
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		resp.Body.Close()
+		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(text)))
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "text/event-stream" {
+		// The response arrives on the stream, possibly after requests and
+		// notifications from the server
+		go func() {
+			defer resp.Body.Close()
+			readSSE(resp.Body, t.handleEvent)
+		}()
+		return nil
+	}
+
+	defer resp.Body.Close()
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxMessageBytes))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if len(bytes.TrimSpace(content)) == 0 {
+		return nil
+	}
+	messages, err := decodeMessages(content)
+	if err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	for _, m := range messages {
+		t.handle(m)
+	}
+	return nil
+}
+
+func (t *httpTransport) handleEvent(event, data string) {
+	if event != "message" {
+		return
+	}
+	messages, err := decodeMessages([]byte(data))
+	if err != nil {
+		log.Printf("⚠️  MCP %s sent an invalid message: %v", t.config.Name, err)
+		return
+	}
+	for _, m := range messages {
+		t.handle(m)
+	}
+}
+
+// setProtocolVersion records the negotiated revision, which later
+// requests must announce
+func (t *httpTransport) setProtocolVersion(version string) {
+	t.mu.Lock()
+	t.protocolVersion = version
+	t.mu.Unlock()
+}
+
+// listen keeps a GET stream open for notifications such as tool list
+// changes. Servers that do not offer one answer 405, which is fine.
+func (t *httpTransport) listen() {
+	go func() {
+		for {
+			req, err := t.newRequest(t.ctx, http.MethodGet, nil)
+			if err != nil {
+				return
+			}
+			req.Header.Set("Accept", "text/event-stream")
+			resp, err := t.client.Do(req)
+			if err != nil {
+				if t.ctx.Err() != nil {
+					return
+				}
+			} else {
+				status := resp.StatusCode
+				if status == http.StatusOK {
+					readSSE(resp.Body, t.handleEvent)
+				}
+				resp.Body.Close()
+				if status == http.StatusNotFound && req.Header.Get("Mcp-Session-Id") != "" {
+					t.lose(fmt.Errorf("session expired"))
+					return
+				}
+				if status != http.StatusOK {
+					return
+				}
+			}
+			select {
+			case <-t.ctx.Done():
+				return
+			case <-t.closed:
+				return
+			case <-time.After(time.Second):
+			}
+		}
+	}()
+}
+
+// close ends the session on the server, best effort
+func (t *httpTransport) close() error {
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+	if sessionID != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if req, err := t.newRequest(ctx, http.MethodDelete, nil); err == nil {
+			if resp, err := t.client.Do(req); err == nil {
+				resp.Body.Close()
+			}
+		}
+		cancel()
+	}
+	t.cancel()
+	t.lose(fmt.Errorf("connection closed"))
+	return nil
+}
+
+// sseTransport is the HTTP transport of protocol revision 2024-11-05: the
+// server pushes every message over one event stream and announces the
+// endpoint that messages to it are POSTed to
+type sseTransport struct {
+	*connState
+	config   ServerConfig
+	client   *http.Client
+	endpoint string
+	cancel   context.CancelFunc
+}
+
+// startSSE opens the event stream and waits for the message endpoint
+func startSSE(ctx context.Context, config ServerConfig, handle func(*Message)) (*sseTransport, error) {
+	streamCtx, cancel := context.WithCancel(context.Background())
+	t := &sseTransport{connState: newConnState(), config: config, client: &http.Client{}, cancel: cancel}
+
+	req, err := http.NewRequestWithContext(streamCtx, http.MethodGet, config.URL, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	setHeaders(req, config.Headers)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to open event stream: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("event stream returned status %d", resp.StatusCode)
+	}
+
+	endpoint := make(chan string, 1)
+	go func() {
+		defer resp.Body.Close()
+		err := readSSE(resp.Body, func(event, data string) {
+			switch event {
+			case "endpoint":
+				select {
+				case endpoint <- data:
+				default:
+				}
+			case "message":
+				var msg Message
+				if err := json.Unmarshal([]byte(data), &msg); err != nil {
+					log.Printf("⚠️  MCP %s sent an invalid message: %v", config.Name, err)
+					return
+				}
+				handle(&msg)
+			}
+		})
+		if err == nil {
+			err = fmt.Errorf("event stream ended")
+		}
+		t.lose(err)
+	}()
+
+	select {
+	case raw := <-endpoint:
+		base, _ := url.Parse(config.URL)
+		ref, err := url.Parse(raw)
+		if err != nil {
+			t.close()
+			return nil, fmt.Errorf("invalid endpoint %q: %w", raw, err)
+		}
+		// Headers such as credentials are sent to the endpoint, so it must
+		// be on the server that announced it
+		resolved := base.ResolveReference(ref)
+		if !strings.EqualFold(resolved.Scheme, base.Scheme) || !strings.EqualFold(resolved.Host, base.Host) {
+			t.close()
+			return nil, fmt.Errorf("endpoint %q is not on %s://%s", raw, base.Scheme, base.Host)
+		}
+		t.endpoint = resolved.String()
+		return t, nil
+	case <-t.closed:
+		cancel()
+		return nil, t.err()
+	case <-ctx.Done():
+		t.close()
+		return nil, fmt.Errorf("no endpoint announced: %w", ctx.Err())
+	}
+}
+
+func (t *sseTransport) send(ctx context.Context, msg *Message) error {
+	select {
+	case <-t.closed:
+		return fmt.Errorf("%w: connection lost: %v", errNotSent, t.err())
+	default:
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	setHeaders(req, t.config.Headers)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(text)))
+	}
+	return nil
+}
+
+func (t *sseTransport) close() error {
+	t.cancel()
+	t.lose(fmt.Errorf("connection closed"))
+	return nil
+}
@@ -0,0 +1,73 @@
+package mcp
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/go-tools-agent/internal/agent"
+)
+
+// Manager runs a client for every configured server
+type Manager struct {
+	clients []*Client
+
+	// skipped holds the tool names already reported as taken
+	skipped sync.Map
+}
+
+// Start connects to all configured servers in parallel. It returns once
+// every server has had its first connection attempt or ctx is done;
+// servers that are not up yet keep being retried in the background and
+// their tools appear once they connect.
+func Start(ctx context.Context, config Config) *Manager {
+	m := &Manager{}
+	var wg sync.WaitGroup
+	for _, server := range config.Servers {
+		client := NewClient(server)
+		m.clients = append(m.clients, client)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.Start(ctx)
+		}()
+	}
+	wg.Wait()
+	return m
+}
+
+// Tools returns the current tools of all servers, in configuration order.
+// It is meant for AgentConfig.DynamicTools so tool list changes reach the
+// model on its next step. A tool whose name an earlier one already uses,
+// such as from another server without a toolPrefix or after truncation to
+// 64 characters, is left out with a warning.
+func (m *Manager) Tools() []agent.Tool {
+	if m == nil {
+		return nil
+	}
+	var tools []agent.Tool
+	names := make(map[string]bool)
+	for _, client := range m.clients {
+		for _, tool := range client.Tools() {
+			if names[tool.Name] {
+				if _, reported := m.skipped.LoadOrStore(tool.Name, true); !reported {
+					log.Printf("⚠️  Skipping MCP tool %s from %s: the name is already used by another MCP tool", tool.Name, client.config.Name)
+				}
+				continue
+			}
+			names[tool.Name] = true
+			tools = append(tools, tool)
+		}
+	}
+	return tools
+}
+
+// Close disconnects from all servers
+func (m *Manager) Close() {
+	if m == nil {
+		return
+	}
+	for _, client := range m.clients {
+		client.Close()
+	}
+}
@@ -0,0 +1,226 @@
+// Package mcp speaks the Model Context Protocol, so tools can be imported
+// from MCP servers and the agent's own tools offered to MCP clients
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ProtocolVersion is the MCP revision this package implements
+const ProtocolVersion = "2025-06-18"
+
+// supportedVersions are the revisions a peer may negotiate down to
+var supportedVersions = []string{ProtocolVersion, "2025-03-26", "2024-11-05"}
+
+// negotiateVersion picks the revision to answer an initialize request
+// with: the requested one if supported, otherwise the latest
+func negotiateVersion(requested string) string {
+	if SupportedVersion(requested) {
+		return requested
+	}
+	return ProtocolVersion
+}
+
+// SupportedVersion reports whether a negotiated protocol revision is one
+// this package can speak
+func SupportedVersion(version string) bool {
+	for _, v := range supportedVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// JSON-RPC error codes
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Message is a JSON-RPC 2.0 request, notification or response. Requests
+// have an ID and a method, notifications only a method and responses an ID
+// with a result or an error.
+type Message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// IsRequest reports whether m expects a response
+func (m *Message) IsRequest() bool { return m.Method != "" && len(m.ID) > 0 }
+
+// IsNotification reports whether m is a one-way message
+func (m *Message) IsNotification() bool { return m.Method != "" && len(m.ID) == 0 }
+
+// IsResponse reports whether m answers a request
+func (m *Message) IsResponse() bool { return m.Method == "" && len(m.ID) > 0 }
+
+// Error is a JSON-RPC error object
+type Error struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// Implementation names a client or server
+type Implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// InitializeParams opens a session
+type InitializeParams struct {
+	ProtocolVersion string                 `json:"protocolVersion"`
+	Capabilities    map[string]interface{} `json:"capabilities"`
+	ClientInfo      Implementation         `json:"clientInfo"`
+}
+
+// InitializeResult is the server's answer to initialize
+type InitializeResult struct {
+	ProtocolVersion string                 `json:"protocolVersion"`
+	Capabilities    ServerCapabilities     `json:"capabilities"`
+	ServerInfo      Implementation         `json:"serverInfo"`
+	Instructions    string                 `json:"instructions,omitempty"`
+	Meta            map[string]interface{} `json:"_meta,omitempty"`
+}
+
+// ServerCapabilities lists what a server offers
+type ServerCapabilities struct {
+	Tools   *ToolsCapability       `json:"tools,omitempty"`
+	Logging map[string]interface{} `json:"logging,omitempty"`
+}
+
+// ToolsCapability tells whether a server announces tool list changes
+type ToolsCapability struct {
+	ListChanged bool `json:"listChanged,omitempty"`
+}
+
+// Tool describes a tool offered by a server
+type Tool struct {
+	Name         string           `json:"name"`
+	Title        string           `json:"title,omitempty"`
+	Description  string           `json:"description,omitempty"`
+	InputSchema  json.RawMessage  `json:"inputSchema"`
+	OutputSchema json.RawMessage  `json:"outputSchema,omitempty"`
+	Annotations  *ToolAnnotations `json:"annotations,omitempty"`
+}
+
+// ToolAnnotations are hints about a tool's behaviour
+type ToolAnnotations struct {
+	Title           string `json:"title,omitempty"`
+	ReadOnlyHint    *bool  `json:"readOnlyHint,omitempty"`
+	DestructiveHint *bool  `json:"destructiveHint,omitempty"`
+	IdempotentHint  *bool  `json:"idempotentHint,omitempty"`
+	OpenWorldHint   *bool  `json:"openWorldHint,omitempty"`
+}
+
+// ListToolsParams pages through a server's tools
+type ListToolsParams struct {
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// ListToolsResult is one page of tools
+type ListToolsResult struct {
+	Tools      []Tool `json:"tools"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// CallToolParams invokes a tool
+type CallToolParams struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+// CallToolResult is a tool's result. IsError marks failures the model
+// should see and may recover from, as opposed to protocol errors.
+type CallToolResult struct {
+	Content           []Content       `json:"content"`
+	StructuredContent json.RawMessage `json:"structuredContent,omitempty"`
+	IsError           bool            `json:"isError,omitempty"`
+}
+
+// Content is a block of tool output
+type Content struct {
+	Type     string           `json:"type"`
+	Text     string           `json:"text,omitempty"`
+	Data     string           `json:"data,omitempty"`
+	MIMEType string           `json:"mimeType,omitempty"`
+	URI      string           `json:"uri,omitempty"`
+	Name     string           `json:"name,omitempty"`
+	Resource *ResourceContent `json:"resource,omitempty"`
+}
+
+// ResourceContent is an embedded resource
+type ResourceContent struct {
+	URI      string `json:"uri"`
+	MIMEType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"`
+}
+
+// Notification and request methods used by this package
+const (
+	MethodInitialize       = "initialize"
+	MethodInitialized      = "notifications/initialized"
+	MethodPing             = "ping"
+	MethodToolsList        = "tools/list"
+	MethodToolsCall        = "tools/call"
+	MethodToolsListChanged = "notifications/tools/list_changed"
+	MethodCancelled        = "notifications/cancelled"
+	MethodLogMessage       = "notifications/message"
+)
+
+// newRequest builds a request message
+func newRequest(id int64, method string, params interface{}) (*Message, error) {
+	msg := &Message{JSONRPC: "2.0", ID: json.RawMessage(fmt.Sprint(id)), Method: method}
+	if params != nil {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		msg.Params = raw
+	}
+	return msg, nil
+}
+
+// newNotification builds a notification message
+func newNotification(method string, params interface{}) (*Message, error) {
+	msg := &Message{JSONRPC: "2.0", Method: method}
+	if params != nil {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		msg.Params = raw
+	}
+	return msg, nil
+}
+
+// newResult builds a successful response to the request with the given ID
+func newResult(id json.RawMessage, result interface{}) *Message {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return newError(id, CodeInternalError, err.Error())
+	}
+	return &Message{JSONRPC: "2.0", ID: id, Result: raw}
+}
+
+// newError builds an error response to the request with the given ID
+func newError(id json.RawMessage, code int, message string) *Message {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return &Message{JSONRPC: "2.0", ID: id, Error: &Error{Code: code, Message: message}}
+}
@@ -0,0 +1,318 @@
+package mcp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-tools-agent/internal/agent"
+)
+
+// ServerOptions configures a Server
+type ServerOptions struct {
+	// Info names the server to clients
+	Info Implementation
+	// Instructions optionally tell clients how to use the tools
+	Instructions string
+	// Redact, if set, removes secrets from tool outputs and errors
+	Redact func(string) string
+	// SessionEndHooks are called with the session ID when a session ends,
+	// so tools can release resources scoped to it
+	SessionEndHooks []func(sessionID string)
+	// SessionIdleTimeout ends HTTP sessions without requests for this
+	// long; zero keeps them until the client deletes them
+	SessionIdleTimeout time.Duration
+	// CallTimeout bounds each tool call; zero means DefaultCallTimeout
+	CallTimeout time.Duration
+	// BearerToken, if set, is required from HTTP clients
+	BearerToken string
+	// AllowedOrigins are browser origins allowed besides local ones
+	AllowedOrigins []string
+}
+
+// DefaultCallTimeout bounds tool calls when ServerOptions sets no timeout
+const DefaultCallTimeout = 2 * time.Minute
+
+// Server offers agent tools to MCP clients. Every session is treated like
+// an agent run: tool calls carry the session ID as their run ID, so state
+// such as code sessions lives as long as the MCP session.
+type Server struct {
+	tools   []agent.Tool
+	byName  map[string]agent.Tool
+	options ServerOptions
+
+	mu       sync.Mutex
+	sessions map[string]*serverSession
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewServer creates a server for the given tools
+func NewServer(tools []agent.Tool, options ServerOptions) *Server {
+	if options.CallTimeout <= 0 {
+		options.CallTimeout = DefaultCallTimeout
+	}
+	s := &Server{
+		tools:    tools,
+		byName:   make(map[string]agent.Tool, len(tools)),
+		options:  options,
+		sessions: make(map[string]*serverSession),
+		stop:     make(chan struct{}),
+	}
+	for _, tool := range tools {
+		s.byName[tool.Name] = tool
+	}
+	if options.SessionIdleTimeout > 0 {
+		go s.expireSessions()
+	}
+	return s
+}
+
+// Close ends all sessions
+func (s *Server) Close() {
+	s.stopOnce.Do(func() { close(s.stop) })
+	s.mu.Lock()
+	sessions := make([]*serverSession, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		sessions = append(sessions, session)
+	}
+	s.mu.Unlock()
+	for _, session := range sessions {
+		s.endSession(session)
+	}
+}
+
+// serverSession is the state of one client session
+type serverSession struct {
+	id string
+
+	mu       sync.Mutex
+	lastUsed time.Time
+	inflight map[string]context.CancelFunc
+	calls    sync.WaitGroup
+	// ended is set once endSession starts, after which no call may begin
+	ended bool
+}
+
+func (s *Server) newSession() *serverSession {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	session := &serverSession{
+		id:       hex.EncodeToString(b),
+		lastUsed: time.Now(),
+		inflight: make(map[string]context.CancelFunc),
+	}
+	s.mu.Lock()
+	s.sessions[session.id] = session
+	s.mu.Unlock()
+	return session
+}
+
+func (s *Server) session(id string) *serverSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session := s.sessions[id]
+	if session != nil {
+		session.mu.Lock()
+		session.lastUsed = time.Now()
+		session.mu.Unlock()
+	}
+	return session
+}
+
+// endSession cancels the session's calls, waits for them and runs the
+// end hooks; ending a session twice is a no-op
+func (s *Server) endSession(session *serverSession) {
+	s.mu.Lock()
+	_, ok := s.sessions[session.id]
+	delete(s.sessions, session.id)
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	session.mu.Lock()
+	session.ended = true
+	for _, cancel := range session.inflight {
+		cancel()
+	}
+	session.mu.Unlock()
+	session.calls.Wait()
+
+	for _, hook := range s.options.SessionEndHooks {
+		hook(session.id)
+	}
+}
+
+// expireSessions ends sessions that have been idle too long
+func (s *Server) expireSessions() {
+	ticker := time.NewTicker(s.options.SessionIdleTimeout / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+		var idle []*serverSession
+		s.mu.Lock()
+		for _, session := range s.sessions {
+			session.mu.Lock()
+			if len(session.inflight) == 0 && time.Since(session.lastUsed) > s.options.SessionIdleTimeout {
+				idle = append(idle, session)
+			}
+			session.mu.Unlock()
+		}
+		s.mu.Unlock()
+		for _, session := range idle {
+			log.Printf("🔌 MCP session %s expired", session.id)
+			s.endSession(session)
+		}
+	}
+}
+
+// handle processes one message of a session and returns the response, or
+// nil for notifications and responses
+func (s *Server) handle(ctx context.Context, session *serverSession, msg *Message) *Message {
+	switch {
+	case msg.IsNotification():
+		if msg.Method == MethodCancelled {
+			var params struct {
+				RequestID json.RawMessage `json:"requestId"`
+			}
+			if json.Unmarshal(msg.Params, &params) == nil {
+				session.mu.Lock()
+				if cancel, ok := session.inflight[strings.TrimSpace(string(params.RequestID))]; ok {
+					cancel()
+				}
+				session.mu.Unlock()
+			}
+		}
+		return nil
+	case msg.IsResponse():
+		// The server sends no requests, so there is nothing to match
+		return nil
+	case !msg.IsRequest():
+		return newError(msg.ID, CodeInvalidRequest, "invalid message")
+	}
+
+	switch msg.Method {
+	case MethodInitialize:
+		var params InitializeParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return newError(msg.ID, CodeInvalidParams, "invalid initialize params: "+err.Error())
+		}
+		log.Printf("🔌 MCP session %s opened by %s %s", session.id, params.ClientInfo.Name, params.ClientInfo.Version)
+		return newResult(msg.ID, InitializeResult{
+			ProtocolVersion: negotiateVersion(params.ProtocolVersion),
+			Capabilities:    ServerCapabilities{Tools: &ToolsCapability{}},
+			ServerInfo:      s.options.Info,
+			Instructions:    s.options.Instructions,
+		})
+
+	case MethodPing:
+		return newResult(msg.ID, struct{}{})
+
+	case MethodToolsList:
+		result := ListToolsResult{Tools: make([]Tool, 0, len(s.tools))}
+		for _, tool := range s.tools {
+			result.Tools = append(result.Tools, describeTool(tool))
+		}
+		return newResult(msg.ID, result)
+
+	case MethodToolsCall:
+		var params CallToolParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return newError(msg.ID, CodeInvalidParams, "invalid tools/call params: "+err.Error())
+		}
+		tool, ok := s.byName[params.Name]
+		if !ok {
+			return newError(msg.ID, CodeInvalidParams, "unknown tool: "+params.Name)
+		}
+
+		// Track the call so notifications/cancelled and the end of the
+		// session can stop it
+		key := strings.TrimSpace(string(msg.ID))
+		callCtx, cancel := context.WithCancel(agent.WithRunID(ctx, session.id))
+		defer cancel()
+		session.mu.Lock()
+		if session.ended {
+			session.mu.Unlock()
+			return newError(msg.ID, CodeInvalidRequest, "session has ended")
+		}
+		session.inflight[key] = cancel
+		session.calls.Add(1)
+		session.mu.Unlock()
+		defer func() {
+			session.mu.Lock()
+			delete(session.inflight, key)
+			session.mu.Unlock()
+			session.calls.Done()
+		}()
+
+		return newResult(msg.ID, s.callTool(callCtx, tool, params.Arguments))
+
+	default:
+		return newError(msg.ID, CodeMethodNotFound, "method not found: "+msg.Method)
+	}
+}
+
+// callTool runs a tool for at most CallTimeout. Handler errors become
+// results with isError set, so the client's model sees them and can try
+// again.
+func (s *Server) callTool(ctx context.Context, tool agent.Tool, arguments json.RawMessage) CallToolResult {
+	if len(arguments) == 0 || string(arguments) == "null" {
+		arguments = json.RawMessage("{}")
+	}
+	log.Printf("🛠️  MCP call %s: %s", tool.Name, s.redact(string(arguments)))
+
+	callCtx, cancel := context.WithTimeout(ctx, s.options.CallTimeout)
+	defer cancel()
+	output, err := tool.Handler(callCtx, arguments)
+	if err != nil {
+		if ctx.Err() == nil && errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("%s timed out after %s: %w", tool.Name, s.options.CallTimeout, err)
+		}
+		message := s.redact(err.Error())
+		log.Printf("❌ Tool execution failed: %s", message)
+		return CallToolResult{Content: []Content{{Type: "text", Text: message}}, IsError: true}
+	}
+
+	text := s.redact(string(output))
+	result := CallToolResult{Content: []Content{{Type: "text", Text: text}}}
+	// Object outputs double as structured content
+	if trimmed := strings.TrimSpace(text); strings.HasPrefix(trimmed, "{") && json.Valid([]byte(trimmed)) {
+		result.StructuredContent = json.RawMessage(trimmed)
+	}
+	return result
+}
+
+func (s *Server) redact(text string) string {
+	if s.options.Redact == nil {
+		return text
+	}
+	return s.options.Redact(text)
+}
+
+// describeTool maps an agent tool to its MCP description
+func describeTool(tool agent.Tool) Tool {
+	schema := tool.Schema
+	if len(schema) == 0 {
+		schema = json.RawMessage(`{"type":"object","properties":{}}`)
+	}
+	described := Tool{Name: tool.Name, Description: tool.Description, InputSchema: schema}
+	if tool.Cacheable {
+		readOnly := true
+		described.Annotations = &ToolAnnotations{ReadOnlyHint: &readOnly}
+	}
+	return described
+}
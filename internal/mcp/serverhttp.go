@@ -0,0 +1,146 @@
+package mcp
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// ServeHTTP implements the streamable HTTP transport on a single endpoint.
+// Messages are POSTed and answered with JSON; an initialize request opens
+// a session whose ID the client repeats in the Mcp-Session-Id header, and
+// a DELETE ends it. The server sends no messages of its own, so GET
+// streams are not offered.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.allowedOrigin(r.Header.Get("Origin")) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+	if s.options.BearerToken != "" {
+		want := "Bearer " + s.options.BearerToken
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(want)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		s.servePost(w, r)
+	case http.MethodDelete:
+		session := s.session(r.Header.Get("Mcp-Session-Id"))
+		if session == nil {
+			http.Error(w, "session not found", http.StatusNotFound)
+			return
+		}
+		s.endSession(session)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) servePost(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxMessageBytes+1))
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxMessageBytes {
+		http.Error(w, "request too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	messages, err := decodeMessages(body)
+	if err != nil || len(messages) == 0 {
+		writeJSON(w, http.StatusBadRequest, newError(nil, CodeParseError, "parse error"))
+		return
+	}
+
+	// initialize opens a new session; everything else needs an open one
+	var session *serverSession
+	if len(messages) == 1 && messages[0].Method == MethodInitialize {
+		session = s.newSession()
+		w.Header().Set("Mcp-Session-Id", session.id)
+	} else {
+		id := r.Header.Get("Mcp-Session-Id")
+		if id == "" {
+			writeJSON(w, http.StatusBadRequest, newError(nil, CodeInvalidRequest, "missing Mcp-Session-Id header"))
+			return
+		}
+		if session = s.session(id); session == nil {
+			http.Error(w, "session not found", http.StatusNotFound)
+			return
+		}
+		if version := r.Header.Get("MCP-Protocol-Version"); version != "" && !SupportedVersion(version) {
+			writeJSON(w, http.StatusBadRequest, newError(nil, CodeInvalidRequest, "unsupported protocol version "+version))
+			return
+		}
+	}
+
+	// Requests of a batch run concurrently; notifications and responses
+	// need no answer
+	responses := make([]*Message, len(messages))
+	var wg sync.WaitGroup
+	for i, msg := range messages {
+		if !msg.IsRequest() {
+			s.handle(r.Context(), session, msg)
+			continue
+		}
+		wg.Add(1)
+		go func(i int, msg *Message) {
+			defer wg.Done()
+			responses[i] = s.handle(r.Context(), session, msg)
+		}(i, msg)
+	}
+	wg.Wait()
+
+	var answers []*Message
+	for _, resp := range responses {
+		if resp != nil {
+			answers = append(answers, resp)
+		}
+	}
+	switch {
+	case len(answers) == 0:
+		w.WriteHeader(http.StatusAccepted)
+	case len(messages) == 1:
+		writeJSON(w, http.StatusOK, answers[0])
+	default:
+		writeJSON(w, http.StatusOK, answers)
+	}
+}
+
+// allowedOrigin guards against DNS rebinding: browsers may only call the
+// server from local pages or the configured origins
+func (s *Server) allowedOrigin(origin string) bool {
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range s.options.AllowedOrigins {
+		if origin == allowed {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,83 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// ServeStdio serves one session over newline delimited JSON messages read
+// from in and written to out, until in ends or ctx is done. Requests are
+// handled concurrently, so a slow tool does not hold up pings or
+// cancellations.
+func (s *Server) ServeStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	session := s.newSession()
+	defer s.endSession(session)
+
+	var writeMu sync.Mutex
+	write := func(msg *Message) {
+		line, err := json.Marshal(msg)
+		if err != nil {
+			return
+		}
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		out.Write(append(line, '\n'))
+	}
+
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 64<<10), maxMessageBytes)
+		for scanner.Scan() {
+			line := append([]byte(nil), scanner.Bytes()...)
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+		readErr <- scanner.Err()
+	}()
+
+	// Calls still running when the input ends are cancelled
+	var requests sync.WaitGroup
+	defer requests.Wait()
+	defer cancel()
+	for {
+		var line []byte
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-readErr:
+			return err
+		case line = <-lines:
+		}
+		if len(line) == 0 {
+			continue
+		}
+
+		messages, err := decodeMessages(line)
+		if err != nil {
+			write(newError(nil, CodeParseError, "parse error: "+err.Error()))
+			continue
+		}
+		for _, msg := range messages {
+			if !msg.IsRequest() {
+				s.handle(ctx, session, msg)
+				continue
+			}
+			requests.Add(1)
+			go func(msg *Message) {
+				defer requests.Done()
+				write(s.handle(ctx, session, msg))
+			}(msg)
+		}
+	}
+}
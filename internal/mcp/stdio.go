@@ -0,0 +1,179 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// transport carries messages to and from one server connection.
+// Incoming messages are passed to the handler given when connecting.
+type transport interface {
+	// send delivers a message to the server
+	send(ctx context.Context, msg *Message) error
+	// done is closed when the connection is lost
+	done() <-chan struct{}
+	// err tells why the connection was lost
+	err() error
+	// close shuts the connection down
+	close() error
+}
+
+// errNotSent marks send failures where the message certainly did not reach
+// the server, so a request can safely be retried on a new connection
+var errNotSent = errors.New("message not sent")
+
+// connState tracks when a connection ends and why
+type connState struct {
+	once    sync.Once
+	closed  chan struct{}
+	mu      sync.Mutex
+	lostErr error
+}
+
+func newConnState() *connState {
+	return &connState{closed: make(chan struct{})}
+}
+
+// lose marks the connection lost; only the first reason is kept
+func (s *connState) lose(err error) {
+	s.once.Do(func() {
+		s.mu.Lock()
+		s.lostErr = err
+		s.mu.Unlock()
+		close(s.closed)
+	})
+}
+
+func (s *connState) done() <-chan struct{} { return s.closed }
+
+func (s *connState) err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lostErr
+}
+
+// maxMessageBytes caps a single message read from a server
+const maxMessageBytes = 16 << 20
+
+// stdioTransport runs a server as a child process and exchanges newline
+// delimited JSON messages over its stdin and stdout
+type stdioTransport struct {
+	*connState
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	mu    sync.Mutex
+}
+
+// startStdio launches the server described by config
+func startStdio(config ServerConfig, handle func(*Message)) (*stdioTransport, error) {
+	cmd := exec.Command(os.ExpandEnv(config.Command), expandAll(config.Args)...)
+	cmd.Dir = config.Dir
+	cmd.Env = os.Environ()
+	for key, value := range config.Env {
+		cmd.Env = append(cmd.Env, key+"="+os.ExpandEnv(value))
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", config.Command, err)
+	}
+
+	t := &stdioTransport{connState: newConnState(), cmd: cmd, stdin: stdin}
+
+	// Servers log to stderr
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		scanner.Buffer(make([]byte, 64<<10), maxMessageBytes)
+		for scanner.Scan() {
+			log.Printf("🔌 MCP %s: %s", config.Name, scanner.Text())
+		}
+	}()
+
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 64<<10), maxMessageBytes)
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			if len(line) == 0 {
+				continue
+			}
+			var msg Message
+			if err := json.Unmarshal(line, &msg); err != nil {
+				log.Printf("⚠️  MCP %s sent an invalid message: %v", config.Name, err)
+				continue
+			}
+			handle(&msg)
+		}
+		readErr := scanner.Err()
+		waitErr := cmd.Wait()
+		switch {
+		case readErr != nil:
+			t.lose(fmt.Errorf("reading from server failed: %w", readErr))
+		case waitErr != nil:
+			t.lose(fmt.Errorf("server exited: %w", waitErr))
+		default:
+			t.lose(fmt.Errorf("server exited"))
+		}
+	}()
+
+	return t, nil
+}
+
+func (t *stdioTransport) send(ctx context.Context, msg *Message) error {
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	select {
+	case <-t.closed:
+		return fmt.Errorf("%w: connection lost: %v", errNotSent, t.err())
+	default:
+	}
+	if _, err := t.stdin.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write to server: %w", err)
+	}
+	return nil
+}
+
+// close ends stdin, which asks the server to exit, and kills it if it
+// does not within a few seconds
+func (t *stdioTransport) close() error {
+	t.stdin.Close()
+	select {
+	case <-t.closed:
+	case <-time.After(5 * time.Second):
+		t.cmd.Process.Kill()
+		<-t.closed
+	}
+	return nil
+}
+
+func expandAll(values []string) []string {
+	expanded := make([]string, len(values))
+	for i, value := range values {
+		expanded[i] = os.ExpandEnv(value)
+	}
+	return expanded
+}
@@ -0,0 +1,64 @@
+package plugin
+
+import (
+	"fmt"
+
+	"github.com/go-tools-agent/internal/redact"
+)
+
+// Config holds the settings for executable plugins
+type Config struct {
+	// Dir is searched for plugin executables; plugins are disabled when
+	// it is empty
+	Dir string `json:"dir"`
+	// TimeoutSeconds bounds each call. A plugin that does not answer in
+	// time is killed and restarted on the next call.
+	TimeoutSeconds int `json:"timeoutSeconds"`
+	// DescribeTimeoutSeconds bounds the --describe run at startup
+	DescribeTimeoutSeconds int `json:"describeTimeoutSeconds"`
+	// MaxRestarts is how often a plugin may crash or time out within
+	// RestartWindowSeconds; after that it is disabled for the rest of the
+	// window
+	MaxRestarts          int `json:"maxRestarts"`
+	RestartWindowSeconds int `json:"restartWindowSeconds"`
+	// MaxOutputBytes caps a single response
+	MaxOutputBytes int `json:"maxOutputBytes"`
+	// Env sets extra environment variables for every plugin; ${NAME}
+	// references are expanded
+	Env map[string]string `json:"env,omitempty"`
+}
+
+// DefaultConfig returns the default plugin settings, with plugins disabled
+func DefaultConfig() Config {
+	return Config{
+		TimeoutSeconds:         30,
+		DescribeTimeoutSeconds: 10,
+		MaxRestarts:            3,
+		RestartWindowSeconds:   60,
+		MaxOutputBytes:         1 << 20,
+	}
+}
+
+// Validate checks the limits
+func (c Config) Validate() error {
+	if c.TimeoutSeconds <= 0 || c.DescribeTimeoutSeconds <= 0 {
+		return fmt.Errorf("timeoutSeconds and describeTimeoutSeconds must be positive")
+	}
+	if c.MaxRestarts < 0 || c.RestartWindowSeconds <= 0 {
+		return fmt.Errorf("maxRestarts must not be negative and restartWindowSeconds must be positive")
+	}
+	if c.MaxOutputBytes <= 0 {
+		return fmt.Errorf("maxOutputBytes must be positive")
+	}
+	return nil
+}
+
+// Secrets returns the values of the environment variables that env
+// references, so callers can redact them
+func Secrets(config Config) []string {
+	var secrets []string
+	for _, value := range config.Env {
+		secrets = append(secrets, redact.EnvValues(value)...)
+	}
+	return secrets
+}
@@ -0,0 +1,86 @@
+package plugin
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-tools-agent/internal/agent"
+)
+
+// Set is the plugins found in the configured directory
+type Set struct {
+	plugins []*Plugin
+}
+
+// Load describes every executable in config.Dir and returns the valid
+// plugins. Executables that fail to describe themselves, or whose tool name
+// is taken, are skipped with a warning. A nil set is returned when plugins
+// are disabled.
+func Load(config Config) (*Set, error) {
+	if config.Dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(config.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin directory: %w", err)
+	}
+
+	set := &Set{}
+	names := make(map[string]string)
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path, err := filepath.Abs(filepath.Join(config.Dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		// Stat follows symlinks, so linked executables are found too
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+			continue
+		}
+
+		manifest, err := describe(path, config)
+		if err != nil {
+			log.Printf("⚠️  Skipping plugin %s: %v", entry.Name(), err)
+			continue
+		}
+		if other, taken := names[manifest.Name]; taken {
+			log.Printf("⚠️  Skipping plugin %s: tool %s is already provided by %s", entry.Name(), manifest.Name, other)
+			continue
+		}
+		names[manifest.Name] = entry.Name()
+		set.plugins = append(set.plugins, newPlugin(path, manifest, config))
+	}
+
+	sort.Slice(set.plugins, func(i, j int) bool { return set.plugins[i].manifest.Name < set.plugins[j].manifest.Name })
+	log.Printf("🧩 Loaded %d plugins from %s", len(set.plugins), config.Dir)
+	return set, nil
+}
+
+// Tools returns a tool for every plugin
+func (s *Set) Tools() []agent.Tool {
+	if s == nil {
+		return nil
+	}
+	tools := make([]agent.Tool, len(s.plugins))
+	for i, p := range s.plugins {
+		tools[i] = p.Tool()
+	}
+	return tools
+}
+
+// Close stops all running plugin processes
+func (s *Set) Close() {
+	if s == nil {
+		return
+	}
+	for _, p := range s.plugins {
+		p.close()
+	}
+}
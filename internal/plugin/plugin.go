@@ -0,0 +1,459 @@
+// Package plugin runs tools implemented as external executables.
+//
+// A plugin is an executable that prints its manifest as JSON when run with
+// --describe:
+//
+//	{"name": "weather", "description": "...", "schema": {...}, "cacheable": true}
+//
+// Otherwise it is started once and kept running. It reads one request per
+// line on stdin and writes one response per line on stdout, in order:
+//
+//	{"id": 1, "runId": "...", "input": {...}}
+//	{"id": 1, "output": {...}}
+//	{"id": 1, "error": "message"}
+//
+// Anything written to stderr is logged.
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/go-tools-agent/internal/agent"
+	"github.com/go-tools-agent/internal/capped"
+)
+
+// Manifest describes the tool a plugin provides
+type Manifest struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Schema      json.RawMessage `json:"schema"`
+	// Cacheable marks tools without side effects, see agent.Tool
+	Cacheable bool `json:"cacheable,omitempty"`
+}
+
+// namePattern matches names the OpenAI API accepts for tools
+var namePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// request and response are the messages exchanged with a running plugin
+type request struct {
+	ID    int64           `json:"id"`
+	RunID string          `json:"runId,omitempty"`
+	Input json.RawMessage `json:"input"`
+}
+
+type response struct {
+	ID     int64           `json:"id"`
+	Output json.RawMessage `json:"output"`
+	Error  string          `json:"error"`
+}
+
+// describe runs the executable with --describe and reads its manifest
+func describe(path string, config Config) (Manifest, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.DescribeTimeoutSeconds)*time.Second)
+	defer cancel()
+
+	stdout := capped.NewBuffer(config.MaxOutputBytes)
+	stderr := capped.NewBuffer(4 << 10)
+	cmd := exec.CommandContext(ctx, path, "--describe")
+	cmd.Dir = filepath.Dir(path)
+	cmd.Env = environ(config)
+	// On timeout kill the plugin with its children, and stop waiting for
+	// output a child may hold open
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error { return killProcessGroup(cmd) }
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	// ErrWaitDelay means the plugin exited cleanly but left a child holding
+	// its output; the manifest it wrote is still used
+	if err := cmd.Run(); err != nil && !errors.Is(err, exec.ErrWaitDelay) {
+		if ctx.Err() != nil {
+			return Manifest{}, fmt.Errorf("--describe timed out")
+		}
+		return Manifest{}, fmt.Errorf("--describe failed: %v %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(stdout.Bytes(), &manifest); err != nil {
+		return Manifest{}, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if err := manifest.Validate(); err != nil {
+		return Manifest{}, err
+	}
+	return manifest, nil
+}
+
+// Validate checks the tool name, description and schema. A missing schema
+// is replaced by one for an empty object.
+func (m *Manifest) Validate() error {
+	if !namePattern.MatchString(m.Name) {
+		return fmt.Errorf("invalid tool name %q", m.Name)
+	}
+	if m.Description == "" {
+		return fmt.Errorf("manifest has no description")
+	}
+	if len(m.Schema) == 0 || string(m.Schema) == "null" {
+		m.Schema = json.RawMessage(`{"type":"object","properties":{}}`)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(m.Schema, &schema); err != nil {
+		return fmt.Errorf("schema must be a JSON object")
+	}
+	return nil
+}
+
+// Plugin is one plugin executable. Calls are handled one at a time by a
+// single long-running process, started on the first call and restarted
+// after it crashes or times out.
+type Plugin struct {
+	path     string
+	manifest Manifest
+	config   Config
+
+	// slot admits one call at a time while still honouring contexts
+	slot chan struct{}
+
+	// Guarded by slot
+	proc          *process
+	nextID        int64
+	failures      []time.Time
+	disabledUntil time.Time
+}
+
+func newPlugin(path string, manifest Manifest, config Config) *Plugin {
+	return &Plugin{path: path, manifest: manifest, config: config, slot: make(chan struct{}, 1)}
+}
+
+// Tool returns the plugin as an agent tool
+func (p *Plugin) Tool() agent.Tool {
+	return agent.Tool{
+		Name:        p.manifest.Name,
+		Description: p.manifest.Description,
+		Schema:      p.manifest.Schema,
+		Handler:     p.call,
+		Cacheable:   p.manifest.Cacheable,
+	}
+}
+
+// call sends one request to the plugin and waits for its response
+func (p *Plugin) call(ctx context.Context, input json.RawMessage) (json.RawMessage, error) {
+	select {
+	case p.slot <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-p.slot }()
+
+	if now := time.Now(); now.Before(p.disabledUntil) {
+		return nil, fmt.Errorf("plugin %s failed %d times within %ds and is disabled for another %s",
+			p.manifest.Name, p.config.MaxRestarts+1, p.config.RestartWindowSeconds, p.disabledUntil.Sub(now).Round(time.Second))
+	}
+	if len(input) == 0 {
+		input = json.RawMessage("{}")
+	}
+	// A plugin that exits cleanly after answering, such as one serving a
+	// single request per process, may be gone by the next call without
+	// having read it. The request is then sent once more to a new process.
+	for attempt := 1; ; attempt++ {
+		output, retry, err := p.exchange(ctx, input)
+		if !retry || attempt > 1 {
+			return output, err
+		}
+	}
+}
+
+// exchange sends one request, starting the process if needed, and waits
+// for the response. retry is set when the request was not handled because
+// the process had already finished cleanly.
+func (p *Plugin) exchange(ctx context.Context, input json.RawMessage) (output json.RawMessage, retry bool, err error) {
+	if p.proc == nil || p.proc.hasExited() {
+		proc, err := startProcess(p.path, p.manifest.Name, p.config)
+		if err != nil {
+			p.fail()
+			return nil, false, err
+		}
+		p.proc = proc
+	}
+	proc := p.proc
+
+	p.nextID++
+	line, err := json.Marshal(request{ID: p.nextID, RunID: agent.RunIDFromContext(ctx), Input: input})
+	if err != nil {
+		return nil, false, err
+	}
+	if err := proc.send(line); err != nil {
+		p.kill()
+		if proc.served > 0 && proc.cleanExit() {
+			return nil, true, err
+		}
+		p.fail()
+		return nil, false, fmt.Errorf("plugin %s: %w", p.manifest.Name, err)
+	}
+
+	timeout := time.Duration(p.config.TimeoutSeconds) * time.Second
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case line := <-proc.lines:
+			var resp response
+			if err := json.Unmarshal(line, &resp); err != nil {
+				p.kill()
+				p.fail()
+				return nil, false, fmt.Errorf("plugin %s sent an invalid response: %v", p.manifest.Name, err)
+			}
+			if resp.ID != p.nextID {
+				// A late answer to a call that was given up on
+				continue
+			}
+			proc.served++
+			if resp.Error != "" {
+				return nil, false, errors.New(resp.Error)
+			}
+			if len(resp.Output) == 0 {
+				return json.RawMessage("null"), false, nil
+			}
+			return resp.Output, false, nil
+
+		case <-proc.exited:
+			// Responses written just before exiting are read first
+			if len(proc.lines) > 0 {
+				continue
+			}
+			if proc.served > 0 && proc.cleanExit() {
+				return nil, true, nil
+			}
+			p.fail()
+			return nil, false, fmt.Errorf("plugin %s exited: %v", p.manifest.Name, proc.exitErr())
+
+		case <-timer.C:
+			p.kill()
+			p.fail()
+			return nil, false, fmt.Errorf("plugin %s timed out after %s", p.manifest.Name, timeout)
+
+		case <-ctx.Done():
+			// The plugin cannot be told to stop working on the request,
+			// so it is restarted; this does not count as a failure
+			p.kill()
+			return nil, false, ctx.Err()
+		}
+	}
+}
+
+// fail records a crash or timeout and disables the plugin when there were
+// too many within the restart window
+func (p *Plugin) fail() {
+	window := time.Duration(p.config.RestartWindowSeconds) * time.Second
+	now := time.Now()
+	recent := p.failures[:0]
+	for _, t := range p.failures {
+		if now.Sub(t) < window {
+			recent = append(recent, t)
+		}
+	}
+	p.failures = append(recent, now)
+	if len(p.failures) > p.config.MaxRestarts {
+		p.disabledUntil = p.failures[0].Add(window)
+		p.failures = nil
+		log.Printf("⚠️  Plugin %s failed too often; disabled until %s", p.manifest.Name, p.disabledUntil.Format(time.TimeOnly))
+	}
+}
+
+func (p *Plugin) kill() {
+	if p.proc != nil {
+		p.proc.kill()
+		p.proc = nil
+	}
+}
+
+// close stops the plugin's process, if it runs
+func (p *Plugin) close() {
+	p.slot <- struct{}{}
+	defer func() { <-p.slot }()
+	if p.proc != nil {
+		p.proc.stop()
+		p.proc = nil
+	}
+}
+
+// process is a running plugin
+type process struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	lines  chan []byte
+
+	// served counts the responses received; guarded by the plugin's slot
+	served int
+
+	exited   chan struct{}
+	killed   chan struct{}
+	killOnce sync.Once
+	mu       sync.Mutex
+	waitErr  error
+	readErr  error
+}
+
+func startProcess(path, name string, config Config) (*process, error) {
+	cmd := exec.Command(path)
+	cmd.Dir = filepath.Dir(path)
+	cmd.Env = environ(config)
+	setProcessGroup(cmd)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start plugin %s: %w", name, err)
+	}
+
+	p := &process{cmd: cmd, stdin: stdin, stdout: stdout, lines: make(chan []byte, 16), exited: make(chan struct{}), killed: make(chan struct{})}
+
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			log.Printf("🧩 Plugin %s: %s", name, scanner.Text())
+		}
+	}()
+
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, min(64<<10, config.MaxOutputBytes)), config.MaxOutputBytes)
+		for scanner.Scan() {
+			if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+				select {
+				case p.lines <- append([]byte(nil), line...):
+				case <-p.killed:
+				}
+			}
+		}
+		if err := scanner.Err(); err != nil && !p.wasKilled() {
+			// An oversized response leaves the stream unusable
+			p.mu.Lock()
+			if errors.Is(err, bufio.ErrTooLong) {
+				p.readErr = fmt.Errorf("response larger than %d bytes", config.MaxOutputBytes)
+			} else {
+				p.readErr = err
+			}
+			p.mu.Unlock()
+			cmd.Process.Kill()
+		}
+		err := cmd.Wait()
+		p.mu.Lock()
+		p.waitErr = err
+		p.mu.Unlock()
+		close(p.exited)
+	}()
+
+	return p, nil
+}
+
+func (p *process) send(line []byte) error {
+	if _, err := p.stdin.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write request: %w", err)
+	}
+	return nil
+}
+
+func (p *process) hasExited() bool {
+	select {
+	case <-p.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+// exitErr tells why the process ended
+func (p *process) exitErr() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.readErr != nil {
+		return p.readErr
+	}
+	if p.waitErr != nil {
+		return p.waitErr
+	}
+	return errors.New("exit status 0")
+}
+
+// cleanExit reports whether the process exited by itself with status 0
+func (p *process) cleanExit() bool {
+	select {
+	case <-p.exited:
+	case <-time.After(time.Second):
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.waitErr == nil && p.readErr == nil
+}
+
+// kill kills the plugin and its children. A child that left the process
+// group may still hold stdout open, so the pipe is closed to end the
+// reader, which then waits for the plugin.
+func (p *process) kill() {
+	p.killOnce.Do(func() { close(p.killed) })
+	killProcessGroup(p.cmd)
+	p.stdout.Close()
+	<-p.exited
+}
+
+func (p *process) wasKilled() bool {
+	select {
+	case <-p.killed:
+		return true
+	default:
+		return false
+	}
+}
+
+// stop closes stdin, which asks the plugin to exit, and kills it if it has
+// not within a few seconds
+func (p *process) stop() {
+	p.stdin.Close()
+	select {
+	case <-p.exited:
+	case <-time.After(3 * time.Second):
+		p.kill()
+	}
+}
+
+// inheritedEnv are the variables plugins get from the agent's environment,
+// which otherwise holds secrets such as API keys
+var inheritedEnv = []string{"PATH", "HOME", "LANG"}
+
+// environ returns the environment for plugin processes: the inherited
+// variables plus config.Env
+func environ(config Config) []string {
+	var env []string
+	for _, key := range inheritedEnv {
+		if value, ok := os.LookupEnv(key); ok {
+			env = append(env, key+"="+value)
+		}
+	}
+	for key, value := range config.Env {
+		env = append(env, key+"="+os.ExpandEnv(value))
+	}
+	return env
+}
@@ -0,0 +1,18 @@
+//go:build !unix
+
+package plugin
+
+import (
+	"os/exec"
+	"time"
+)
+
+// setProcessGroup only bounds Wait; process groups are not available
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.WaitDelay = 2 * time.Second
+}
+
+// killProcessGroup kills cmd
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
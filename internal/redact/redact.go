@@ -0,0 +1,93 @@
+// Package redact removes known secrets from text before it is logged,
+// stored in agent steps or shown to the model.
+package redact
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Placeholder replaces every redacted secret
+const Placeholder = "[REDACTED]"
+
+// minSecretLength keeps very short values, which would match ordinary
+// text, from being treated as secrets
+const minSecretLength = 4
+
+// Redactor replaces a fixed set of secrets
+type Redactor struct {
+	replacer *strings.Replacer
+}
+
+// New creates a redactor for the given secrets. Each secret is also
+// matched in its JSON-escaped form, since tool output is JSON.
+func New(secrets ...string) *Redactor {
+	seen := make(map[string]bool)
+	var forms []string
+	for _, secret := range secrets {
+		if len(secret) < minSecretLength {
+			continue
+		}
+		quoted, _ := json.Marshal(secret)
+		for _, form := range []string{secret, string(quoted[1 : len(quoted)-1])} {
+			if !seen[form] {
+				seen[form] = true
+				forms = append(forms, form)
+			}
+		}
+	}
+
+	// Longer secrets first, so one secret containing another is replaced whole
+	sort.Slice(forms, func(i, j int) bool { return len(forms[i]) > len(forms[j]) })
+	var pairs []string
+	for _, form := range forms {
+		pairs = append(pairs, form, Placeholder)
+	}
+	if len(pairs) == 0 {
+		return &Redactor{}
+	}
+	return &Redactor{replacer: strings.NewReplacer(pairs...)}
+}
+
+// String returns s with every secret replaced
+func (r *Redactor) String(s string) string {
+	if r == nil || r.replacer == nil {
+		return s
+	}
+	return r.replacer.Replace(s)
+}
+
+// Writer wraps w so everything written through it is redacted. It suits
+// line-oriented writers such as a log output, where a secret is never split
+// across writes.
+func (r *Redactor) Writer(w io.Writer) io.Writer {
+	return &writer{redactor: r, w: w}
+}
+
+type writer struct {
+	redactor *Redactor
+	w        io.Writer
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, w.redactor.String(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// EnvValues returns the values of the environment variables that value
+// references as $NAME or ${NAME}, as os.ExpandEnv would substitute them
+func EnvValues(value string) []string {
+	var values []string
+	os.Expand(value, func(name string) string {
+		if v := os.Getenv(name); v != "" {
+			values = append(values, v)
+		}
+		return ""
+	})
+	return values
+}
@@ -0,0 +1,192 @@
+// Package sandbox runs untrusted child processes with resource limits and,
+// where the host supports it, namespace-based isolation.
+//
+// Programs that use this package must call Init at the very start of main.
+// On Linux the sandbox re-executes the current binary as a small init process
+// that applies mounts, rlimits and capability drops before exec'ing the
+// target, since none of that can be done between fork and exec from Go.
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Config describes the restrictions applied to a sandboxed process. Zero
+// limits mean "unlimited".
+type Config struct {
+	Enabled      bool     `json:"enabled"`
+	CPUSeconds   uint64   `json:"cpuSeconds"`
+	MemoryBytes  uint64   `json:"memoryBytes"`
+	MaxProcesses uint64   `json:"maxProcesses"`
+	MaxFileSize  uint64   `json:"maxFileSizeBytes"`
+	Network      bool     `json:"network"`
+	ReadOnlyFS   bool     `json:"readOnlyFilesystem"`
+	EnvAllowlist []string `json:"envAllowlist"`
+	// WritablePaths are directories that stay writable, in addition to the
+	// working directory, when the filesystem is read-only
+	WritablePaths []string `json:"writablePaths,omitempty"`
+	// DataBytes caps heap and other private memory instead of address
+	// space, which suits runtimes such as Go that reserve address space
+	// up front
+	DataBytes uint64 `json:"dataBytes,omitempty"`
+}
+
+// DefaultConfig returns a restrictive configuration suitable for short scripts
+func DefaultConfig() Config {
+	return Config{
+		Enabled:      true,
+		CPUSeconds:   10,
+		MemoryBytes:  512 << 20,
+		MaxProcesses: 64,
+		MaxFileSize:  64 << 20,
+		Network:      false,
+		ReadOnlyFS:   true,
+		EnvAllowlist: []string{"PATH", "LANG", "LC_ALL", "TZ"},
+	}
+}
+
+// Isolation and limit names used in reports
+const (
+	IsolationProcessGroup = "processGroup"
+	IsolationRlimits      = "rlimits"
+	IsolationNetwork      = "network"
+	IsolationReadOnlyFS   = "readOnlyFilesystem"
+
+	LimitCPU       = "cpu"
+	LimitMemory    = "memory"
+	LimitProcesses = "processes"
+	LimitFileSize  = "fileSize"
+	LimitWallTime  = "wallTime"
+)
+
+// Report describes how a process was isolated and which limits it hit
+type Report struct {
+	Isolation      []string `json:"isolation"`
+	Unavailable    []string `json:"unavailable,omitempty"`
+	LimitsExceeded []string `json:"limitsExceeded,omitempty"`
+}
+
+// Cmd is an exec.Cmd prepared to run inside the sandbox
+type Cmd struct {
+	*exec.Cmd
+	config Config
+	report Report
+}
+
+// defaultPath is used when PATH is not passed through the env allowlist
+const defaultPath = "/usr/local/bin:/usr/bin:/bin"
+
+// initFailureExitCode is returned by the init process when it cannot apply
+// the requested isolation; the target is never started in that case
+const initFailureExitCode = 125
+
+// ErrInitNotCalled is returned when a sandboxed command is requested but the
+// program never called Init
+var ErrInitNotCalled = errors.New("sandbox: Init must be called at the start of main")
+
+// IsInitFailure reports whether a command failed because the sandbox could
+// not be set up, as opposed to the target program failing
+func IsInitFailure(err *exec.ExitError, output []byte) bool {
+	return err.ExitCode() == initFailureExitCode && bytes.HasPrefix(output, []byte("sandbox: "))
+}
+
+// filterEnv builds the child environment from the allowlist, pointing HOME
+// and TMPDIR at the writable working directory
+func filterEnv(allowlist []string, dir string) []string {
+	allowed := make(map[string]bool, len(allowlist))
+	for _, name := range allowlist {
+		allowed[name] = true
+	}
+
+	var env []string
+	hasPath := false
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if !allowed[name] || name == "HOME" || name == "TMPDIR" {
+			continue
+		}
+		if name == "PATH" {
+			hasPath = true
+		}
+		env = append(env, kv)
+	}
+	if !hasPath {
+		env = append(env, "PATH="+defaultPath)
+	}
+	return append(env, "HOME="+dir, "TMPDIR="+dir)
+}
+
+// Report returns the isolation that was applied and infers which limits the
+// process ran into from its exit status and output. It must be called after
+// the command has finished.
+func (c *Cmd) Report(ctx context.Context, output []byte) *Report {
+	report := c.report
+	report.LimitsExceeded = nil
+
+	add := func(limit string) {
+		for _, l := range report.LimitsExceeded {
+			if l == limit {
+				return
+			}
+		}
+		report.LimitsExceeded = append(report.LimitsExceeded, limit)
+	}
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		add(LimitWallTime)
+	}
+
+	if c.ProcessState != nil && c.config.Enabled {
+		for _, limit := range c.signalLimits() {
+			add(limit)
+		}
+
+		// Some runtimes catch the limit and fail with an error message
+		// instead of dying from a signal
+		if (c.config.MemoryBytes > 0 || c.config.DataBytes > 0) && containsAny(output, memoryErrors) {
+			add(LimitMemory)
+		}
+		if c.config.MaxProcesses > 0 && containsAny(output, processErrors) {
+			add(LimitProcesses)
+		}
+		if c.config.MaxFileSize > 0 && containsAny(output, fileSizeErrors) {
+			add(LimitFileSize)
+		}
+		if c.config.CPUSeconds > 0 && containsAny(output, cpuErrors) {
+			add(LimitCPU)
+		}
+	}
+
+	return &report
+}
+
+var (
+	memoryErrors = []string{
+		"MemoryError", "Cannot allocate memory", "out of memory", "std::bad_alloc",
+		"JavaScript heap out of memory", "failed to reserve virtual memory",
+		"runtime: cannot allocate memory",
+	}
+	processErrors = []string{
+		"Resource temporarily unavailable", "fork: retry", "EAGAIN",
+	}
+	fileSizeErrors = []string{
+		"File too large", "EFBIG", "File size limit exceeded",
+	}
+	cpuErrors = []string{
+		"CPU time limit exceeded",
+	}
+)
+
+func containsAny(output []byte, needles []string) bool {
+	for _, needle := range needles {
+		if bytes.Contains(output, []byte(needle)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,294 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	initArg  = "__go_tools_sandbox_init__"
+	probeArg = "__go_tools_sandbox_probe__"
+	specEnv  = "GO_TOOLS_SANDBOX_SPEC"
+)
+
+// Securebits from linux/securebits.h, not exported by x/sys/unix
+const (
+	secbitNoRoot              = 1 << 0
+	secbitNoRootLocked        = 1 << 1
+	secbitNoSetuidFixup       = 1 << 2
+	secbitNoSetuidFixupLocked = 1 << 3
+)
+
+// spec is passed from the parent to the init process
+type spec struct {
+	Config    Config `json:"config"`
+	Dir       string `json:"dir"`
+	Namespace bool   `json:"namespace"`
+}
+
+var (
+	initialized bool
+
+	probeOnce          sync.Once
+	namespaceSupported bool
+)
+
+// Init must be called at the start of main. When the process was started as
+// a sandbox init process it applies the isolation and execs the target,
+// never returning; otherwise it returns immediately.
+func Init() {
+	initialized = true
+	if len(os.Args) < 2 {
+		return
+	}
+
+	switch os.Args[1] {
+	case probeArg:
+		os.Exit(0)
+	case initArg:
+	default:
+		return
+	}
+
+	if err := runInit(); err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+		os.Exit(initFailureExitCode)
+	}
+}
+
+func runInit() error {
+	var s spec
+	if err := json.Unmarshal([]byte(os.Getenv(specEnv)), &s); err != nil {
+		return fmt.Errorf("invalid spec: %w", err)
+	}
+	os.Unsetenv(specEnv)
+
+	if len(os.Args) < 3 {
+		return fmt.Errorf("missing command")
+	}
+
+	if s.Namespace && s.Config.ReadOnlyFS {
+		if err := mountReadOnly(s.Dir, s.Config.WritablePaths); err != nil {
+			return fmt.Errorf("failed to make filesystem read-only: %w", err)
+		}
+	}
+
+	if err := setRlimits(s.Config); err != nil {
+		return err
+	}
+
+	if s.Namespace {
+		if err := dropCapabilities(); err != nil {
+			return fmt.Errorf("failed to drop capabilities: %w", err)
+		}
+	}
+
+	return syscall.Exec(os.Args[2], os.Args[2:], os.Environ())
+}
+
+// mountReadOnly remounts every mount read-only except bind mounts of dir
+// and the extra writable paths
+func mountReadOnly(dir string, writable []string) error {
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return err
+	}
+	writable = append([]string{dir}, writable...)
+	for _, path := range writable {
+		if err := unix.Mount(path, path, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if err := unix.MountSetattr(unix.AT_FDCWD, "/", unix.AT_RECURSIVE,
+		&unix.MountAttr{Attr_set: unix.MOUNT_ATTR_RDONLY}); err != nil {
+		return err
+	}
+	for _, path := range writable {
+		if err := unix.MountSetattr(unix.AT_FDCWD, path, unix.AT_RECURSIVE,
+			&unix.MountAttr{Attr_clr: unix.MOUNT_ATTR_RDONLY}); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	// The working directory was entered before the bind mount existed and
+	// still refers to the read-only mount underneath it
+	return unix.Chdir(dir)
+}
+
+func setRlimits(cfg Config) error {
+	limits := []struct {
+		resource int
+		name     string
+		soft     uint64
+		hard     uint64
+	}{
+		// A hard limit above the soft one lets SIGXCPU arrive before SIGKILL
+		{unix.RLIMIT_CPU, "cpu", cfg.CPUSeconds, cfg.CPUSeconds + 1},
+		{unix.RLIMIT_AS, "address space", cfg.MemoryBytes, cfg.MemoryBytes},
+		{unix.RLIMIT_DATA, "data", cfg.DataBytes, cfg.DataBytes},
+		{unix.RLIMIT_NPROC, "processes", cfg.MaxProcesses, cfg.MaxProcesses},
+		{unix.RLIMIT_FSIZE, "file size", cfg.MaxFileSize, cfg.MaxFileSize},
+	}
+	for _, l := range limits {
+		if l.soft == 0 {
+			continue
+		}
+		if err := unix.Setrlimit(l.resource, &unix.Rlimit{Cur: l.soft, Max: l.hard}); err != nil {
+			return fmt.Errorf("failed to set %s limit: %w", l.name, err)
+		}
+	}
+	return nil
+}
+
+// dropCapabilities makes sure the target cannot undo the isolation: the init
+// process runs as root inside the user namespace, so it locks securebits to
+// stop exec from granting capabilities to uid 0 and empties the bounding set
+func dropCapabilities() error {
+	bits := uintptr(secbitNoRoot | secbitNoRootLocked | secbitNoSetuidFixup | secbitNoSetuidFixupLocked)
+	if err := unix.Prctl(unix.PR_SET_SECUREBITS, bits, 0, 0, 0); err != nil {
+		return err
+	}
+	for c := 0; c <= unix.CAP_LAST_CAP; c++ {
+		if err := unix.Prctl(unix.PR_CAPBSET_DROP, uintptr(c), 0, 0, 0); err != nil && err != unix.EINVAL {
+			return err
+		}
+	}
+	if err := unix.Prctl(unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_CLEAR_ALL, 0, 0, 0); err != nil && err != unix.EINVAL {
+		return err
+	}
+	return unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0)
+}
+
+// namespaceAttrs returns clone attributes for a new user, mount and network
+// namespace mapping the current user to root inside it
+func namespaceAttrs(network bool) *syscall.SysProcAttr {
+	flags := uintptr(syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS)
+	if !network {
+		flags |= syscall.CLONE_NEWNET
+	}
+	return &syscall.SysProcAttr{
+		Setpgid:    true,
+		Cloneflags: flags,
+		UidMappings: []syscall.SysProcIDMap{
+			{ContainerID: 0, HostID: os.Getuid(), Size: 1},
+		},
+		GidMappings: []syscall.SysProcIDMap{
+			{ContainerID: 0, HostID: os.Getgid(), Size: 1},
+		},
+		GidMappingsEnableSetgroups: false,
+	}
+}
+
+// namespacesAvailable reports whether unprivileged user namespaces work on
+// this host; container runtimes and some distributions disable them
+func namespacesAvailable() bool {
+	probeOnce.Do(func() {
+		cmd := exec.Command("/proc/self/exe", probeArg)
+		cmd.SysProcAttr = namespaceAttrs(false)
+		namespaceSupported = cmd.Run() == nil
+	})
+	return namespaceSupported
+}
+
+// Command prepares name to run in dir under cfg. dir is the only directory
+// left writable when a read-only filesystem is requested.
+func Command(ctx context.Context, cfg Config, dir string, name string, args ...string) (*Cmd, error) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return nil, fmt.Errorf("%s not found: %w", name, err)
+	}
+
+	if !cfg.Enabled {
+		cmd := exec.CommandContext(ctx, path, args...)
+		cmd.Dir = dir
+		setProcessGroup(cmd, &syscall.SysProcAttr{Setpgid: true})
+		return &Cmd{Cmd: cmd, config: cfg, report: Report{Isolation: []string{IsolationProcessGroup}}}, nil
+	}
+
+	if !initialized {
+		return nil, ErrInitNotCalled
+	}
+
+	report := Report{Isolation: []string{IsolationProcessGroup, IsolationRlimits}}
+	attrs := &syscall.SysProcAttr{Setpgid: true}
+	useNamespace := (!cfg.Network || cfg.ReadOnlyFS) && namespacesAvailable()
+	if useNamespace {
+		attrs = namespaceAttrs(cfg.Network)
+		if !cfg.Network {
+			report.Isolation = append(report.Isolation, IsolationNetwork)
+		}
+		if cfg.ReadOnlyFS {
+			report.Isolation = append(report.Isolation, IsolationReadOnlyFS)
+		}
+	} else {
+		if !cfg.Network {
+			report.Unavailable = append(report.Unavailable, IsolationNetwork)
+		}
+		if cfg.ReadOnlyFS {
+			report.Unavailable = append(report.Unavailable, IsolationReadOnlyFS)
+		}
+	}
+
+	specJSON, err := json.Marshal(spec{Config: cfg, Dir: dir, Namespace: useNamespace})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode sandbox spec: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, "/proc/self/exe", append([]string{initArg, path}, args...)...)
+	cmd.Dir = dir
+	cmd.Env = append(filterEnv(cfg.EnvAllowlist, dir), specEnv+"="+string(specJSON))
+	setProcessGroup(cmd, attrs)
+
+	return &Cmd{Cmd: cmd, config: cfg, report: report}, nil
+}
+
+// setProcessGroup starts cmd in its own process group and makes cancellation
+// kill the whole group, so children spawned by the script do not linger
+func setProcessGroup(cmd *exec.Cmd, attrs *syscall.SysProcAttr) {
+	cmd.SysProcAttr = attrs
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = 2 * time.Second
+}
+
+// signalLimits maps a fatal signal to the limit that raised it
+func (c *Cmd) signalLimits() []string {
+	state := c.ProcessState
+	ws, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !ws.Signaled() {
+		return nil
+	}
+	switch ws.Signal() {
+	case syscall.SIGXCPU:
+		return []string{LimitCPU}
+	case syscall.SIGXFSZ:
+		return []string{LimitFileSize}
+	case syscall.SIGKILL:
+		// The hard CPU limit is one second above the soft limit
+		if c.config.CPUSeconds > 0 && uint64((state.UserTime()+state.SystemTime()).Seconds()) >= c.config.CPUSeconds {
+			return []string{LimitCPU}
+		}
+	}
+	return nil
+}
+
+// Signal returns the name of the signal that terminated the process, if any
+func (c *Cmd) Signal() string {
+	if c.ProcessState == nil {
+		return ""
+	}
+	ws, ok := c.ProcessState.Sys().(syscall.WaitStatus)
+	if !ok || !ws.Signaled() {
+		return ""
+	}
+	return unix.SignalName(ws.Signal())
+}
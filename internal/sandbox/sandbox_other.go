@@ -0,0 +1,49 @@
+//go:build !linux
+
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// Init is a no-op on platforms without sandbox support
+func Init() {}
+
+// Command prepares name to run in dir. Only the environment allowlist is
+// enforced on this platform; every other requested restriction is reported
+// as unavailable.
+func Command(ctx context.Context, cfg Config, dir string, name string, args ...string) (*Cmd, error) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return nil, fmt.Errorf("%s not found: %w", name, err)
+	}
+
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Dir = dir
+
+	report := Report{Isolation: []string{}}
+	if cfg.Enabled {
+		cmd.Env = filterEnv(cfg.EnvAllowlist, dir)
+		report.Unavailable = append(report.Unavailable, IsolationProcessGroup, IsolationRlimits)
+		if !cfg.Network {
+			report.Unavailable = append(report.Unavailable, IsolationNetwork)
+		}
+		if cfg.ReadOnlyFS {
+			report.Unavailable = append(report.Unavailable, IsolationReadOnlyFS)
+		}
+	}
+
+	return &Cmd{Cmd: cmd, config: cfg, report: report}, nil
+}
+
+// signalLimits is not supported on this platform
+func (c *Cmd) signalLimits() []string {
+	return nil
+}
+
+// Signal is not supported on this platform
+func (c *Cmd) Signal() string {
+	return ""
+}
@@ -9,39 +9,118 @@ import (
 
 // CalculatorInput represents the input schema for the calculator tool
 type CalculatorInput struct {
-	Operation string  `json:"operation"`
-	A         float64 `json:"a"`
-	B         float64 `json:"b"`
+	Operation  string      `json:"operation"`
+	A          *float64    `json:"a,omitempty"`
+	B          *float64    `json:"b,omitempty"`
+	Values     []float64   `json:"values,omitempty"`
+	X          []float64   `json:"x,omitempty"`
+	Y          []float64   `json:"y,omitempty"`
+	P          *float64    `json:"p,omitempty"`
+	Population bool        `json:"population,omitempty"`
+	Matrix     [][]float64 `json:"matrix,omitempty"`
+	MatrixB    [][]float64 `json:"matrixB,omitempty"`
+	Value      *float64    `json:"value,omitempty"`
+	From       string      `json:"from,omitempty"`
+	To         string      `json:"to,omitempty"`
 }
 
-// CalculatorOutput represents the output schema for the calculator tool
+// CalculatorOutput represents the output schema for the calculator tool.
+// Exactly one of Result, Matrix, Stats or Regression is set depending on the
+// operation; Unit accompanies Result for conversions.
 type CalculatorOutput struct {
-	Result float64 `json:"result"`
+	Result     *float64          `json:"result,omitempty"`
+	Unit       string            `json:"unit,omitempty"`
+	Matrix     [][]float64       `json:"matrix,omitempty"`
+	Stats      *DescriptiveStats `json:"stats,omitempty"`
+	Regression *Regression       `json:"regression,omitempty"`
 }
 
+var (
+	arithmeticOps = []string{"add", "subtract", "multiply", "divide"}
+	statisticsOps = []string{"sum", "mean", "median", "min", "max", "variance", "stddev", "percentile", "describe"}
+	pairOps       = []string{"correlation", "linearRegression"}
+	matrixOps     = []string{"matrixMultiply", "matrixInverse", "determinant", "transpose"}
+	unitOps       = []string{"convert"}
+)
+
 // NewCalculatorTool creates a new calculator tool
 func NewCalculatorTool() (string, string, json.RawMessage, func(context.Context, json.RawMessage) (json.RawMessage, error)) {
+	var operations []string
+	operations = append(operations, arithmeticOps...)
+	operations = append(operations, statisticsOps...)
+	operations = append(operations, pairOps...)
+	operations = append(operations, matrixOps...)
+	operations = append(operations, unitOps...)
+
+	numberArray := map[string]interface{}{
+		"type":  "array",
+		"items": map[string]interface{}{"type": "number"},
+	}
+	matrix := map[string]interface{}{
+		"type":  "array",
+		"items": numberArray,
+	}
+
 	schema := map[string]interface{}{
 		"type": "object",
 		"properties": map[string]interface{}{
 			"operation": map[string]interface{}{
-				"type": "string",
-				"enum": []string{"add", "subtract", "multiply", "divide"},
+				"type":        "string",
+				"enum":        operations,
+				"description": "The operation to perform",
 			},
 			"a": map[string]interface{}{
-				"type": "number",
+				"type":        "number",
+				"description": "First operand for add, subtract, multiply and divide",
 			},
 			"b": map[string]interface{}{
-				"type": "number",
+				"type":        "number",
+				"description": "Second operand for add, subtract, multiply and divide",
+			},
+			"values": withDescription(numberArray,
+				"Data series for sum, mean, median, min, max, variance, stddev, percentile and describe"),
+			"x": withDescription(numberArray,
+				"Independent series for correlation and linearRegression; must be the same length as y"),
+			"y": withDescription(numberArray,
+				"Dependent series for correlation and linearRegression; must be the same length as x"),
+			"p": map[string]interface{}{
+				"type":        "number",
+				"minimum":     0,
+				"maximum":     100,
+				"description": "Percentile to compute (0-100), required for percentile",
+			},
+			"population": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Use population rather than sample variance and standard deviation (default false)",
+			},
+			"matrix": withDescription(matrix,
+				"Matrix as an array of rows for matrixMultiply (left operand), matrixInverse, determinant and transpose"),
+			"matrixB": withDescription(matrix,
+				"Right operand for matrixMultiply; its row count must equal the column count of matrix"),
+			"value": map[string]interface{}{
+				"type":        "number",
+				"description": "Quantity to convert, required for convert",
+			},
+			"from": map[string]interface{}{
+				"type": "string",
+				"description": "Source unit for convert. Supports SI prefixes and compound units, " +
+					"e.g. \"km/h\", \"kg*m^2/s^2\", \"degF\", \"GiB\", \"kWh\"",
+			},
+			"to": map[string]interface{}{
+				"type":        "string",
+				"description": "Target unit for convert; must have the same dimensions as from",
 			},
 		},
-		"required": []string{"operation", "a", "b"},
+		"required": []string{"operation"},
 	}
 
 	schemaJSON, _ := json.Marshal(schema)
 
 	return "calculator",
-		"Performs basic arithmetic operations (add, subtract, multiply, divide)",
+		"Performs arithmetic (add, subtract, multiply, divide), descriptive statistics over a list of values " +
+			"(sum, mean, median, min, max, variance, stddev, percentile, describe), correlation and linear regression " +
+			"over paired x/y series, matrix operations (matrixMultiply, matrixInverse, determinant, transpose), " +
+			"and unit conversion (convert) across length, mass, time, temperature, data size, speed, energy and compound units",
 		schemaJSON,
 		func(ctx context.Context, input json.RawMessage) (json.RawMessage, error) {
 			select {
@@ -55,27 +134,11 @@ func NewCalculatorTool() (string, string, json.RawMessage, func(context.Context,
 				return nil, fmt.Errorf("invalid input: %w", err)
 			}
 
-			var result float64
-			switch params.Operation {
-			case "add":
-				result = params.A + params.B
-			case "subtract":
-				result = params.A - params.B
-			case "multiply":
-				result = params.A * params.B
-			case "divide":
-				if params.B == 0 {
-					return nil, fmt.Errorf("division by zero")
-				}
-				result = params.A / params.B
-			default:
-				return nil, fmt.Errorf("unsupported operation: %s", params.Operation)
+			output, err := calculate(params)
+			if err != nil {
+				return nil, err
 			}
 
-			// Round to 6 decimal places to avoid floating-point precision issues
-			result = math.Round(result*1000000) / 1000000
-
-			output := CalculatorOutput{Result: result}
 			outputJSON, err := json.Marshal(output)
 			if err != nil {
 				return nil, fmt.Errorf("failed to marshal output: %w", err)
@@ -83,4 +146,212 @@ func NewCalculatorTool() (string, string, json.RawMessage, func(context.Context,
 
 			return outputJSON, nil
 		}
-} 
\ No newline at end of file
+}
+
+// calculate dispatches an operation and rounds the numeric results
+func calculate(params CalculatorInput) (*CalculatorOutput, error) {
+	switch {
+	case contains(arithmeticOps, params.Operation):
+		result, err := arithmetic(params)
+		if err != nil {
+			return nil, err
+		}
+		return scalar(result), nil
+
+	case contains(statisticsOps, params.Operation):
+		return statistics(params)
+
+	case contains(pairOps, params.Operation):
+		if err := validatePair(params.X, params.Y); err != nil {
+			return nil, err
+		}
+		if params.Operation == "correlation" {
+			r, err := correlation(params.X, params.Y)
+			if err != nil {
+				return nil, err
+			}
+			return scalar(r), nil
+		}
+		reg, err := linearRegression(params.X, params.Y)
+		if err != nil {
+			return nil, err
+		}
+		reg.Slope, reg.Intercept, reg.RSquared = round(reg.Slope), round(reg.Intercept), round(reg.RSquared)
+		return &CalculatorOutput{Regression: reg}, nil
+
+	case contains(matrixOps, params.Operation):
+		return matrixOperation(params)
+
+	case contains(unitOps, params.Operation):
+		if params.Value == nil || params.From == "" || params.To == "" {
+			return nil, fmt.Errorf("operation convert requires value, from and to")
+		}
+		result, err := convertUnits(*params.Value, params.From, params.To)
+		if err != nil {
+			return nil, err
+		}
+		// Conversions span many orders of magnitude, so keep significant
+		// digits instead of a fixed number of decimal places
+		result = roundSignificant(result, 10)
+		return &CalculatorOutput{Result: &result, Unit: params.To}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported operation: %s", params.Operation)
+	}
+}
+
+func arithmetic(params CalculatorInput) (float64, error) {
+	if params.A == nil || params.B == nil {
+		return 0, fmt.Errorf("operation %s requires both a and b", params.Operation)
+	}
+	a, b := *params.A, *params.B
+
+	switch params.Operation {
+	case "add":
+		return a + b, nil
+	case "subtract":
+		return a - b, nil
+	case "multiply":
+		return a * b, nil
+	case "divide":
+		if b == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return a / b, nil
+	}
+	return 0, fmt.Errorf("unsupported operation: %s", params.Operation)
+}
+
+func statistics(params CalculatorInput) (*CalculatorOutput, error) {
+	if err := validateSeries("values", params.Values, 1); err != nil {
+		return nil, err
+	}
+	values := params.Values
+
+	switch params.Operation {
+	case "sum":
+		return scalar(sum(values)), nil
+	case "mean":
+		return scalar(mean(values)), nil
+	case "median":
+		return scalar(median(values)), nil
+	case "min", "max":
+		stats, err := describe(values, true)
+		if err != nil {
+			return nil, err
+		}
+		if params.Operation == "min" {
+			return scalar(stats.Min), nil
+		}
+		return scalar(stats.Max), nil
+	case "variance", "stddev":
+		v, err := variance(values, params.Population)
+		if err != nil {
+			return nil, err
+		}
+		if params.Operation == "stddev" {
+			v = math.Sqrt(v)
+		}
+		return scalar(v), nil
+	case "percentile":
+		if params.P == nil {
+			return nil, fmt.Errorf("operation percentile requires p")
+		}
+		v, err := percentile(values, *params.P)
+		if err != nil {
+			return nil, err
+		}
+		return scalar(v), nil
+	case "describe":
+		stats, err := describe(values, params.Population)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range []*float64{&stats.Sum, &stats.Mean, &stats.Median, &stats.Min, &stats.Max,
+			&stats.Variance, &stats.StdDev, &stats.Q1, &stats.Q3} {
+			*f = round(*f)
+		}
+		return &CalculatorOutput{Stats: stats}, nil
+	}
+	return nil, fmt.Errorf("unsupported operation: %s", params.Operation)
+}
+
+func matrixOperation(params CalculatorInput) (*CalculatorOutput, error) {
+	var result [][]float64
+
+	switch params.Operation {
+	case "transpose":
+		if _, _, err := validateMatrix("matrix", params.Matrix); err != nil {
+			return nil, err
+		}
+		result = transpose(params.Matrix)
+	case "matrixMultiply":
+		if _, _, err := validateMatrix("matrix", params.Matrix); err != nil {
+			return nil, err
+		}
+		if _, _, err := validateMatrix("matrixB", params.MatrixB); err != nil {
+			return nil, err
+		}
+		product, err := matrixMultiply(params.Matrix, params.MatrixB)
+		if err != nil {
+			return nil, err
+		}
+		result = product
+	case "matrixInverse":
+		if _, err := validateSquare("matrix", params.Matrix); err != nil {
+			return nil, err
+		}
+		inv, err := inverse(params.Matrix)
+		if err != nil {
+			return nil, err
+		}
+		result = inv
+	case "determinant":
+		if _, err := validateSquare("matrix", params.Matrix); err != nil {
+			return nil, err
+		}
+		return scalar(determinant(params.Matrix)), nil
+	default:
+		return nil, fmt.Errorf("unsupported operation: %s", params.Operation)
+	}
+
+	for _, row := range result {
+		for j := range row {
+			row[j] = round(row[j])
+		}
+	}
+	return &CalculatorOutput{Matrix: result}, nil
+}
+
+// round rounds to 6 decimal places to avoid floating-point precision issues
+func round(v float64) float64 {
+	r := math.Round(v*1000000) / 1000000
+	// Normalise negative zero so outputs never show -0
+	if r == 0 {
+		return 0
+	}
+	return r
+}
+
+func scalar(v float64) *CalculatorOutput {
+	r := round(v)
+	return &CalculatorOutput{Result: &r}
+}
+
+func withDescription(schema map[string]interface{}, description string) map[string]interface{} {
+	s := make(map[string]interface{}, len(schema)+1)
+	for k, v := range schema {
+		s[k] = v
+	}
+	s["description"] = description
+	return s
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
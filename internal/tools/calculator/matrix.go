@@ -0,0 +1,180 @@
+package tools
+
+import (
+	"fmt"
+	"math"
+)
+
+// singularTolerance returns the pivot magnitude at or below which m is
+// treated as singular. It is relative to the largest row sum of m, so
+// scaling a matrix does not change whether it counts as singular.
+func singularTolerance(m [][]float64) float64 {
+	norm := 0.0
+	for _, row := range m {
+		sum := 0.0
+		for _, v := range row {
+			sum += math.Abs(v)
+		}
+		norm = math.Max(norm, sum)
+	}
+	return 1e-12 * norm
+}
+
+// maxMatrixDim caps matrix dimensions to keep the tool's work bounded
+const maxMatrixDim = 100
+
+// validateMatrix ensures a matrix is non-empty, rectangular, finite and within size limits
+func validateMatrix(name string, m [][]float64) (rows, cols int, err error) {
+	rows = len(m)
+	if rows == 0 {
+		return 0, 0, fmt.Errorf("%s must have at least one row", name)
+	}
+	cols = len(m[0])
+	if cols == 0 {
+		return 0, 0, fmt.Errorf("%s must have at least one column", name)
+	}
+	if rows > maxMatrixDim || cols > maxMatrixDim {
+		return 0, 0, fmt.Errorf("%s is %dx%d, maximum supported size is %dx%d", name, rows, cols, maxMatrixDim, maxMatrixDim)
+	}
+	for i, row := range m {
+		if len(row) != cols {
+			return 0, 0, fmt.Errorf("%s is not rectangular: row 0 has %d columns but row %d has %d", name, cols, i, len(row))
+		}
+		for j, v := range row {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return 0, 0, fmt.Errorf("%s[%d][%d] is not a finite number", name, i, j)
+			}
+		}
+	}
+	return rows, cols, nil
+}
+
+// validateSquare ensures a matrix is valid and square
+func validateSquare(name string, m [][]float64) (int, error) {
+	rows, cols, err := validateMatrix(name, m)
+	if err != nil {
+		return 0, err
+	}
+	if rows != cols {
+		return 0, fmt.Errorf("%s must be square, got %dx%d", name, rows, cols)
+	}
+	return rows, nil
+}
+
+func newMatrix(rows, cols int) [][]float64 {
+	m := make([][]float64, rows)
+	for i := range m {
+		m[i] = make([]float64, cols)
+	}
+	return m
+}
+
+func copyMatrix(m [][]float64) [][]float64 {
+	c := make([][]float64, len(m))
+	for i, row := range m {
+		c[i] = append([]float64(nil), row...)
+	}
+	return c
+}
+
+func transpose(m [][]float64) [][]float64 {
+	t := newMatrix(len(m[0]), len(m))
+	for i, row := range m {
+		for j, v := range row {
+			t[j][i] = v
+		}
+	}
+	return t
+}
+
+// matrixMultiply returns a*b; the caller must have validated both matrices
+func matrixMultiply(a, b [][]float64) ([][]float64, error) {
+	if len(a[0]) != len(b) {
+		return nil, fmt.Errorf("cannot multiply %dx%d by %dx%d: columns of matrix must equal rows of matrixB",
+			len(a), len(a[0]), len(b), len(b[0]))
+	}
+	result := newMatrix(len(a), len(b[0]))
+	for i := range a {
+		for j := range b[0] {
+			var s float64
+			for k := range b {
+				s += a[i][k] * b[k][j]
+			}
+			result[i][j] = s
+		}
+	}
+	return result, nil
+}
+
+// determinant computes the determinant by Gaussian elimination with partial pivoting
+func determinant(m [][]float64) float64 {
+	a := copyMatrix(m)
+	n := len(a)
+	det := 1.0
+	tolerance := singularTolerance(m)
+	for col := 0; col < n; col++ {
+		pivot := col
+		for r := col + 1; r < n; r++ {
+			if math.Abs(a[r][col]) > math.Abs(a[pivot][col]) {
+				pivot = r
+			}
+		}
+		if math.Abs(a[pivot][col]) <= tolerance {
+			return 0
+		}
+		if pivot != col {
+			a[pivot], a[col] = a[col], a[pivot]
+			det = -det
+		}
+		det *= a[col][col]
+		for r := col + 1; r < n; r++ {
+			factor := a[r][col] / a[col][col]
+			for c := col; c < n; c++ {
+				a[r][c] -= factor * a[col][c]
+			}
+		}
+	}
+	return det
+}
+
+// inverse computes the matrix inverse by Gauss-Jordan elimination
+func inverse(m [][]float64) ([][]float64, error) {
+	n := len(m)
+	a := copyMatrix(m)
+	inv := newMatrix(n, n)
+	tolerance := singularTolerance(m)
+	for i := range inv {
+		inv[i][i] = 1
+	}
+
+	for col := 0; col < n; col++ {
+		pivot := col
+		for r := col + 1; r < n; r++ {
+			if math.Abs(a[r][col]) > math.Abs(a[pivot][col]) {
+				pivot = r
+			}
+		}
+		if math.Abs(a[pivot][col]) <= tolerance {
+			return nil, fmt.Errorf("matrix is singular and cannot be inverted")
+		}
+		a[pivot], a[col] = a[col], a[pivot]
+		inv[pivot], inv[col] = inv[col], inv[pivot]
+
+		p := a[col][col]
+		for c := 0; c < n; c++ {
+			a[col][c] /= p
+			inv[col][c] /= p
+		}
+		for r := 0; r < n; r++ {
+			if r == col {
+				continue
+			}
+			factor := a[r][col]
+			for c := 0; c < n; c++ {
+				a[r][c] -= factor * a[col][c]
+				inv[r][c] -= factor * inv[col][c]
+			}
+		}
+	}
+	return inv, nil
+}
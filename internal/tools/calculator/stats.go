@@ -0,0 +1,178 @@
+package tools
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// DescriptiveStats holds a summary of a data series
+type DescriptiveStats struct {
+	Count    int     `json:"count"`
+	Sum      float64 `json:"sum"`
+	Mean     float64 `json:"mean"`
+	Median   float64 `json:"median"`
+	Min      float64 `json:"min"`
+	Max      float64 `json:"max"`
+	Variance float64 `json:"variance"`
+	StdDev   float64 `json:"stddev"`
+	Q1       float64 `json:"q1"`
+	Q3       float64 `json:"q3"`
+}
+
+// Regression holds the result of a simple least-squares linear regression
+type Regression struct {
+	Slope     float64 `json:"slope"`
+	Intercept float64 `json:"intercept"`
+	RSquared  float64 `json:"rSquared"`
+}
+
+// validateSeries ensures a data series has at least min values and no NaN/Inf entries
+func validateSeries(name string, values []float64, min int) error {
+	if len(values) < min {
+		return fmt.Errorf("%s must contain at least %d values, got %d", name, min, len(values))
+	}
+	for i, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("%s[%d] is not a finite number", name, i)
+		}
+	}
+	return nil
+}
+
+// validatePair ensures two data series can be compared element-wise
+func validatePair(x, y []float64) error {
+	if err := validateSeries("x", x, 2); err != nil {
+		return err
+	}
+	if err := validateSeries("y", y, 2); err != nil {
+		return err
+	}
+	if len(x) != len(y) {
+		return fmt.Errorf("x and y must have the same length, got %d and %d", len(x), len(y))
+	}
+	return nil
+}
+
+func sum(values []float64) float64 {
+	var s float64
+	for _, v := range values {
+		s += v
+	}
+	return s
+}
+
+func mean(values []float64) float64 {
+	return sum(values) / float64(len(values))
+}
+
+// variance returns the sample variance (n-1) unless population is set
+func variance(values []float64, population bool) (float64, error) {
+	n := len(values)
+	if !population && n < 2 {
+		return 0, fmt.Errorf("sample variance requires at least 2 values")
+	}
+	m := mean(values)
+	var ss float64
+	for _, v := range values {
+		ss += (v - m) * (v - m)
+	}
+	if population {
+		return ss / float64(n), nil
+	}
+	return ss / float64(n-1), nil
+}
+
+// percentile returns the p-th percentile (0-100) using linear interpolation
+// between closest ranks, matching numpy's default method
+func percentile(values []float64, p float64) (float64, error) {
+	if p < 0 || p > 100 {
+		return 0, fmt.Errorf("percentile must be between 0 and 100, got %v", p)
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	frac := rank - float64(lower)
+	return sorted[lower] + frac*(sorted[upper]-sorted[lower]), nil
+}
+
+func median(values []float64) float64 {
+	m, _ := percentile(values, 50)
+	return m
+}
+
+// describe computes the full set of descriptive statistics for a series
+func describe(values []float64, population bool) (*DescriptiveStats, error) {
+	stats := &DescriptiveStats{
+		Count:  len(values),
+		Sum:    sum(values),
+		Mean:   mean(values),
+		Median: median(values),
+		Min:    values[0],
+		Max:    values[0],
+	}
+	for _, v := range values {
+		stats.Min = math.Min(stats.Min, v)
+		stats.Max = math.Max(stats.Max, v)
+	}
+
+	// A single value has no spread; report zero rather than failing
+	if len(values) > 1 || population {
+		v, err := variance(values, population)
+		if err != nil {
+			return nil, err
+		}
+		stats.Variance = v
+		stats.StdDev = math.Sqrt(v)
+	}
+
+	stats.Q1, _ = percentile(values, 25)
+	stats.Q3, _ = percentile(values, 75)
+	return stats, nil
+}
+
+// correlation returns the Pearson correlation coefficient of x and y
+func correlation(x, y []float64) (float64, error) {
+	mx, my := mean(x), mean(y)
+	var sxy, sxx, syy float64
+	for i := range x {
+		dx, dy := x[i]-mx, y[i]-my
+		sxy += dx * dy
+		sxx += dx * dx
+		syy += dy * dy
+	}
+	if sxx == 0 || syy == 0 {
+		return 0, fmt.Errorf("correlation is undefined when a series has zero variance")
+	}
+	return sxy / math.Sqrt(sxx*syy), nil
+}
+
+// linearRegression fits y = slope*x + intercept by ordinary least squares
+func linearRegression(x, y []float64) (*Regression, error) {
+	mx, my := mean(x), mean(y)
+	var sxy, sxx, syy float64
+	for i := range x {
+		dx, dy := x[i]-mx, y[i]-my
+		sxy += dx * dy
+		sxx += dx * dx
+		syy += dy * dy
+	}
+	if sxx == 0 {
+		return nil, fmt.Errorf("linear regression requires at least two distinct x values")
+	}
+
+	slope := sxy / sxx
+	reg := &Regression{
+		Slope:     slope,
+		Intercept: my - slope*mx,
+		RSquared:  1,
+	}
+	// A constant y is fit perfectly by a horizontal line
+	if syy != 0 {
+		reg.RSquared = (sxy * sxy) / (sxx * syy)
+	}
+	return reg, nil
+}
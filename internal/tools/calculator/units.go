@@ -0,0 +1,482 @@
+package tools
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Base dimensions tracked during dimensional analysis
+const (
+	dimLength = iota
+	dimMass
+	dimTime
+	dimTemperature
+	dimData
+	numDims
+)
+
+var dimNames = [numDims]string{"length", "mass", "time", "temperature", "data"}
+
+// dimensions holds the exponent of each base dimension
+type dimensions [numDims]int
+
+func (d dimensions) String() string {
+	var parts []string
+	for i, exp := range d {
+		switch {
+		case exp == 0:
+		case exp == 1:
+			parts = append(parts, dimNames[i])
+		default:
+			parts = append(parts, fmt.Sprintf("%s^%d", dimNames[i], exp))
+		}
+	}
+	if len(parts) == 0 {
+		return "dimensionless"
+	}
+	return strings.Join(parts, "·")
+}
+
+// unit describes how to convert a unit to SI base units: si = value*factor + offset
+type unit struct {
+	factor     float64
+	offset     float64
+	dims       dimensions
+	prefixable bool
+}
+
+func dims(length, mass, time, temperature, data int) dimensions {
+	return dimensions{length, mass, time, temperature, data}
+}
+
+var (
+	dLength      = dims(1, 0, 0, 0, 0)
+	dArea        = dims(2, 0, 0, 0, 0)
+	dVolume      = dims(3, 0, 0, 0, 0)
+	dMass        = dims(0, 1, 0, 0, 0)
+	dTime        = dims(0, 0, 1, 0, 0)
+	dTemperature = dims(0, 0, 0, 1, 0)
+	dData        = dims(0, 0, 0, 0, 1)
+	dSpeed       = dims(1, 0, -1, 0, 0)
+	dFrequency   = dims(0, 0, -1, 0, 0)
+	dForce       = dims(1, 1, -2, 0, 0)
+	dPressure    = dims(-1, 1, -2, 0, 0)
+	dEnergy      = dims(2, 1, -2, 0, 0)
+	dPower       = dims(2, 1, -3, 0, 0)
+)
+
+// unitTable maps unit symbols and names to their definitions. Prefixable
+// units also accept SI prefixes (e.g. km, ms, kJ) and, for data, binary
+// prefixes (e.g. KiB, Mibit).
+var unitTable = map[string]unit{
+	// Length
+	"m":     {factor: 1, dims: dLength, prefixable: true},
+	"meter": {factor: 1, dims: dLength},
+	"metre": {factor: 1, dims: dLength},
+	"in":    {factor: 0.0254, dims: dLength},
+	"inch":  {factor: 0.0254, dims: dLength},
+	"ft":    {factor: 0.3048, dims: dLength},
+	"foot":  {factor: 0.3048, dims: dLength},
+	"yd":    {factor: 0.9144, dims: dLength},
+	"yard":  {factor: 0.9144, dims: dLength},
+	"mi":    {factor: 1609.344, dims: dLength},
+	"mile":  {factor: 1609.344, dims: dLength},
+	"nmi":   {factor: 1852, dims: dLength},
+	"au":    {factor: 149597870700, dims: dLength},
+	"ly":    {factor: 9460730472580800, dims: dLength},
+
+	// Area and volume
+	"ha":    {factor: 10000, dims: dArea},
+	"acre":  {factor: 4046.8564224, dims: dArea},
+	"L":     {factor: 0.001, dims: dVolume, prefixable: true},
+	"l":     {factor: 0.001, dims: dVolume},
+	"liter": {factor: 0.001, dims: dVolume},
+	"litre": {factor: 0.001, dims: dVolume},
+	"gal":   {factor: 0.003785411784, dims: dVolume},
+	"floz":  {factor: 0.0000295735295625, dims: dVolume},
+
+	// Mass
+	"g":     {factor: 0.001, dims: dMass, prefixable: true},
+	"gram":  {factor: 0.001, dims: dMass},
+	"t":     {factor: 1000, dims: dMass},
+	"tonne": {factor: 1000, dims: dMass},
+	"lb":    {factor: 0.45359237, dims: dMass},
+	"pound": {factor: 0.45359237, dims: dMass},
+	"oz":    {factor: 0.028349523125, dims: dMass},
+	"ounce": {factor: 0.028349523125, dims: dMass},
+	"st":    {factor: 6.35029318, dims: dMass},
+	"stone": {factor: 6.35029318, dims: dMass},
+	"ton":   {factor: 907.18474, dims: dMass},
+
+	// Time
+	"s":      {factor: 1, dims: dTime, prefixable: true},
+	"sec":    {factor: 1, dims: dTime},
+	"second": {factor: 1, dims: dTime},
+	"min":    {factor: 60, dims: dTime},
+	"minute": {factor: 60, dims: dTime},
+	"h":      {factor: 3600, dims: dTime},
+	"hr":     {factor: 3600, dims: dTime},
+	"hour":   {factor: 3600, dims: dTime},
+	"d":      {factor: 86400, dims: dTime},
+	"day":    {factor: 86400, dims: dTime},
+	"wk":     {factor: 604800, dims: dTime},
+	"week":   {factor: 604800, dims: dTime},
+	"yr":     {factor: 31557600, dims: dTime},
+	"year":   {factor: 31557600, dims: dTime},
+	"Hz":     {factor: 1, dims: dFrequency, prefixable: true},
+
+	// Temperature
+	"K":          {factor: 1, dims: dTemperature, prefixable: true},
+	"kelvin":     {factor: 1, dims: dTemperature},
+	"degC":       {factor: 1, offset: 273.15, dims: dTemperature},
+	"°C":         {factor: 1, offset: 273.15, dims: dTemperature},
+	"C":          {factor: 1, offset: 273.15, dims: dTemperature},
+	"celsius":    {factor: 1, offset: 273.15, dims: dTemperature},
+	"degF":       {factor: 5.0 / 9.0, offset: 459.67 * 5.0 / 9.0, dims: dTemperature},
+	"°F":         {factor: 5.0 / 9.0, offset: 459.67 * 5.0 / 9.0, dims: dTemperature},
+	"F":          {factor: 5.0 / 9.0, offset: 459.67 * 5.0 / 9.0, dims: dTemperature},
+	"fahrenheit": {factor: 5.0 / 9.0, offset: 459.67 * 5.0 / 9.0, dims: dTemperature},
+	"R":          {factor: 5.0 / 9.0, dims: dTemperature},
+	"rankine":    {factor: 5.0 / 9.0, dims: dTemperature},
+
+	// Data sizes (base unit: byte)
+	"B":    {factor: 1, dims: dData, prefixable: true},
+	"byte": {factor: 1, dims: dData},
+	"bit":  {factor: 0.125, dims: dData, prefixable: true},
+
+	// Speed
+	"mph":  {factor: 0.44704, dims: dSpeed},
+	"kph":  {factor: 1000.0 / 3600.0, dims: dSpeed},
+	"kn":   {factor: 1852.0 / 3600.0, dims: dSpeed},
+	"knot": {factor: 1852.0 / 3600.0, dims: dSpeed},
+
+	// Force and pressure
+	"N":   {factor: 1, dims: dForce, prefixable: true},
+	"Pa":  {factor: 1, dims: dPressure, prefixable: true},
+	"bar": {factor: 100000, dims: dPressure, prefixable: true},
+	"atm": {factor: 101325, dims: dPressure},
+	"psi": {factor: 6894.757293168, dims: dPressure},
+
+	// Energy and power
+	"J":   {factor: 1, dims: dEnergy, prefixable: true},
+	"Wh":  {factor: 3600, dims: dEnergy, prefixable: true},
+	"cal": {factor: 4.184, dims: dEnergy, prefixable: true},
+	"Cal": {factor: 4184, dims: dEnergy},
+	"eV":  {factor: 1.602176634e-19, dims: dEnergy, prefixable: true},
+	"BTU": {factor: 1055.05585262, dims: dEnergy},
+	"erg": {factor: 1e-7, dims: dEnergy},
+	"W":   {factor: 1, dims: dPower, prefixable: true},
+	"hp":  {factor: 745.69987158227022, dims: dPower},
+}
+
+// siPrefixes lists SI prefixes; longer symbols come first so "da" wins over "d"
+var siPrefixes = []struct {
+	symbol string
+	factor float64
+}{
+	{"da", 1e1}, {"Y", 1e24}, {"Z", 1e21}, {"E", 1e18}, {"P", 1e15}, {"T", 1e12},
+	{"G", 1e9}, {"M", 1e6}, {"k", 1e3}, {"h", 1e2}, {"d", 1e-1}, {"c", 1e-2},
+	{"m", 1e-3}, {"µ", 1e-6}, {"μ", 1e-6}, {"u", 1e-6}, {"n", 1e-9}, {"p", 1e-12},
+	{"f", 1e-15}, {"a", 1e-18}, {"z", 1e-21}, {"y", 1e-24},
+}
+
+// binaryPrefixes are IEC prefixes, valid only for data units
+var binaryPrefixes = []struct {
+	symbol string
+	factor float64
+}{
+	{"Ki", 1 << 10}, {"Mi", 1 << 20}, {"Gi", 1 << 30}, {"Ti", 1 << 40}, {"Pi", 1 << 50}, {"Ei", 1 << 60},
+}
+
+// irregularPlurals maps plural unit names not formed with -s or -es to
+// their singular
+var irregularPlurals = map[string]string{
+	"feet": "foot",
+}
+
+// lookupUnit resolves a single unit symbol, applying a prefix if needed
+func lookupUnit(symbol string) (unit, error) {
+	if u, ok := unitTable[symbol]; ok {
+		return u, nil
+	}
+	for _, p := range binaryPrefixes {
+		if base, ok := unitTable[strings.TrimPrefix(symbol, p.symbol)]; ok &&
+			strings.HasPrefix(symbol, p.symbol) && base.prefixable && base.dims == dData {
+			base.factor *= p.factor
+			return base, nil
+		}
+	}
+	for _, p := range siPrefixes {
+		if !strings.HasPrefix(symbol, p.symbol) {
+			continue
+		}
+		if base, ok := unitTable[strings.TrimPrefix(symbol, p.symbol)]; ok && base.prefixable {
+			base.factor *= p.factor
+			return base, nil
+		}
+	}
+	if singular, ok := singularUnit(symbol); ok {
+		return unitTable[singular], nil
+	}
+	return unit{}, fmt.Errorf("unknown unit %q", symbol)
+}
+
+// singularUnit returns the named unit a plural spelling such as "miles",
+// "inches" or "feet" refers to
+func singularUnit(symbol string) (string, bool) {
+	if singular, ok := irregularPlurals[symbol]; ok {
+		return singular, true
+	}
+	if len(symbol) <= 2 || !strings.HasSuffix(symbol, "s") {
+		return "", false
+	}
+	// Names ending in a sibilant take -es, as in "inches"
+	if singular := strings.TrimSuffix(symbol, "es"); singular != symbol {
+		for _, ending := range []string{"ch", "sh", "s", "x", "z"} {
+			if _, ok := unitTable[singular]; ok && strings.HasSuffix(singular, ending) {
+				return singular, true
+			}
+		}
+	}
+	singular := strings.TrimSuffix(symbol, "s")
+	_, ok := unitTable[singular]
+	return singular, ok
+}
+
+// quantity is the result of parsing a unit expression
+type quantity struct {
+	factor float64
+	dims   dimensions
+	// affine is set when the expression is a single unit with an offset
+	// (e.g. °C); only then is the offset applied during conversion
+	affine *unit
+}
+
+func (q quantity) mul(o quantity, sign int) quantity {
+	r := quantity{factor: q.factor * math.Pow(o.factor, float64(sign))}
+	for i := range r.dims {
+		r.dims[i] = q.dims[i] + sign*o.dims[i]
+	}
+	return r
+}
+
+func (q quantity) pow(n int) quantity {
+	r := quantity{factor: math.Pow(q.factor, float64(n))}
+	for i := range r.dims {
+		r.dims[i] = q.dims[i] * n
+	}
+	if n == 1 {
+		r.affine = q.affine
+	}
+	return r
+}
+
+// unitParser is a small recursive-descent parser for unit expressions such
+// as "km/h", "kg*m^2/s^2", "J/(kg K)" or "m·s⁻¹"
+type unitParser struct {
+	input []rune
+	pos   int
+}
+
+// parseUnit parses a unit expression into its SI factor and dimensions
+func parseUnit(expr string) (quantity, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return quantity{}, fmt.Errorf("unit must not be empty")
+	}
+	p := &unitParser{input: []rune(expr)}
+	q, err := p.parseExpr()
+	if err != nil {
+		return quantity{}, fmt.Errorf("invalid unit %q: %w", expr, err)
+	}
+	if p.pos < len(p.input) {
+		return quantity{}, fmt.Errorf("invalid unit %q: unexpected %q", expr, string(p.input[p.pos]))
+	}
+	return q, nil
+}
+
+func (p *unitParser) peek() rune {
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *unitParser) skipSpaces() bool {
+	skipped := false
+	for p.pos < len(p.input) && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+		skipped = true
+	}
+	return skipped
+}
+
+func (p *unitParser) parseExpr() (quantity, error) {
+	p.skipSpaces()
+	q, err := p.parseFactor()
+	if err != nil {
+		return quantity{}, err
+	}
+	for {
+		spaced := p.skipSpaces()
+		sign := 1
+		switch p.peek() {
+		case '*', '·', '.':
+			p.pos++
+		case '/':
+			p.pos++
+			sign = -1
+		case 0, ')':
+			return q, nil
+		default:
+			// Juxtaposition separated by whitespace means multiplication
+			if !spaced {
+				return quantity{}, fmt.Errorf("unexpected %q", string(p.peek()))
+			}
+		}
+		p.skipSpaces()
+		next, err := p.parseFactor()
+		if err != nil {
+			return quantity{}, err
+		}
+		q = q.mul(next, sign)
+	}
+}
+
+func (p *unitParser) parseFactor() (quantity, error) {
+	base, err := p.parsePrimary()
+	if err != nil {
+		return quantity{}, err
+	}
+	exp, err := p.parseExponent()
+	if err != nil {
+		return quantity{}, err
+	}
+	return base.pow(exp), nil
+}
+
+func (p *unitParser) parsePrimary() (quantity, error) {
+	r := p.peek()
+	switch {
+	case r == '(':
+		p.pos++
+		q, err := p.parseExpr()
+		if err != nil {
+			return quantity{}, err
+		}
+		if p.peek() != ')' {
+			return quantity{}, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		// Parenthesised groups never carry an affine offset
+		q.affine = nil
+		return q, nil
+	case r == '1':
+		// Allows reciprocal units such as "1/s"
+		p.pos++
+		return quantity{factor: 1}, nil
+	case isUnitRune(r):
+		start := p.pos
+		for p.pos < len(p.input) && isUnitRune(p.input[p.pos]) {
+			p.pos++
+		}
+		symbol := string(p.input[start:p.pos])
+		u, err := lookupUnit(symbol)
+		if err != nil {
+			return quantity{}, err
+		}
+		q := quantity{factor: u.factor, dims: u.dims}
+		if u.offset != 0 {
+			q.affine = &u
+		}
+		return q, nil
+	case r == 0:
+		return quantity{}, fmt.Errorf("unexpected end of expression")
+	default:
+		return quantity{}, fmt.Errorf("unexpected %q", string(r))
+	}
+}
+
+var superscripts = map[rune]rune{
+	'⁰': '0', '¹': '1', '²': '2', '³': '3', '⁴': '4', '⁵': '5', '⁶': '6', '⁷': '7', '⁸': '8', '⁹': '9', '⁻': '-',
+}
+
+// parseExponent reads an optional "^n" or superscript exponent, defaulting to 1
+func (p *unitParser) parseExponent() (int, error) {
+	var digits []rune
+	if p.peek() == '^' {
+		p.pos++
+		for p.pos < len(p.input) && (p.input[p.pos] == '-' || unicode.IsDigit(p.input[p.pos])) {
+			digits = append(digits, p.input[p.pos])
+			p.pos++
+		}
+		if len(digits) == 0 {
+			return 0, fmt.Errorf("missing exponent after '^'")
+		}
+	} else {
+		for p.pos < len(p.input) {
+			d, ok := superscripts[p.input[p.pos]]
+			if !ok {
+				break
+			}
+			digits = append(digits, d)
+			p.pos++
+		}
+		if len(digits) == 0 {
+			return 1, nil
+		}
+	}
+	n, err := strconv.Atoi(string(digits))
+	if err != nil {
+		return 0, fmt.Errorf("invalid exponent %q", string(digits))
+	}
+	return n, nil
+}
+
+func isUnitRune(r rune) bool {
+	return unicode.IsLetter(r) || r == '°'
+}
+
+// convertUnits converts value from one unit expression to another after
+// checking that both have the same dimensions
+func convertUnits(value float64, from, to string) (float64, error) {
+	src, err := parseUnit(from)
+	if err != nil {
+		return 0, err
+	}
+	dst, err := parseUnit(to)
+	if err != nil {
+		return 0, err
+	}
+	if src.dims != dst.dims {
+		return 0, fmt.Errorf("cannot convert %s (%s) to %s (%s): incompatible dimensions", from, src.dims, to, dst.dims)
+	}
+
+	// Absolute temperatures need their offsets; anything else (including
+	// temperatures inside compound units) is a pure scale conversion
+	if src.affine != nil || dst.affine != nil {
+		si := value*src.factor + offsetOf(src)
+		return (si - offsetOf(dst)) / dst.factor, nil
+	}
+	return value * src.factor / dst.factor, nil
+}
+
+func offsetOf(q quantity) float64 {
+	if q.affine == nil {
+		return 0
+	}
+	return q.affine.offset
+}
+
+// roundSignificant rounds to the given number of significant digits, which
+// keeps very small and very large conversion results meaningful
+func roundSignificant(v float64, digits int) float64 {
+	if v == 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return v
+	}
+	r, _ := strconv.ParseFloat(strconv.FormatFloat(v, 'g', digits, 64), 64)
+	return r
+}
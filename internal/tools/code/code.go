@@ -3,48 +3,141 @@ package code
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
+
+	"github.com/go-tools-agent/internal/artifacts"
+	"github.com/go-tools-agent/internal/capped"
+	"github.com/go-tools-agent/internal/sandbox"
 )
 
 // CodeInput represents the input schema for the code execution tool
 type CodeInput struct {
-	Language string `json:"language"`
-	Code     string `json:"code"`
+	Language     string      `json:"language"`
+	Code         string      `json:"code"`
+	Session      bool        `json:"session,omitempty"`
+	Files        []InputFile `json:"files,omitempty"`
+	Dependencies []string    `json:"dependencies,omitempty"`
 }
 
 // CodeOutput represents the output schema for the code execution tool
 type CodeOutput struct {
-	Output   string `json:"output"`
-	Error    string `json:"error,omitempty"`
-	ExitCode int    `json:"exitCode"`
+	Stdout           string               `json:"stdout"`
+	Stderr           string               `json:"stderr"`
+	StdoutTruncated  bool                 `json:"stdoutTruncated,omitempty"`
+	StderrTruncated  bool                 `json:"stderrTruncated,omitempty"`
+	Error            string               `json:"error,omitempty"`
+	ExitCode         int                  `json:"exitCode"`
+	WallTimeMs       int64                `json:"wallTimeMs"`
+	CPUTimeMs        int64                `json:"cpuTimeMs"`
+	TimedOut         bool                 `json:"timedOut"`
+	KilledBySignal   string               `json:"killedBySignal,omitempty"`
+	Sandbox          *sandbox.Report      `json:"sandbox,omitempty"`
+	Session          *SessionInfo         `json:"session,omitempty"`
+	Dependencies     *DependencyInfo      `json:"dependencies,omitempty"`
+	Artifacts        []artifacts.Artifact `json:"artifacts,omitempty"`
+	ArtifactsSkipped []string             `json:"artifactsSkipped,omitempty"`
 }
 
-// NewCodeExecutionTool creates a new code execution tool
+// NewCodeExecutionTool creates a new code execution tool with the default sandbox settings
 func NewCodeExecutionTool() (string, string, json.RawMessage, func(context.Context, json.RawMessage) (json.RawMessage, error)) {
+	return NewCodeExecutionToolWithConfig(DefaultConfig(), nil, nil)
+}
+
+// NewCodeExecutionToolWithConfig creates a new code execution tool that runs
+// each language under its configured sandbox. Persistent sessions are only
+// offered when a SessionManager is given, and files written by the code are
+// only collected as artifacts when a Store is given.
+func NewCodeExecutionToolWithConfig(config Config, sessions *SessionManager, store *artifacts.Store) (string, string, json.RawMessage, func(context.Context, json.RawMessage) (json.RawMessage, error)) {
+	// Only advertise the runtimes installed on this host
+	versions := availableRuntimes(config.Runtimes)
+	languages := make([]string, 0, len(versions))
+	for language := range versions {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+
 	schema := map[string]interface{}{
 		"type": "object",
 		"properties": map[string]interface{}{
 			"language": map[string]interface{}{
-				"type": "string",
-				"enum": []string{"python", "node", "bash"},
+				"type":        "string",
+				"enum":        languages,
 				"description": "The programming language to execute",
 			},
 			"code": map[string]interface{}{
-				"type": "string",
+				"type":        "string",
 				"description": "The code to execute",
 			},
+			"files": map[string]interface{}{
+				"type":        "array",
+				"description": "Files to create in the working directory before the code runs",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"name": map[string]interface{}{
+							"type":        "string",
+							"description": "Relative path of the file, e.g. data/input.csv",
+						},
+						"content": map[string]interface{}{
+							"type":        "string",
+							"description": "The file content",
+						},
+						"encoding": map[string]interface{}{
+							"type":        "string",
+							"enum":        []string{"text", "base64"},
+							"description": "How content is encoded (default text)",
+						},
+					},
+					"required": []string{"name", "content"},
+				},
+			},
 		},
 		"required": []string{"language", "code"},
 	}
+	if sessions != nil {
+		schema["properties"].(map[string]interface{})["session"] = map[string]interface{}{
+			"type": "boolean",
+			"description": "Run in a persistent interpreter (python and node only) that keeps variables, " +
+				"functions and files in its working directory across calls during this conversation. " +
+				"The value of a trailing expression is echoed like in a REPL",
+		}
+	}
+
+	installer := newDependencyInstaller(config)
+	var allowed []string
+	for _, language := range languages {
+		if config.Dependencies.configured(language) && len(config.Dependencies.Allowlist[language]) > 0 {
+			allowed = append(allowed, fmt.Sprintf("%s: %s", language, strings.Join(config.Dependencies.Allowlist[language], ", ")))
+		}
+	}
+	if len(allowed) > 0 {
+		schema["properties"].(map[string]interface{})["dependencies"] = map[string]interface{}{
+			"type":  "array",
+			"items": map[string]interface{}{"type": "string"},
+			"description": "Packages to install before running, with optional version constraints " +
+				"(e.g. \"numpy==1.26.4\" or \"lodash@^4\"). Not available with session. Allowed packages: " +
+				strings.Join(allowed, "; "),
+		}
+	}
 
 	schemaJSON, _ := json.Marshal(schema)
 
+	installed := make([]string, len(languages))
+	for i, language := range languages {
+		installed[i] = fmt.Sprintf("%s (%s)", language, versions[language])
+	}
+
 	return "codeExecution",
-		"Executes code in various programming languages",
+		"Executes code in various programming languages inside a sandbox with CPU, memory, process and file size limits, " +
+			"no network access and a read-only filesystem apart from the working directory. " +
+			"Available runtimes: " + strings.Join(installed, ", "),
 		schemaJSON,
 		func(ctx context.Context, input json.RawMessage) (json.RawMessage, error) {
 			var params CodeInput
@@ -52,6 +145,33 @@ func NewCodeExecutionTool() (string, string, json.RawMessage, func(context.Conte
 				return nil, fmt.Errorf("invalid input: %w", err)
 			}
 
+			if params.Session {
+				if len(params.Dependencies) > 0 {
+					return nil, fmt.Errorf("dependencies cannot be used with sessions")
+				}
+				if sessions == nil {
+					return nil, fmt.Errorf("sessions are not enabled")
+				}
+				resp, err := sessions.Execute(ctx, params.Language, params.Code, params.Files, store)
+				if err != nil {
+					return nil, err
+				}
+				return json.Marshal(resp)
+			}
+
+			runtime, ok := config.Runtimes[params.Language]
+			if _, available := versions[params.Language]; !ok || !available {
+				return nil, fmt.Errorf("unsupported language: %s", params.Language)
+			}
+
+			var env *environment
+			if len(params.Dependencies) > 0 {
+				var err error
+				if env, err = installer.prepare(ctx, params.Language, params.Dependencies); err != nil {
+					return nil, err
+				}
+			}
+
 			// Create temporary directory
 			tmpDir, err := os.MkdirTemp("", "code-execution-*")
 			if err != nil {
@@ -59,50 +179,87 @@ func NewCodeExecutionTool() (string, string, json.RawMessage, func(context.Conte
 			}
 			defer os.RemoveAll(tmpDir)
 
-			var cmd *exec.Cmd
-			switch params.Language {
-			case "python":
-				scriptPath := filepath.Join(tmpDir, "script.py")
-				if err := os.WriteFile(scriptPath, []byte(params.Code), 0644); err != nil {
-					return nil, fmt.Errorf("failed to write Python script: %w", err)
-				}
-				cmd = exec.CommandContext(ctx, "python3", scriptPath)
+			sandboxConfig, err := config.SandboxFor(params.Language)
+			if err != nil {
+				return nil, err
+			}
+			timeout, err := config.TimeoutFor(params.Language)
+			if err != nil {
+				return nil, err
+			}
 
-			case "node":
-				scriptPath := filepath.Join(tmpDir, "script.js")
-				if err := os.WriteFile(scriptPath, []byte(params.Code), 0644); err != nil {
-					return nil, fmt.Errorf("failed to write Node.js script: %w", err)
+			if err := writeInputFiles(tmpDir, params.Files, config.MaxInputFileBytes); err != nil {
+				return nil, err
+			}
+			var before snapshot
+			if store != nil {
+				if before, err = takeSnapshot(tmpDir); err != nil {
+					return nil, fmt.Errorf("failed to scan working directory: %w", err)
 				}
-				cmd = exec.CommandContext(ctx, "node", scriptPath)
+			}
 
-			case "bash":
-				scriptPath := filepath.Join(tmpDir, "script.sh")
-				if err := os.WriteFile(scriptPath, []byte(params.Code), 0644); err != nil {
-					return nil, fmt.Errorf("failed to write bash script: %w", err)
-				}
-				if err := os.Chmod(scriptPath, 0755); err != nil {
-					return nil, fmt.Errorf("failed to make script executable: %w", err)
-				}
-				cmd = exec.CommandContext(ctx, "bash", scriptPath)
+			scriptName := "script" + runtime.Extension
+			scriptPath := filepath.Join(tmpDir, scriptName)
+			if err := os.WriteFile(scriptPath, []byte(params.Code), 0644); err != nil {
+				return nil, fmt.Errorf("failed to write %s script: %w", params.Language, err)
+			}
 
-			default:
-				return nil, fmt.Errorf("unsupported language: %s", params.Language)
+			sandboxConfig.WritablePaths = append(sandboxConfig.WritablePaths, runtime.WritablePaths...)
+			argv := runtime.command(scriptPath)
+			extraEnv := runtime.environment()
+			if env != nil {
+				var depsEnv []string
+				argv, depsEnv = env.apply(params.Language, argv)
+				extraEnv = append(extraEnv, depsEnv...)
+			}
+			// The deadline is reported as the wallTime limit
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			cmd, err := sandbox.Command(ctx, sandboxConfig, tmpDir, argv[0], argv[1:]...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to prepare sandbox: %w", err)
 			}
+			if len(extraEnv) > 0 {
+				if cmd.Env == nil {
+					cmd.Env = os.Environ()
+				}
+				cmd.Env = append(cmd.Env, extraEnv...)
+			}
+
+			// Capture stdout and stderr separately, each with its own cap
+			stdout := capped.NewBuffer(config.MaxStdoutBytes)
+			stderr := capped.NewBuffer(config.MaxStderrBytes)
+			cmd.Stdout = stdout
+			cmd.Stderr = stderr
 
-			// Set working directory
-			cmd.Dir = tmpDir
+			start := time.Now()
+			err = cmd.Run()
+			wallTime := time.Since(start)
 
-			// Capture output
-			output, err := cmd.CombinedOutput()
-			
 			// Prepare response
 			resp := CodeOutput{
-				Output:   strings.TrimSpace(string(output)),
-				ExitCode: 0,
+				Stdout:          outputText(stdout.Bytes(), stdout.Dropped()),
+				Stderr:          outputText(stderr.Bytes(), stderr.Dropped()),
+				StdoutTruncated: stdout.Truncated(),
+				StderrTruncated: stderr.Truncated(),
+				ExitCode:        0,
+				WallTimeMs:      wallTime.Milliseconds(),
+				TimedOut:        errors.Is(ctx.Err(), context.DeadlineExceeded),
+				Sandbox:         cmd.Report(ctx, append(append([]byte(nil), stdout.Bytes()...), stderr.Bytes()...)),
+			}
+			if env != nil {
+				resp.Dependencies = env.info
+			}
+			if state := cmd.ProcessState; state != nil {
+				resp.CPUTimeMs = (state.UserTime() + state.SystemTime()).Milliseconds()
+				resp.KilledBySignal = cmd.Signal()
 			}
 
 			if err != nil {
 				if exitErr, ok := err.(*exec.ExitError); ok {
+					if sandbox.IsInitFailure(exitErr, stderr.Bytes()) {
+						return nil, fmt.Errorf("failed to start sandbox: %s", resp.Stderr)
+					}
 					resp.ExitCode = exitErr.ExitCode()
 					resp.Error = err.Error()
 				} else {
@@ -110,6 +267,12 @@ func NewCodeExecutionTool() (string, string, json.RawMessage, func(context.Conte
 				}
 			}
 
+			if store != nil {
+				if err := attachArtifacts(ctx, &resp, store, config, tmpDir, before, map[string]bool{scriptName: true}); err != nil {
+					return nil, err
+				}
+			}
+
 			outputJSON, err := json.Marshal(resp)
 			if err != nil {
 				return nil, fmt.Errorf("failed to marshal output: %w", err)
@@ -117,4 +280,4 @@ func NewCodeExecutionTool() (string, string, json.RawMessage, func(context.Conte
 
 			return outputJSON, nil
 		}
-} 
\ No newline at end of file
+}
@@ -0,0 +1,181 @@
+package code
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-tools-agent/internal/sandbox"
+)
+
+// Config holds the settings for the code execution tool
+type Config struct {
+	// Runtimes maps each language to how its scripts are run. Entries are
+	// merged over the built-in table, so new languages can be added here.
+	Runtimes map[string]Runtime `json:"runtimes"`
+	// Sandbox applies to every language unless overridden in Languages
+	Sandbox sandbox.Config `json:"sandbox"`
+	// Languages holds per-language overrides of the sandbox settings and of
+	// TimeoutSeconds. Each entry only needs the fields that differ, e.g.
+	// {"node": {"memoryBytes": 4294967296}}
+	Languages LanguageOverrides `json:"languages"`
+	// TimeoutSeconds bounds the wall time of each run
+	TimeoutSeconds int `json:"timeoutSeconds"`
+	// MaxStdoutBytes and MaxStderrBytes cap the output kept from each stream
+	MaxStdoutBytes int `json:"maxStdoutBytes"`
+	MaxStderrBytes int `json:"maxStderrBytes"`
+	// SessionIdleSeconds is how long an unused session kernel is kept alive
+	SessionIdleSeconds int `json:"sessionIdleSeconds"`
+	// MaxInputFileBytes caps the decoded size of each input file
+	MaxInputFileBytes int `json:"maxInputFileBytes"`
+	// MaxArtifactBytes and MaxArtifacts limit the files collected after a run
+	MaxArtifactBytes int64 `json:"maxArtifactBytes"`
+	MaxArtifacts     int   `json:"maxArtifacts"`
+	// Dependencies configures installing packages requested by scripts
+	Dependencies DependencyConfig `json:"dependencies"`
+}
+
+// DefaultConfig returns the default code execution settings
+func DefaultConfig() Config {
+	return Config{
+		Runtimes: DefaultRuntimes(),
+		Sandbox:  sandbox.DefaultConfig(),
+		Languages: LanguageOverrides{
+			// V8 reserves several gigabytes of address space up front
+			"node": json.RawMessage(`{"memoryBytes": 4294967296}`),
+			// The Go toolchain compiles before running and needs far more
+			// memory and time than the script itself
+			"go": json.RawMessage(`{"memoryBytes": 4294967296, "cpuSeconds": 60, "timeoutSeconds": 90}`),
+		},
+		TimeoutSeconds: 30,
+
+		MaxStdoutBytes: 32 << 10,
+		MaxStderrBytes: 16 << 10,
+
+		SessionIdleSeconds: 600,
+
+		MaxInputFileBytes: 1 << 20,
+		MaxArtifactBytes:  5 << 20,
+		MaxArtifacts:      10,
+
+		Dependencies: DefaultDependencyConfig(),
+	}
+}
+
+// LanguageOverrides maps each language to a JSON object of settings
+type LanguageOverrides map[string]json.RawMessage
+
+// UnmarshalJSON merges the decoded overrides field by field into the ones
+// already present, so configuring a language keeps the defaults for the
+// fields it does not set. A null entry removes a language's overrides.
+func (o *LanguageOverrides) UnmarshalJSON(data []byte) error {
+	var decoded map[string]json.RawMessage
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	if *o == nil {
+		*o = make(LanguageOverrides, len(decoded))
+	}
+	for language, override := range decoded {
+		if string(override) == "null" {
+			delete(*o, language)
+			continue
+		}
+		merged, err := mergeObjects((*o)[language], override)
+		if err != nil {
+			return fmt.Errorf("invalid settings for %s: %w", language, err)
+		}
+		(*o)[language] = merged
+	}
+	return nil
+}
+
+// mergeObjects returns the JSON object base with the fields of override
+// set over it
+func mergeObjects(base, override json.RawMessage) (json.RawMessage, error) {
+	fields := make(map[string]json.RawMessage)
+	if len(base) > 0 {
+		if err := json.Unmarshal(base, &fields); err != nil {
+			return nil, err
+		}
+	}
+	var overrideFields map[string]json.RawMessage
+	if err := json.Unmarshal(override, &overrideFields); err != nil {
+		return nil, err
+	}
+	for name, value := range overrideFields {
+		// Field names match case-insensitively when decoded
+		for existing := range fields {
+			if strings.EqualFold(existing, name) {
+				delete(fields, existing)
+			}
+		}
+		fields[name] = value
+	}
+	return json.Marshal(fields)
+}
+
+// SandboxFor returns the sandbox settings for a language
+func (c Config) SandboxFor(language string) (sandbox.Config, error) {
+	cfg := c.Sandbox
+	// Copy slices so overrides never alias the shared defaults
+	cfg.EnvAllowlist = append([]string(nil), cfg.EnvAllowlist...)
+	cfg.WritablePaths = append([]string(nil), cfg.WritablePaths...)
+	if override, ok := c.Languages[language]; ok {
+		if err := json.Unmarshal(override, &cfg); err != nil {
+			return sandbox.Config{}, fmt.Errorf("invalid sandbox settings for %s: %w", language, err)
+		}
+	}
+	return cfg, nil
+}
+
+// TimeoutFor returns the wall time limit for a language
+func (c Config) TimeoutFor(language string) (time.Duration, error) {
+	limits := struct {
+		TimeoutSeconds int `json:"timeoutSeconds"`
+	}{c.TimeoutSeconds}
+	if override, ok := c.Languages[language]; ok {
+		if err := json.Unmarshal(override, &limits); err != nil {
+			return 0, fmt.Errorf("invalid settings for %s: %w", language, err)
+		}
+	}
+	return time.Duration(limits.TimeoutSeconds) * time.Second, nil
+}
+
+// Validate checks the output limits and that every language override can be decoded
+func (c Config) Validate() error {
+	if c.MaxStdoutBytes <= 0 || c.MaxStderrBytes <= 0 {
+		return fmt.Errorf("maxStdoutBytes and maxStderrBytes must be positive")
+	}
+	if c.TimeoutSeconds <= 0 {
+		return fmt.Errorf("timeoutSeconds must be positive")
+	}
+	if c.SessionIdleSeconds <= 0 {
+		return fmt.Errorf("sessionIdleSeconds must be positive")
+	}
+	if c.MaxInputFileBytes <= 0 || c.MaxArtifactBytes <= 0 || c.MaxArtifacts <= 0 {
+		return fmt.Errorf("maxInputFileBytes, maxArtifactBytes and maxArtifacts must be positive")
+	}
+	if c.Dependencies.CacheDir == "" || c.Dependencies.InstallTimeoutSeconds <= 0 {
+		return fmt.Errorf("dependencies.cacheDir and dependencies.installTimeoutSeconds must be set")
+	}
+	for language, runtime := range c.Runtimes {
+		if err := runtime.validate(); err != nil {
+			return fmt.Errorf("invalid runtime %s: %w", language, err)
+		}
+	}
+	for language := range c.Languages {
+		if _, err := c.SandboxFor(language); err != nil {
+			return err
+		}
+		timeout, err := c.TimeoutFor(language)
+		if err != nil {
+			return err
+		}
+		if timeout <= 0 {
+			return fmt.Errorf("timeoutSeconds for %s must be positive", language)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+package code
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-tools-agent/internal/capped"
+	"github.com/go-tools-agent/internal/sandbox"
+)
+
+// DependencyConfig controls installing packages for python and node scripts.
+// Packages only come from the configured local sources, never from the
+// public registries.
+type DependencyConfig struct {
+	// CacheDir holds one environment per distinct dependency set
+	CacheDir string `json:"cacheDir"`
+	// PythonWheelhouse is a directory of wheel files; PythonIndexURL is a
+	// local package index mirror. Either enables python dependencies.
+	PythonWheelhouse string `json:"pythonWheelhouse"`
+	PythonIndexURL   string `json:"pythonIndexUrl"`
+	// NodeRegistry is the URL of a local npm registry mirror.
+	//
+	// The sandbox cannot limit network access to one host, so installs
+	// from NodeRegistry or PythonIndexURL run with full network access.
+	// npm and pip only contact the configured source, and no package code
+	// runs while installing, but a host firewall is needed to enforce it.
+	NodeRegistry string `json:"nodeRegistry"`
+	// Allowlist lists the packages that may be installed, per language
+	Allowlist map[string][]string `json:"allowlist"`
+	// InstallTimeoutSeconds bounds building a new environment
+	InstallTimeoutSeconds int `json:"installTimeoutSeconds"`
+}
+
+// DefaultDependencyConfig returns settings with no package sources, which
+// leaves dependency installation disabled
+func DefaultDependencyConfig() DependencyConfig {
+	return DependencyConfig{
+		CacheDir:              filepath.Join(os.TempDir(), "go-tools-agent", "deps"),
+		Allowlist:             map[string][]string{},
+		InstallTimeoutSeconds: 120,
+	}
+}
+
+// DependencyInfo describes the environment a script ran in
+type DependencyInfo struct {
+	Environment string   `json:"environment"`
+	Packages    []string `json:"packages"`
+	Cached      bool     `json:"cached"`
+}
+
+// configured reports whether a package source is set for language
+func (c DependencyConfig) configured(language string) bool {
+	switch language {
+	case "python":
+		return c.PythonWheelhouse != "" || c.PythonIndexURL != ""
+	case "node":
+		return c.NodeRegistry != ""
+	}
+	return false
+}
+
+// source identifies where packages come from, so environments built from
+// different mirrors never share a cache entry
+func (c DependencyConfig) source(language string) string {
+	switch language {
+	case "python":
+		return c.PythonWheelhouse + "|" + c.PythonIndexURL
+	case "node":
+		return c.NodeRegistry
+	}
+	return ""
+}
+
+var (
+	// name, optional extras and version specifiers, e.g. pandas[excel]>=2.0,<3
+	pythonRequirement = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)(\[[A-Za-z0-9,._-]+\])?((?:(?:==|>=|<=|~=|!=|<|>)[A-Za-z0-9.*+!-]+,?)*)$`)
+	// optionally scoped name and version range, e.g. @scope/pkg@^1.2.0
+	nodeRequirement = regexp.MustCompile(`^((?:@[a-z0-9][a-z0-9._-]*/)?[a-z0-9][a-z0-9._-]*)(@[A-Za-z0-9.^~<>=*| -]+)?$`)
+
+	pythonNameSeparators = regexp.MustCompile(`[-_.]+`)
+)
+
+// packageName validates a requirement and returns its normalized package
+// name. Anything other than a plain name and version, such as URLs, paths
+// or installer options, is rejected.
+func packageName(language, requirement string) (string, error) {
+	switch language {
+	case "python":
+		m := pythonRequirement.FindStringSubmatch(requirement)
+		if m == nil {
+			return "", fmt.Errorf("invalid python requirement %q", requirement)
+		}
+		return normalizePythonName(m[1]), nil
+	case "node":
+		m := nodeRequirement.FindStringSubmatch(requirement)
+		if m == nil {
+			return "", fmt.Errorf("invalid node package %q", requirement)
+		}
+		return m[1], nil
+	}
+	return "", fmt.Errorf("dependencies are not supported for language: %s", language)
+}
+
+// normalizePythonName applies the PEP 503 name normalization
+func normalizePythonName(name string) string {
+	return strings.ToLower(pythonNameSeparators.ReplaceAllString(name, "-"))
+}
+
+// environment is an installed dependency set
+type environment struct {
+	id   string
+	dir  string
+	info *DependencyInfo
+}
+
+// apply points a command at the environment: python runs the virtualenv's
+// interpreter and node resolves modules from its node_modules
+func (e *environment) apply(language string, argv []string) ([]string, []string) {
+	switch language {
+	case "python":
+		argv = append([]string{filepath.Join(e.dir, "bin", "python")}, argv[1:]...)
+		return argv, []string{"VIRTUAL_ENV=" + e.dir}
+	case "node":
+		return argv, []string{"NODE_PATH=" + filepath.Join(e.dir, "node_modules")}
+	}
+	return argv, nil
+}
+
+// dependencyInstaller builds environments and caches them by dependency set
+type dependencyInstaller struct {
+	config Config
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+func newDependencyInstaller(config Config) *dependencyInstaller {
+	return &dependencyInstaller{config: config, locks: make(map[string]*sync.Mutex)}
+}
+
+// completeMarker is written once an environment has been fully installed
+const completeMarker = ".complete"
+
+// prepare returns the environment for the given requirements, installing it
+// first if no cached copy exists
+func (d *dependencyInstaller) prepare(ctx context.Context, language string, requirements []string) (*environment, error) {
+	deps := d.config.Dependencies
+	if !deps.configured(language) {
+		return nil, fmt.Errorf("dependency installation is not configured for %s", language)
+	}
+
+	allowed := make(map[string]bool)
+	for _, name := range deps.Allowlist[language] {
+		if language == "python" {
+			name = normalizePythonName(name)
+		}
+		allowed[name] = true
+	}
+
+	seen := make(map[string]bool)
+	var packages []string
+	for _, requirement := range requirements {
+		requirement = strings.TrimSpace(requirement)
+		name, err := packageName(language, requirement)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed[name] {
+			return nil, fmt.Errorf("package %s is not in the allowlist for %s", name, language)
+		}
+		if !seen[requirement] {
+			seen[requirement] = true
+			packages = append(packages, requirement)
+		}
+	}
+	sort.Strings(packages)
+
+	sum := sha256.Sum256([]byte(language + "\n" + deps.source(language) + "\n" + strings.Join(packages, "\n")))
+	id := language + "-" + hex.EncodeToString(sum[:8])
+	env := &environment{
+		id:   id,
+		dir:  filepath.Join(deps.CacheDir, id),
+		info: &DependencyInfo{Environment: id, Packages: packages},
+	}
+
+	lock := d.lock(id)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if _, err := os.Stat(filepath.Join(env.dir, completeMarker)); err == nil {
+		env.info.Cached = true
+		return env, nil
+	}
+
+	if err := os.RemoveAll(env.dir); err != nil {
+		return nil, fmt.Errorf("failed to clear environment %s: %w", id, err)
+	}
+	if err := os.MkdirAll(env.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create environment %s: %w", id, err)
+	}
+	if err := d.install(ctx, language, env.dir, packages); err != nil {
+		os.RemoveAll(env.dir)
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(env.dir, completeMarker), nil, 0644); err != nil {
+		os.RemoveAll(env.dir)
+		return nil, fmt.Errorf("failed to finish environment %s: %w", id, err)
+	}
+	return env, nil
+}
+
+func (d *dependencyInstaller) lock(id string) *sync.Mutex {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.locks[id] == nil {
+		d.locks[id] = &sync.Mutex{}
+	}
+	return d.locks[id]
+}
+
+// install runs the package manager inside the sandbox. Only binary wheels
+// are accepted and npm lifecycle scripts are disabled, so no package code
+// runs during installation.
+func (d *dependencyInstaller) install(ctx context.Context, language, dir string, packages []string) error {
+	deps := d.config.Dependencies
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(deps.InstallTimeoutSeconds)*time.Second)
+	defer cancel()
+
+	sandboxConfig, err := d.config.SandboxFor(language)
+	if err != nil {
+		return err
+	}
+	sandboxConfig.CPUSeconds = uint64(deps.InstallTimeoutSeconds)
+
+	var steps [][]string
+	switch language {
+	case "python":
+		pip := []string{filepath.Join(dir, "bin", "python"), "-m", "pip", "install",
+			"--no-cache-dir", "--disable-pip-version-check", "--only-binary", ":all:"}
+		if deps.PythonWheelhouse != "" {
+			pip = append(pip, "--find-links", deps.PythonWheelhouse)
+			if deps.PythonIndexURL == "" {
+				pip = append(pip, "--no-index")
+			}
+		}
+		if deps.PythonIndexURL != "" {
+			pip = append(pip, "--index-url", deps.PythonIndexURL)
+			sandboxConfig.Network = true
+		}
+		steps = [][]string{
+			{"python3", "-m", "venv", dir},
+			append(pip, packages...),
+		}
+	case "node":
+		sandboxConfig.Network = true
+		steps = [][]string{
+			append([]string{"npm", "install", "--prefix", dir, "--registry", deps.NodeRegistry,
+				"--cache", filepath.Join(dir, ".npm-cache"), "--ignore-scripts", "--no-audit", "--no-fund",
+				"--no-package-lock"}, packages...),
+		}
+	}
+
+	for _, argv := range steps {
+		cmd, err := sandbox.Command(ctx, sandboxConfig, dir, argv[0], argv[1:]...)
+		if err != nil {
+			return fmt.Errorf("failed to prepare sandbox: %w", err)
+		}
+		output := capped.NewBuffer(8 << 10)
+		cmd.Stdout = output
+		cmd.Stderr = output
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("failed to install %s dependencies (%v): %s", language, err, outputText(output.Bytes(), output.Dropped()))
+		}
+	}
+
+	// The npm download cache is not needed once node_modules is populated
+	return os.RemoveAll(filepath.Join(dir, ".npm-cache"))
+}
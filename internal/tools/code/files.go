@@ -0,0 +1,188 @@
+package code
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-tools-agent/internal/agent"
+	"github.com/go-tools-agent/internal/artifacts"
+)
+
+// InputFile is a file placed in the working directory before the code runs
+type InputFile struct {
+	Name     string `json:"name"`
+	Content  string `json:"content"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// fileState identifies a version of a file in the working directory
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// snapshot records the regular files in a directory tree by relative path
+type snapshot map[string]fileState
+
+// writeInputFiles decodes and writes input files under dir. Names must be
+// relative paths that stay inside dir.
+func writeInputFiles(dir string, files []InputFile, maxBytes int) error {
+	for _, f := range files {
+		name := filepath.Clean(filepath.FromSlash(f.Name))
+		if f.Name == "" || filepath.IsAbs(name) || name == "." || strings.HasPrefix(name, ".."+string(filepath.Separator)) || name == ".." {
+			return fmt.Errorf("invalid file name %q: must be a relative path inside the working directory", f.Name)
+		}
+
+		var content []byte
+		switch f.Encoding {
+		case "", "text":
+			content = []byte(f.Content)
+		case "base64":
+			decoded, err := base64.StdEncoding.DecodeString(f.Content)
+			if err != nil {
+				return fmt.Errorf("invalid base64 content for %s: %w", f.Name, err)
+			}
+			content = decoded
+		default:
+			return fmt.Errorf("unsupported encoding %q for %s", f.Encoding, f.Name)
+		}
+
+		if len(content) > maxBytes {
+			return fmt.Errorf("input file %s is %d bytes, limit is %d bytes", f.Name, len(content), maxBytes)
+		}
+
+		if err := writeInside(dir, name, content); err != nil {
+			return fmt.Errorf("failed to write input file %s: %w", f.Name, err)
+		}
+	}
+	return nil
+}
+
+// writeInside replaces the content of name under dir. Session code may have
+// left symlinks or hard links behind, so the file is opened with openInside
+// and checked before it is truncated.
+func writeInside(dir, name string, content []byte) error {
+	file, err := openInside(dir, name, true)
+	if err != nil {
+		return err
+	}
+	if err := file.Truncate(0); err != nil {
+		file.Close()
+		return err
+	}
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// readInside reads name under dir through openInside, refusing files that
+// grew past maxBytes since they were listed
+func readInside(dir, name string, maxBytes int64) ([]byte, error) {
+	file, err := openInside(dir, filepath.FromSlash(name), false)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, fmt.Errorf("exceeds the %d byte limit", maxBytes)
+	}
+	return data, nil
+}
+
+// takeSnapshot records every regular file under dir. Symlinks are never
+// followed, so code cannot point an artifact at a file outside the sandbox.
+func takeSnapshot(dir string) (snapshot, error) {
+	snap := make(snapshot)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		snap[filepath.ToSlash(rel)] = fileState{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	return snap, err
+}
+
+// attachArtifacts stores every file that is new or changed since before,
+// apart from the names in exclude, in the run's artifact store and lists
+// them in resp
+func attachArtifacts(ctx context.Context, resp *CodeOutput, store *artifacts.Store, config Config, dir string, before snapshot, exclude map[string]bool) error {
+	after, err := takeSnapshot(dir)
+	if err != nil {
+		return fmt.Errorf("failed to scan working directory: %w", err)
+	}
+
+	names := make([]string, 0, len(after))
+	for name := range after {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	runID := agent.RunIDFromContext(ctx)
+	for _, name := range names {
+		state := after[name]
+		if exclude[name] {
+			continue
+		}
+		if prev, ok := before[name]; ok && prev == state {
+			continue
+		}
+
+		if len(resp.Artifacts) >= config.MaxArtifacts {
+			resp.ArtifactsSkipped = append(resp.ArtifactsSkipped, fmt.Sprintf("%s: more than %d artifacts", name, config.MaxArtifacts))
+			continue
+		}
+		if state.size > config.MaxArtifactBytes {
+			resp.ArtifactsSkipped = append(resp.ArtifactsSkipped, fmt.Sprintf("%s: %d bytes exceeds the %d byte limit", name, state.size, config.MaxArtifactBytes))
+			continue
+		}
+
+		data, err := readInside(dir, name, config.MaxArtifactBytes)
+		if err != nil {
+			resp.ArtifactsSkipped = append(resp.ArtifactsSkipped, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		artifact, err := store.Put(runID, name, detectMIMEType(name, data), data)
+		if err != nil {
+			resp.ArtifactsSkipped = append(resp.ArtifactsSkipped, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		resp.Artifacts = append(resp.Artifacts, *artifact)
+	}
+	return nil
+}
+
+// detectMIMEType uses the file extension, falling back to content sniffing
+func detectMIMEType(name string, data []byte) string {
+	if t := mime.TypeByExtension(filepath.Ext(name)); t != "" {
+		return t
+	}
+	return http.DetectContentType(data)
+}
@@ -0,0 +1,61 @@
+//go:build !unix
+
+package code
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// openInside opens name, a clean relative path, under dir, refusing
+// symlinks in any component. Without openat the check and the open are
+// separate steps, but code here runs without a sandbox anyway. With
+// create, missing directories and the file are created and the file is
+// opened for writing without truncating it. Only regular files are returned.
+func openInside(dir, name string, create bool) (*os.File, error) {
+	parts := strings.Split(name, string(filepath.Separator))
+	path := dir
+	for i, part := range parts {
+		path = filepath.Join(path, part)
+		last := i == len(parts)-1
+		info, err := os.Lstat(path)
+		if os.IsNotExist(err) && create {
+			if last {
+				break
+			}
+			if err = os.Mkdir(path, 0755); err == nil || os.IsExist(err) {
+				info, err = os.Lstat(path)
+			}
+		}
+		current := filepath.ToSlash(filepath.Join(parts[:i+1]...))
+		switch {
+		case err != nil:
+			return nil, fmt.Errorf("%s: %w", current, err)
+		case info.Mode()&os.ModeSymlink != 0:
+			return nil, fmt.Errorf("%s is a symlink", current)
+		case !last && !info.IsDir():
+			return nil, fmt.Errorf("%s is not a directory", current)
+		}
+	}
+
+	flags := os.O_RDONLY
+	if create {
+		flags = os.O_WRONLY | os.O_CREATE
+	}
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		file.Close()
+		return nil, fmt.Errorf("%s is not a regular file", filepath.ToSlash(name))
+	}
+	return file, nil
+}
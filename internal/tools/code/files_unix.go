@@ -0,0 +1,92 @@
+//go:build unix
+
+package code
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// openInside opens name, a clean relative path, under dir. Each component
+// is opened relative to its parent with O_NOFOLLOW, so code in the sandbox
+// cannot redirect the open by swapping a directory for a symlink, even
+// between checks. With create, missing directories and the file are
+// created and the file is opened for writing; it is not truncated, so the
+// caller can check it first. Only regular files are returned.
+func openInside(dir, name string, create bool) (*os.File, error) {
+	fd, err := unix.Open(dir, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: dir, Err: err}
+	}
+
+	parts := strings.Split(name, string(filepath.Separator))
+	for i, part := range parts[:len(parts)-1] {
+		next, err := openDirAt(fd, part)
+		if errors.Is(err, unix.ENOENT) && create {
+			if err = unix.Mkdirat(fd, part, 0755); err == nil || errors.Is(err, unix.EEXIST) {
+				// Open what is there now, which may not be what was made
+				next, err = openDirAt(fd, part)
+			}
+		}
+		unix.Close(fd)
+		if err != nil {
+			return nil, pathError(filepath.Join(parts[:i+1]...), err)
+		}
+		fd = next
+	}
+
+	flags := unix.O_RDONLY
+	if create {
+		flags = unix.O_WRONLY | unix.O_CREAT
+	}
+	// O_NONBLOCK keeps a FIFO planted under the name from blocking the open
+	file, err := unix.Openat(fd, parts[len(parts)-1], flags|unix.O_NOFOLLOW|unix.O_NONBLOCK|unix.O_CLOEXEC, 0644)
+	unix.Close(fd)
+	if err != nil {
+		return nil, pathError(name, err)
+	}
+
+	var st unix.Stat_t
+	if err := unix.Fstat(file, &st); err != nil {
+		unix.Close(file)
+		return nil, pathError(name, err)
+	}
+	if st.Mode&unix.S_IFMT != unix.S_IFREG {
+		unix.Close(file)
+		return nil, fmt.Errorf("%s is not a regular file", filepath.ToSlash(name))
+	}
+	// A hard link would let a write reach a file outside the directory
+	if create && st.Nlink > 1 {
+		unix.Close(file)
+		return nil, fmt.Errorf("%s has other hard links", filepath.ToSlash(name))
+	}
+	if err := unix.SetNonblock(file, false); err != nil {
+		unix.Close(file)
+		return nil, pathError(name, err)
+	}
+	return os.NewFile(uintptr(file), filepath.Join(dir, name)), nil
+}
+
+func openDirAt(fd int, name string) (int, error) {
+	return unix.Openat(fd, name, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+}
+
+// pathError explains the errors a symlink produces under O_NOFOLLOW, and a
+// FIFO without a reader under O_NONBLOCK
+func pathError(name string, err error) error {
+	name = filepath.ToSlash(name)
+	switch {
+	case errors.Is(err, unix.ELOOP):
+		return fmt.Errorf("%s is a symlink", name)
+	case errors.Is(err, unix.ENOTDIR):
+		return fmt.Errorf("%s is not a directory", name)
+	case errors.Is(err, unix.ENXIO):
+		return fmt.Errorf("%s is not a regular file", name)
+	}
+	return fmt.Errorf("%s: %w", name, err)
+}
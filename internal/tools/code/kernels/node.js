@@ -0,0 +1,107 @@
+// Session kernel for the codeExecution tool.
+//
+// Reads one JSON request per line from stdin ({"id", "code", "maxStdout",
+// "maxStderr"}) and writes one JSON result per line to stdout. Code runs in
+// the kernel's global context, so var, function, let and const declarations
+// persist between requests. Writes to process.stdout/stderr made while a
+// request runs are captured up to maxStdout/maxStderr bytes and the rest is
+// counted as dropped; anything else reaches the host as stray output.
+const fs = require('fs');
+const readline = require('readline');
+const util = require('util');
+const vm = require('vm');
+
+globalThis.require = require;
+
+let capture = null;
+
+function newCapture(limit) {
+  return { chunks: [], size: 0, limit, dropped: 0 };
+}
+
+// append keeps data up to the capture's limit, so a chatty request cannot
+// exhaust the kernel's memory
+function append(target, data) {
+  const bytes = Buffer.from(data);
+  const room = Math.max(target.limit - target.size, 0);
+  if (bytes.length > room) {
+    target.dropped += bytes.length - room;
+  }
+  if (room > 0) {
+    const kept = bytes.subarray(0, room);
+    target.chunks.push(kept);
+    target.size += kept.length;
+  }
+}
+
+function hook(stream, original) {
+  return (chunk, encoding, callback) => {
+    if (!capture) {
+      return original(chunk, encoding, callback);
+    }
+    append(capture[stream], typeof chunk === 'string' ? Buffer.from(chunk, typeof encoding === 'string' ? encoding : 'utf8') : chunk);
+    const done = typeof encoding === 'function' ? encoding : callback;
+    if (typeof done === 'function') {
+      done();
+    }
+    return true;
+  };
+}
+
+process.stdout.write = hook('stdout', process.stdout.write.bind(process.stdout));
+process.stderr.write = hook('stderr', process.stderr.write.bind(process.stderr));
+
+function send(message) {
+  fs.writeSync(1, JSON.stringify(message) + '\n');
+}
+
+// formatError drops stack frames that belong to the kernel itself
+function formatError(e) {
+  if (!e || !e.stack) {
+    return String(e);
+  }
+  return String(e.stack)
+    .split('\n')
+    .filter((line) => !/\[eval\]|\(node:|^\s+at node:/.test(line))
+    .join('\n');
+}
+
+async function run(code) {
+  let result = vm.runInThisContext(code, { filename: '<session>' });
+  if (result && typeof result.then === 'function') {
+    result = await result;
+  }
+  if (result !== undefined) {
+    append(capture.stdout, util.inspect(result) + '\n');
+  }
+}
+
+(async () => {
+  send({ type: 'ready' });
+  const lines = readline.createInterface({ input: process.stdin, crlfDelay: Infinity });
+  for await (const line of lines) {
+    const request = JSON.parse(line);
+    capture = { stdout: newCapture(request.maxStdout), stderr: newCapture(request.maxStderr) };
+    let error = '';
+    const start = process.cpuUsage();
+    try {
+      await run(request.code);
+    } catch (e) {
+      error = formatError(e);
+    }
+    const output = capture;
+    capture = null;
+    const cpu = process.cpuUsage(start);
+
+    send({
+      type: 'result',
+      id: request.id,
+      stdout: Buffer.concat(output.stdout.chunks).toString('utf8'),
+      stderr: Buffer.concat(output.stderr.chunks).toString('utf8'),
+      stdoutDropped: output.stdout.dropped,
+      stderrDropped: output.stderr.dropped,
+      error,
+      cpuMs: Math.round((cpu.user + cpu.system) / 1000),
+    });
+  }
+})();
@@ -0,0 +1,103 @@
+# Session kernel for the codeExecution tool.
+#
+# Reads one JSON request per line from stdin ({"id", "code", "maxStdout",
+# "maxStderr"}) and writes one JSON result per line to stdout. User code runs
+# in a single namespace that persists between requests; its stdout and stderr
+# (including output from subprocesses) are captured at the file descriptor
+# level so they never mix with the protocol stream.
+import ast
+import json
+import os
+import sys
+import tempfile
+import time
+import traceback
+
+_proto_in = os.fdopen(os.dup(0), "r", encoding="utf-8")
+_proto_out = os.fdopen(os.dup(1), "w", encoding="utf-8")
+_null = os.open(os.devnull, os.O_RDONLY)
+os.dup2(_null, 0)
+os.close(_null)
+
+_namespace = {"__name__": "__main__", "__builtins__": __builtins__}
+
+
+def _send(message):
+    _proto_out.write(json.dumps(message) + "\n")
+    _proto_out.flush()
+
+
+def _collect(f, limit):
+    f.seek(0, os.SEEK_END)
+    total = f.tell()
+    f.seek(0)
+    data = f.read(limit)
+    return data.decode("utf-8", "replace"), total - len(data)
+
+
+def _run(code):
+    # Like the interactive interpreter, echo the value of a trailing expression
+    tree = ast.parse(code, "<session>", "exec")
+    last = None
+    if tree.body and isinstance(tree.body[-1], ast.Expr):
+        last = ast.Expression(tree.body.pop().value)
+    exec(compile(tree, "<session>", "exec"), _namespace)
+    if last is not None:
+        value = eval(compile(last, "<session>", "eval"), _namespace)
+        if value is not None:
+            print(repr(value))
+
+
+def _format_error():
+    etype, value, tb = sys.exc_info()
+    frames = [f for f in traceback.extract_tb(tb) if f.filename != "<string>"]
+    lines = traceback.format_exception_only(etype, value)
+    if frames:
+        lines = ["Traceback (most recent call last):\n"] + traceback.format_list(frames) + lines
+    return "".join(lines)
+
+
+_send({"type": "ready"})
+
+for line in _proto_in:
+    request = json.loads(line)
+    out = tempfile.TemporaryFile()
+    err = tempfile.TemporaryFile()
+
+    sys.stdout.flush()
+    sys.stderr.flush()
+    saved = os.dup(1), os.dup(2)
+    os.dup2(out.fileno(), 1)
+    os.dup2(err.fileno(), 2)
+
+    error = ""
+    start = time.process_time()
+    try:
+        _run(request["code"])
+    except SystemExit as e:
+        error = "SystemExit: %s" % (e.code,)
+    except BaseException:
+        error = _format_error()
+    finally:
+        sys.stdout.flush()
+        sys.stderr.flush()
+        os.dup2(saved[0], 1)
+        os.dup2(saved[1], 2)
+        os.close(saved[0])
+        os.close(saved[1])
+
+    stdout, stdout_dropped = _collect(out, request["maxStdout"])
+    stderr, stderr_dropped = _collect(err, request["maxStderr"])
+    out.close()
+    err.close()
+
+    _send({
+        "type": "result",
+        "id": request["id"],
+        "stdout": stdout,
+        "stderr": stderr,
+        "stdoutDropped": stdout_dropped,
+        "stderrDropped": stderr_dropped,
+        "error": error,
+        "cpuMs": int((time.process_time() - start) * 1000),
+    })
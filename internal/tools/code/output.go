@@ -0,0 +1,16 @@
+package code
+
+import (
+	"fmt"
+	"strings"
+)
+
+// outputText returns captured output as text, with a marker noting how
+// many bytes were cut
+func outputText(data []byte, dropped int64) string {
+	text := strings.TrimSpace(strings.ToValidUTF8(string(data), "�"))
+	if dropped > 0 {
+		text += fmt.Sprintf("\n... [truncated %d bytes]", dropped)
+	}
+	return text
+}
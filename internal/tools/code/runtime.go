@@ -0,0 +1,164 @@
+package code
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Runtime describes how to run a script in one language
+type Runtime struct {
+	// Extension is the file extension of the script, including the dot
+	Extension string `json:"extension"`
+	// Command runs the script; "{file}" is replaced by the script path
+	Command []string `json:"command"`
+	// VersionCommand prints the runtime version. It doubles as the
+	// availability check: a runtime is only offered if it succeeds.
+	VersionCommand []string `json:"versionCommand"`
+	// Env holds extra environment variables for the script
+	Env map[string]string `json:"env,omitempty"`
+	// WritablePaths are directories, such as build caches, that the script
+	// may write to in addition to its working directory. They are created
+	// on startup if missing.
+	WritablePaths []string `json:"writablePaths,omitempty"`
+}
+
+// DefaultRuntimes returns the built-in runtime table
+func DefaultRuntimes() map[string]Runtime {
+	// Go keeps its build and module caches between runs so only the first
+	// run pays for compiling the standard library. No proxy is configured,
+	// so only modules already in the cache can be imported.
+	goCache := filepath.Join(os.TempDir(), "go-tools-agent", "go")
+
+	return map[string]Runtime{
+		"python": {
+			Extension:      ".py",
+			Command:        []string{"python3", "{file}"},
+			VersionCommand: []string{"python3", "--version"},
+		},
+		"node": {
+			Extension:      ".js",
+			Command:        []string{"node", "{file}"},
+			VersionCommand: []string{"node", "--version"},
+		},
+		"bash": {
+			Extension:      ".sh",
+			Command:        []string{"bash", "{file}"},
+			VersionCommand: []string{"bash", "--version"},
+		},
+		"go": {
+			Extension:      ".go",
+			Command:        []string{"go", "run", "{file}"},
+			VersionCommand: []string{"go", "version"},
+			Env: map[string]string{
+				"GOCACHE":     filepath.Join(goCache, "build"),
+				"GOMODCACHE":  filepath.Join(goCache, "mod"),
+				"GOPATH":      filepath.Join(goCache, "path"),
+				"GOPROXY":     "off",
+				"GOTOOLCHAIN": "local",
+				"GOFLAGS":     "-mod=mod",
+				// Keeps toolchain telemetry counters out of the working directory
+				"XDG_CONFIG_HOME": filepath.Join(goCache, "config"),
+			},
+			WritablePaths: []string{goCache},
+		},
+		"ruby": {
+			Extension:      ".rb",
+			Command:        []string{"ruby", "{file}"},
+			VersionCommand: []string{"ruby", "--version"},
+		},
+		"perl": {
+			Extension:      ".pl",
+			Command:        []string{"perl", "{file}"},
+			VersionCommand: []string{"perl", "-e", "print $^V"},
+		},
+		"lua": {
+			Extension:      ".lua",
+			Command:        []string{"lua", "{file}"},
+			VersionCommand: []string{"lua", "-v"},
+		},
+	}
+}
+
+// validate checks that the runtime can be used to build a command
+func (r Runtime) validate() error {
+	if r.Extension == "" || !strings.HasPrefix(r.Extension, ".") {
+		return fmt.Errorf("extension must start with a dot")
+	}
+	if len(r.Command) == 0 {
+		return fmt.Errorf("command is required")
+	}
+	if len(r.VersionCommand) == 0 {
+		return fmt.Errorf("versionCommand is required")
+	}
+	return nil
+}
+
+// command returns the argv that runs scriptPath
+func (r Runtime) command(scriptPath string) []string {
+	argv := make([]string, len(r.Command))
+	for i, arg := range r.Command {
+		argv[i] = strings.ReplaceAll(arg, "{file}", scriptPath)
+	}
+	return argv
+}
+
+// environment returns the runtime's extra variables in a stable order
+func (r Runtime) environment() []string {
+	env := make([]string, 0, len(r.Env))
+	for name, value := range r.Env {
+		env = append(env, name+"="+value)
+	}
+	sort.Strings(env)
+	return env
+}
+
+// versionProbeTimeout bounds each version command run at startup
+const versionProbeTimeout = 10 * time.Second
+
+// probeVersion runs the version command and returns its first line of
+// output, or an error if the runtime is not installed
+func (r Runtime) probeVersion() (string, error) {
+	if _, err := exec.LookPath(r.VersionCommand[0]); err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), versionProbeTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, r.VersionCommand[0], r.VersionCommand[1:]...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %w", strings.Join(r.VersionCommand, " "), err)
+	}
+
+	version, _, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+	return strings.TrimSpace(version), nil
+}
+
+// availableRuntimes probes every configured runtime and returns the version
+// of each one installed on the host, keyed by language
+func availableRuntimes(runtimes map[string]Runtime) map[string]string {
+	available := make(map[string]string)
+	for language, runtime := range runtimes {
+		version, err := runtime.probeVersion()
+		if err != nil || createPaths(runtime.WritablePaths) != nil {
+			continue
+		}
+		available[language] = version
+	}
+	return available
+}
+
+// createPaths makes sure every writable path exists so it can be mounted
+func createPaths(paths []string) error {
+	for _, path := range paths {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return err
+		}
+	}
+	return nil
+}
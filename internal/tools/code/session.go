@@ -0,0 +1,553 @@
+package code
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-tools-agent/internal/agent"
+	"github.com/go-tools-agent/internal/artifacts"
+	"github.com/go-tools-agent/internal/capped"
+	"github.com/go-tools-agent/internal/sandbox"
+)
+
+var (
+	//go:embed kernels/python.py
+	pythonKernel string
+	//go:embed kernels/node.js
+	nodeKernel string
+)
+
+// kernelCommands lists the languages that support sessions and how to start their kernel
+var kernelCommands = map[string][]string{
+	"python": {"python3", "-u", "-c", pythonKernel},
+	"node":   {"node", "-e", nodeKernel},
+}
+
+const (
+	// kernelStartTimeout bounds how long a kernel may take to report ready
+	kernelStartTimeout = 15 * time.Second
+	// maxKernelLine is the longest protocol line accepted from a kernel
+	maxKernelLine = 1 << 20
+	// maxStrayOutput caps the output kept between calls that the kernel
+	// could not attribute to a request
+	maxStrayOutput = 16 << 10
+)
+
+// SessionInfo describes the session a call ran in
+type SessionInfo struct {
+	ID             string `json:"id"`
+	ExecutionCount int    `json:"executionCount"`
+}
+
+// kernelRequest is sent to a kernel on stdin
+type kernelRequest struct {
+	ID        int    `json:"id"`
+	Code      string `json:"code"`
+	MaxStdout int    `json:"maxStdout"`
+	MaxStderr int    `json:"maxStderr"`
+}
+
+// kernelMessage is read from a kernel's stdout
+type kernelMessage struct {
+	Type          string `json:"type"`
+	ID            int    `json:"id"`
+	Stdout        string `json:"stdout"`
+	Stderr        string `json:"stderr"`
+	StdoutDropped int64  `json:"stdoutDropped"`
+	StderrDropped int64  `json:"stderrDropped"`
+	Error         string `json:"error"`
+	CPUMs         int64  `json:"cpuMs"`
+}
+
+// sessionKey identifies a kernel: one per language per agent run
+type sessionKey struct {
+	runID    string
+	language string
+}
+
+// SessionManager keeps long-lived interpreter kernels so that variables and
+// files survive between codeExecution calls within an agent run. Kernels are
+// stopped when their run ends or after sitting idle.
+type SessionManager struct {
+	config Config
+
+	mu       sync.Mutex
+	sessions map[sessionKey]*kernel
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewSessionManager creates a session manager and starts its idle reaper
+func NewSessionManager(config Config) *SessionManager {
+	m := &SessionManager{
+		config:   config,
+		sessions: make(map[sessionKey]*kernel),
+		stop:     make(chan struct{}),
+	}
+	go m.reapIdle()
+	return m
+}
+
+// EndRun stops every kernel belonging to a run. It is meant to be registered
+// as an agent RunEndHook.
+func (m *SessionManager) EndRun(runID string) {
+	m.mu.Lock()
+	var ended []*kernel
+	for key, k := range m.sessions {
+		if key.runID == runID {
+			ended = append(ended, k)
+			delete(m.sessions, key)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, k := range ended {
+		k.close()
+	}
+}
+
+// Close stops all kernels and the idle reaper
+func (m *SessionManager) Close() {
+	m.stopOnce.Do(func() { close(m.stop) })
+
+	m.mu.Lock()
+	sessions := m.sessions
+	m.sessions = make(map[sessionKey]*kernel)
+	m.mu.Unlock()
+
+	for _, k := range sessions {
+		k.close()
+	}
+}
+
+func (m *SessionManager) reapIdle() {
+	idle := time.Duration(m.config.SessionIdleSeconds) * time.Second
+	ticker := time.NewTicker(idle / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+		}
+
+		m.mu.Lock()
+		var expired []*kernel
+		for key, k := range m.sessions {
+			if k.idleFor() > idle {
+				expired = append(expired, k)
+				delete(m.sessions, key)
+			}
+		}
+		m.mu.Unlock()
+
+		for _, k := range expired {
+			k.close()
+		}
+	}
+}
+
+// Execute runs code in the run's kernel for language, starting one if needed
+func (m *SessionManager) Execute(ctx context.Context, language, code string, files []InputFile, store *artifacts.Store) (*CodeOutput, error) {
+	argv, ok := kernelCommands[language]
+	if !ok {
+		return nil, fmt.Errorf("sessions are not supported for language: %s", language)
+	}
+
+	key := sessionKey{runID: agent.RunIDFromContext(ctx), language: language}
+	timeout, err := m.config.TimeoutFor(language)
+	if err != nil {
+		return nil, err
+	}
+
+	k, err := m.acquire(key, language, argv)
+	if err != nil {
+		return nil, err
+	}
+	defer k.release()
+
+	if err := writeInputFiles(k.dir, files, m.config.MaxInputFileBytes); err != nil {
+		return nil, err
+	}
+	var before snapshot
+	if store != nil {
+		if before, err = takeSnapshot(k.dir); err != nil {
+			return nil, fmt.Errorf("failed to scan session directory: %w", err)
+		}
+	}
+
+	// The deadline is reported as the wallTime limit
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	output, err := k.execute(ctx, code, m.config.MaxStdoutBytes, m.config.MaxStderrBytes)
+	// A kernel that died or timed out has been closed and its directory
+	// removed, so there are no files to collect
+	if err == nil && store != nil && k.alive() {
+		err = attachArtifacts(ctx, output, store, m.config, k.dir, before, nil)
+	}
+
+	// A kernel that died or timed out has lost its state; drop it so the
+	// next call starts fresh
+	if !k.alive() {
+		m.mu.Lock()
+		if m.sessions[key] == k {
+			delete(m.sessions, key)
+		}
+		m.mu.Unlock()
+	}
+	return output, err
+}
+
+// acquire returns the kernel for key, starting one if there is none, and
+// marks it busy so the idle reaper leaves it alone until it is released.
+// Kernels are started without holding the lock, so a slow start does not
+// hold up other runs.
+func (m *SessionManager) acquire(key sessionKey, language string, argv []string) (*kernel, error) {
+	if k := m.lookup(key); k != nil {
+		return k, nil
+	}
+
+	sandboxConfig, err := m.config.SandboxFor(language)
+	if err != nil {
+		return nil, err
+	}
+	started, err := startKernel(sandboxConfig, argv)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	select {
+	case <-m.stop:
+		m.mu.Unlock()
+		started.close()
+		return nil, fmt.Errorf("session manager is closed")
+	default:
+	}
+	// Another call for the same run may have started a kernel meanwhile
+	k := m.sessions[key]
+	if k == nil || !k.alive() {
+		k = started
+		m.sessions[key] = k
+	}
+	k.acquire()
+	m.mu.Unlock()
+
+	if k != started {
+		started.close()
+	}
+	return k, nil
+}
+
+// lookup returns the running kernel for key, marked busy, dropping it if
+// it has died
+func (m *SessionManager) lookup(key sessionKey) *kernel {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := m.sessions[key]
+	if k == nil {
+		return nil
+	}
+	if !k.alive() {
+		delete(m.sessions, key)
+		return nil
+	}
+	k.acquire()
+	return k
+}
+
+// kernel is a running interpreter process speaking the line-delimited JSON protocol
+type kernel struct {
+	id     string
+	dir    string
+	cmd    *sandbox.Cmd
+	ctx    context.Context
+	cancel context.CancelFunc
+	stdin  io.WriteCloser
+
+	results chan kernelMessage
+	done    chan struct{}
+
+	// execMu serialises executions; calls from one run are sequential anyway
+	execMu     sync.Mutex
+	nextID     int
+	executions int
+
+	mu       sync.Mutex
+	lastUsed time.Time
+	// busy counts the calls using the kernel; a busy kernel is never idle
+	busy   int
+	stray  *capped.Buffer
+	closed bool
+}
+
+func startKernel(sandboxConfig sandbox.Config, argv []string) (*kernel, error) {
+	dir, err := os.MkdirTemp("", "code-session-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %w", err)
+	}
+
+	// The kernel outlives any single call, so it gets its own context
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd, err := sandbox.Command(ctx, sandboxConfig, dir, argv[0], argv[1:]...)
+	if err != nil {
+		cancel()
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to prepare sandbox: %w", err)
+	}
+
+	k := &kernel{
+		id:       filepath.Base(dir),
+		ctx:      ctx,
+		dir:      dir,
+		cmd:      cmd,
+		cancel:   cancel,
+		results:  make(chan kernelMessage, 1),
+		done:     make(chan struct{}),
+		lastUsed: time.Now(),
+		stray:    capped.NewBuffer(maxStrayOutput),
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		k.close()
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		k.close()
+		return nil, err
+	}
+	k.stdin = stdin
+	cmd.Stderr = k.strayWriter()
+
+	if err := cmd.Start(); err != nil {
+		k.close()
+		return nil, fmt.Errorf("failed to start kernel: %w", err)
+	}
+
+	go k.readLoop(stdout)
+
+	select {
+	case msg := <-k.results:
+		if msg.Type != "ready" {
+			k.close()
+			return nil, fmt.Errorf("kernel sent unexpected message before ready: %s", msg.Type)
+		}
+	case <-k.done:
+		defer k.close()
+		return nil, fmt.Errorf("kernel exited during startup: %s", k.takeStray())
+	case <-time.After(kernelStartTimeout):
+		k.close()
+		return nil, fmt.Errorf("kernel did not start within %s", kernelStartTimeout)
+	}
+
+	return k, nil
+}
+
+// readLoop forwards protocol messages and keeps any other output as stray
+func (k *kernel) readLoop(stdout io.Reader) {
+	defer func() {
+		k.cmd.Wait()
+		close(k.done)
+	}()
+
+	// Result lines are bounded by the output caps; anything longer is stray
+	// output without newlines and is passed through in pieces
+	reader := bufio.NewReaderSize(stdout, maxKernelLine)
+	for {
+		line, err := reader.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			k.strayWriter().Write(line)
+			continue
+		}
+		if len(line) > 0 {
+			var msg kernelMessage
+			if json.Unmarshal(line, &msg) == nil && (msg.Type == "ready" || msg.Type == "result") {
+				select {
+				case k.results <- msg:
+				case <-k.ctx.Done():
+				}
+			} else {
+				k.strayWriter().Write(line)
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// strayWriter collects output the kernel could not attribute to a request,
+// such as writes from background timers or child processes
+func (k *kernel) strayWriter() io.Writer {
+	return writerFunc(func(p []byte) (int, error) {
+		k.mu.Lock()
+		defer k.mu.Unlock()
+		return k.stray.Write(p)
+	})
+}
+
+func (k *kernel) takeStray() string {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	text := outputText(k.stray.Bytes(), k.stray.Dropped())
+	k.stray = capped.NewBuffer(maxStrayOutput)
+	return text
+}
+
+func (k *kernel) execute(ctx context.Context, code string, maxStdout, maxStderr int) (*CodeOutput, error) {
+	k.execMu.Lock()
+	defer k.execMu.Unlock()
+	defer k.touch()
+
+	k.nextID++
+	k.executions++
+	request, err := json.Marshal(kernelRequest{ID: k.nextID, Code: code, MaxStdout: maxStdout, MaxStderr: maxStderr})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	start := time.Now()
+	if _, err := k.stdin.Write(append(request, '\n')); err != nil {
+		return nil, fmt.Errorf("failed to send code to kernel: %w", err)
+	}
+
+	resp := &CodeOutput{
+		Session: &SessionInfo{ID: k.id, ExecutionCount: k.executions},
+	}
+
+	for {
+		select {
+		case msg := <-k.results:
+			if msg.Type != "result" || msg.ID != k.nextID {
+				continue
+			}
+			// The kernel already capped its output; stray output is added to
+			// stderr within the same cap
+			stderr := capped.NewBuffer(maxStderr)
+			stderr.Write([]byte(msg.Stderr))
+			if stray := k.takeStray(); stray != "" {
+				stderr.Write([]byte("\n" + stray))
+			}
+			stderrDropped := msg.StderrDropped + stderr.Dropped()
+			resp.Stdout, resp.StdoutTruncated = outputText([]byte(msg.Stdout), msg.StdoutDropped), msg.StdoutDropped > 0
+			resp.Stderr, resp.StderrTruncated = outputText(stderr.Bytes(), stderrDropped), stderrDropped > 0
+			resp.WallTimeMs = time.Since(start).Milliseconds()
+			resp.CPUTimeMs = msg.CPUMs
+			if msg.Error != "" {
+				resp.Error = msg.Error
+				resp.ExitCode = 1
+			}
+			return resp, nil
+
+		case <-ctx.Done():
+			// The kernel cannot be interrupted reliably, so the session is lost
+			k.close()
+			resp.WallTimeMs = time.Since(start).Milliseconds()
+			resp.TimedOut = errors.Is(ctx.Err(), context.DeadlineExceeded)
+			resp.ExitCode = -1
+			resp.Error = "execution interrupted; the session was terminated and its state is lost"
+			resp.Stderr = k.takeStray()
+			// Report reads the process state, which is only safe once Wait
+			// has returned; a kernel that outlived close has no details
+			if k.exited() {
+				resp.Sandbox = k.cmd.Report(ctx, []byte(resp.Stderr))
+			}
+			return resp, nil
+
+		case <-k.done:
+			resp.WallTimeMs = time.Since(start).Milliseconds()
+			resp.Stderr = k.takeStray()
+			resp.ExitCode = -1
+			if state := k.cmd.ProcessState; state != nil {
+				resp.ExitCode = state.ExitCode()
+			}
+			resp.KilledBySignal = k.cmd.Signal()
+			resp.Error = "session kernel exited; its state is lost"
+			resp.Sandbox = k.cmd.Report(ctx, []byte(resp.Stderr))
+			k.close()
+			return resp, nil
+		}
+	}
+}
+
+func (k *kernel) touch() {
+	k.mu.Lock()
+	k.lastUsed = time.Now()
+	k.mu.Unlock()
+}
+
+func (k *kernel) idleFor() time.Duration {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.busy > 0 {
+		return 0
+	}
+	return time.Since(k.lastUsed)
+}
+
+// acquire marks the kernel busy for a call; release ends the call
+func (k *kernel) acquire() {
+	k.mu.Lock()
+	k.busy++
+	k.mu.Unlock()
+}
+
+func (k *kernel) release() {
+	k.mu.Lock()
+	k.busy--
+	k.lastUsed = time.Now()
+	k.mu.Unlock()
+}
+
+func (k *kernel) exited() bool {
+	select {
+	case <-k.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// alive reports whether the kernel is running and has not been closed
+func (k *kernel) alive() bool {
+	k.mu.Lock()
+	closed := k.closed
+	k.mu.Unlock()
+	return !closed && !k.exited()
+}
+
+// close kills the kernel's process group and removes its working directory
+func (k *kernel) close() {
+	k.mu.Lock()
+	k.closed = true
+	k.mu.Unlock()
+	if k.stdin != nil {
+		k.stdin.Close()
+	}
+	k.cancel()
+	if k.cmd.Process != nil {
+		select {
+		case <-k.done:
+		case <-time.After(5 * time.Second):
+		}
+	}
+	os.RemoveAll(k.dir)
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
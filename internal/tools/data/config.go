@@ -0,0 +1,34 @@
+package data
+
+import "fmt"
+
+// Config holds the settings for the data tool
+type Config struct {
+	// MaxInputBytes caps the data and csv inputs of a call
+	MaxInputBytes int `json:"maxInputBytes"`
+	// MaxOutputBytes caps the JSON or CSV a call returns; rows and query
+	// results beyond it are dropped and the output is marked truncated
+	MaxOutputBytes int `json:"maxOutputBytes"`
+	// TimeoutSeconds bounds each call
+	TimeoutSeconds int `json:"timeoutSeconds"`
+	// MaxMemoryBytes caps the memory of the process that runs jq programs
+	MaxMemoryBytes int64 `json:"maxMemoryBytes"`
+}
+
+// DefaultConfig returns the default data tool settings
+func DefaultConfig() Config {
+	return Config{
+		MaxInputBytes:  4 << 20,
+		MaxOutputBytes: 64 << 10,
+		TimeoutSeconds: 10,
+		MaxMemoryBytes: 256 << 20,
+	}
+}
+
+// Validate checks that the limits are positive
+func (c Config) Validate() error {
+	if c.MaxInputBytes <= 0 || c.MaxOutputBytes <= 0 || c.TimeoutSeconds <= 0 || c.MaxMemoryBytes <= 0 {
+		return fmt.Errorf("maxInputBytes, maxOutputBytes, timeoutSeconds and maxMemoryBytes must be positive")
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package data
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// delimiters are the separators recognized when a call names none
+var delimiters = []rune{',', '\t', ';', '|'}
+
+// parseDelimiter reads a delimiter name or character. An empty name
+// detects the delimiter from the first line of text.
+func parseDelimiter(name, text string) (rune, error) {
+	switch strings.ToLower(name) {
+	case "":
+		line, _, _ := strings.Cut(text, "\n")
+		best, count := ',', 0
+		for _, d := range delimiters {
+			if n := strings.Count(line, string(d)); n > count {
+				best, count = d, n
+			}
+		}
+		return best, nil
+	case "tab", `\t`:
+		return '\t', nil
+	case "comma":
+		return ',', nil
+	case "semicolon":
+		return ';', nil
+	case "pipe":
+		return '|', nil
+	}
+	r, size := utf8.DecodeRuneInString(name)
+	if size != len(name) || r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+		return 0, fmt.Errorf("invalid delimiter %q; use a single character such as , or ; or tab", name)
+	}
+	return r, nil
+}
+
+// parseCSV reads CSV text into a table. The first record names the
+// columns unless noHeader is set. With infer, columns whose cells all hold
+// numbers, or all booleans, become them, and empty cells become null.
+func parseCSV(text string, delimiter rune, noHeader, infer bool) (*table, error) {
+	reader := csv.NewReader(strings.NewReader(strings.TrimPrefix(text, "\ufeff")))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+
+	t := &table{}
+	if !noHeader && len(records) > 0 {
+		seen := make(map[string]bool)
+		for i, name := range records[0] {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				name = fmt.Sprintf("column%d", i+1)
+			}
+			unique := name
+			for n := 2; seen[unique]; n++ {
+				unique = fmt.Sprintf("%s_%d", name, n)
+			}
+			seen[unique] = true
+			t.columns = append(t.columns, unique)
+		}
+		records = records[1:]
+	}
+	// Ragged rows get null for missing cells and new columns for extra ones
+	for _, record := range records {
+		for len(t.columns) < len(record) {
+			t.columns = append(t.columns, fmt.Sprintf("column%d", len(t.columns)+1))
+		}
+	}
+
+	kinds := make([]cellKind, len(t.columns))
+	if infer {
+		for i := range t.columns {
+			kinds[i] = columnKind(records, i)
+		}
+	}
+	for _, record := range records {
+		row := make(map[string]interface{}, len(t.columns))
+		for i, column := range t.columns {
+			if i >= len(record) {
+				row[column] = nil
+				continue
+			}
+			row[column] = kinds[i].value(record[i])
+		}
+		t.rows = append(t.rows, row)
+	}
+	return t, nil
+}
+
+// cellKind is the type inferred for a CSV column
+type cellKind int
+
+const (
+	textCells cellKind = iota
+	numberCells
+	booleanCells
+	// inferredText is text in a column of mixed cells, with empty cells null
+	inferredText
+)
+
+// columnKind infers the type of column i from its cells that are not
+// empty. Values such as 007 are not numbers, so columns of codes stay text.
+func columnKind(records [][]string, i int) cellKind {
+	numbers, booleans, cells := true, true, 0
+	for _, record := range records {
+		if i >= len(record) {
+			continue
+		}
+		cell := strings.TrimSpace(record[i])
+		if cell == "" {
+			continue
+		}
+		cells++
+		numbers = numbers && numberPattern.MatchString(cell)
+		booleans = booleans && (strings.EqualFold(cell, "true") || strings.EqualFold(cell, "false"))
+	}
+	switch {
+	case cells > 0 && numbers:
+		return numberCells
+	case cells > 0 && booleans:
+		return booleanCells
+	}
+	return inferredText
+}
+
+// value converts a cell to the column's type
+func (k cellKind) value(cell string) interface{} {
+	if k == textCells {
+		return cell
+	}
+	trimmed := strings.TrimSpace(cell)
+	switch {
+	case trimmed == "":
+		return nil
+	case k == numberCells:
+		return json.Number(trimmed)
+	case k == booleanCells:
+		return strings.EqualFold(trimmed, "true")
+	}
+	return cell
+}
+
+// writeCSV writes a table as CSV with a header. Nested objects are
+// flattened into dotted columns such as address.city, and arrays are
+// written as JSON. Rows that would take the text past maxBytes are left
+// out; it returns the number of rows written.
+func writeCSV(t *table, delimiter rune, maxBytes int, ranks map[string]int) (string, int, error) {
+	flat := make([]map[string]interface{}, len(t.rows))
+	var columns []string
+	seen := make(map[string]bool)
+	for i, row := range t.rows {
+		flat[i] = make(map[string]interface{})
+		for _, column := range t.columns {
+			value, ok := row[column]
+			if !ok {
+				continue
+			}
+			flatten(column, value, flat[i], ranks, func(name string) {
+				if !seen[name] {
+					seen[name] = true
+					columns = append(columns, name)
+				}
+			})
+		}
+	}
+	// Columns no row has still get a header
+	for _, column := range t.columns {
+		if !seen[column] && !hasPrefix(columns, column+".") {
+			seen[column] = true
+			columns = append(columns, column)
+		}
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Comma = delimiter
+	if err := writer.Write(columns); err != nil {
+		return "", 0, err
+	}
+	writer.Flush()
+	record := make([]string, len(columns))
+	for i, row := range flat {
+		for j, column := range columns {
+			cell, err := formatCell(row[column])
+			if err != nil {
+				return "", 0, fmt.Errorf("row %d, column %s: %w", i+1, column, err)
+			}
+			record[j] = cell
+		}
+		size := buf.Len()
+		if err := writer.Write(record); err != nil {
+			return "", 0, err
+		}
+		writer.Flush()
+		if buf.Len() > maxBytes {
+			buf.Truncate(size)
+			return buf.String(), i, nil
+		}
+	}
+	return buf.String(), len(flat), writer.Error()
+}
+
+// flatten stores value under name in flat, expanding nested objects into
+// dotted names, and reports each name it stores
+func flatten(name string, value interface{}, flat map[string]interface{}, ranks map[string]int, column func(string)) {
+	object, ok := value.(map[string]interface{})
+	if !ok || len(object) == 0 {
+		flat[name] = value
+		column(name)
+		return
+	}
+	keys := make([]string, 0, len(object))
+	for k := range object {
+		keys = append(keys, k)
+	}
+	orderKeys(keys, ranks)
+	for _, k := range keys {
+		flatten(name+"."+k, object[k], flat, ranks, column)
+	}
+}
+
+func hasPrefix(names []string, prefix string) bool {
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatCell writes a value as CSV text: null as an empty cell, and
+// arrays and objects as JSON
+func formatCell(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
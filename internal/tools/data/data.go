@@ -0,0 +1,491 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DataInput represents the input schema for the data tool
+type DataInput struct {
+	// Operation is query, table, csvToJson, jsonToCsv or schema
+	Operation string `json:"operation"`
+	// Data is the JSON to work on; JSON inside a string is accepted too
+	Data json.RawMessage `json:"data,omitempty"`
+	// CSV is CSV text to work on instead of data
+	CSV string `json:"csv,omitempty"`
+	// Delimiter separates CSV fields; it is detected when left out
+	Delimiter string `json:"delimiter,omitempty"`
+	// NoHeader reads the first CSV record as data, naming the columns
+	// column1, column2 and so on
+	NoHeader bool `json:"noHeader,omitempty"`
+	// KeepStrings keeps CSV cells as text instead of reading numbers,
+	// booleans and empty cells as such
+	KeepStrings bool `json:"keepStrings,omitempty"`
+	// Query is the jq program of the query operation
+	Query string `json:"query,omitempty"`
+	// Variables are available to the query as $name
+	Variables map[string]interface{} `json:"variables,omitempty"`
+	// Path is a jq expression selecting the rows in data, such as .items
+	Path string `json:"path,omitempty"`
+	// Where, GroupBy, Aggregates, SortBy, Limit and Columns transform the
+	// rows, in that order
+	Where      []Condition `json:"where,omitempty"`
+	GroupBy    []string    `json:"groupBy,omitempty"`
+	Aggregates []Aggregate `json:"aggregates,omitempty"`
+	SortBy     []string    `json:"sortBy,omitempty"`
+	Limit      int         `json:"limit,omitempty"`
+	Columns    []string    `json:"columns,omitempty"`
+	// Format is json or csv, for the table operation
+	Format string `json:"format,omitempty"`
+}
+
+// Operations of the data tool
+const (
+	OpQuery     = "query"
+	OpTable     = "table"
+	OpCSVToJSON = "csvToJson"
+	OpJSONToCSV = "jsonToCsv"
+	OpSchema    = "schema"
+)
+
+var operations = []string{OpQuery, OpTable, OpCSVToJSON, OpJSONToCSV, OpSchema}
+
+// DataOutput represents the output schema for the data tool
+type DataOutput struct {
+	Operation string `json:"operation"`
+	// Result is the result of a query that produced one value, and Results
+	// the results of a query that produced none or several
+	Result  json.RawMessage   `json:"result,omitempty"`
+	Results []json.RawMessage `json:"results,omitempty"`
+	// Columns and Rows are the rows of a table in JSON, and CSV the rows
+	// as CSV text
+	Columns []string        `json:"columns,omitempty"`
+	Rows    json.RawMessage `json:"rows,omitempty"`
+	CSV     string          `json:"csv,omitempty"`
+	// RowCount is the number of rows returned
+	RowCount *int `json:"rowCount,omitempty"`
+	// Summary is the result of the schema operation
+	Summary *Summary `json:"summary,omitempty"`
+	// Truncated is set when output was dropped at the size limit
+	Truncated bool     `json:"truncated,omitempty"`
+	Notes     []string `json:"notes,omitempty"`
+}
+
+// NewDataToolWithConfig creates a data tool with the given limits
+func NewDataToolWithConfig(config Config) (string, string, json.RawMessage, func(context.Context, json.RawMessage) (json.RawMessage, error)) {
+	timeout := time.Duration(config.TimeoutSeconds) * time.Second
+
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"operation": map[string]interface{}{
+				"type": "string",
+				"enum": operations,
+				"description": "query runs a jq program on data; table filters, groups, aggregates, sorts and selects rows; " +
+					"csvToJson and jsonToCsv convert rows; schema summarizes the structure, types and values",
+			},
+			"data": map[string]interface{}{
+				"description": "The JSON to work on, such as an httpRequest response body",
+			},
+			"csv": map[string]interface{}{
+				"type":        "string",
+				"description": "CSV text to work on instead of data; the first line names the columns",
+			},
+			"delimiter": map[string]interface{}{
+				"type":        "string",
+				"description": "CSV field separator, such as , or ; or tab; detected from the first line when left out",
+			},
+			"noHeader": map[string]interface{}{
+				"type":        "boolean",
+				"description": "The CSV has no header line; columns are named column1, column2, ...",
+			},
+			"keepStrings": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Keep CSV cells as strings instead of reading numbers, true, false and empty cells as such",
+			},
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "query: a jq program, such as [.items[] | select(.price > 10) | {name, price}]",
+			},
+			"variables": map[string]interface{}{
+				"type":        "object",
+				"description": "query: values available as $name",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "A jq expression selecting the rows in data, such as .data.items, when data is not itself an array of objects",
+			},
+			"where": map[string]interface{}{
+				"type":        "array",
+				"description": "Keep rows passing every condition",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"field":      map[string]interface{}{"type": "string", "description": "Field name; use dots for nested fields, such as address.city"},
+						"op":         map[string]interface{}{"type": "string", "enum": conditionOps},
+						"value":      map[string]interface{}{"description": "Value to compare with; an array for in and notIn, a regular expression for matches"},
+						"ignoreCase": map[string]interface{}{"type": "boolean"},
+					},
+					"required": []string{"field", "op"},
+				},
+			},
+			"groupBy": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Fields to group rows by; each group becomes one row with these fields and the aggregates",
+			},
+			"aggregates": map[string]interface{}{
+				"type":        "array",
+				"description": "Values computed per group, or over all rows without groupBy; count by default",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"op":    map[string]interface{}{"type": "string", "enum": aggregateOps},
+						"field": map[string]interface{}{"type": "string", "description": "Input field; count without a field counts rows"},
+						"as":    map[string]interface{}{"type": "string", "description": "Result column name, op_field by default"},
+					},
+					"required": []string{"op"},
+				},
+			},
+			"sortBy": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Fields to sort by, after grouping; prefix a field with - for descending order",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": "Keep only the first rows, after sorting",
+			},
+			"columns": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Columns to return, in order",
+			},
+			"format": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"json", "csv"},
+				"description": "table: return rows as JSON objects (default) or CSV text",
+			},
+		},
+		"required": []string{"operation"},
+	}
+
+	schemaJSON, _ := json.Marshal(schema)
+
+	return "data",
+		"Wrangles JSON and CSV without writing code: jq queries, CSV to JSON and back, filtering, sorting, grouping and aggregating rows, " +
+			"and summaries of the structure and values of data. Pass the JSON as data or the CSV text as csv.",
+		schemaJSON,
+		func(ctx context.Context, input json.RawMessage) (json.RawMessage, error) {
+			var params DataInput
+			if err := json.Unmarshal(input, &params); err != nil {
+				return nil, fmt.Errorf("invalid input: %w", err)
+			}
+			if len(params.Data) > config.MaxInputBytes || len(params.CSV) > config.MaxInputBytes {
+				return nil, fmt.Errorf("input is larger than the %d byte limit", config.MaxInputBytes)
+			}
+			if len(bytes.TrimSpace(params.Data)) > 0 && params.CSV != "" {
+				return nil, fmt.Errorf("give either data or csv, not both")
+			}
+
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			output := &DataOutput{Operation: params.Operation}
+			var err error
+			switch params.Operation {
+			case OpQuery:
+				err = runQuery(ctx, config, params, output)
+			case OpTable, OpCSVToJSON, OpJSONToCSV:
+				err = runTable(ctx, config, params, output)
+			case OpSchema:
+				err = runSchema(ctx, config, params, output)
+			case "":
+				return nil, fmt.Errorf("operation is required")
+			default:
+				return nil, fmt.Errorf("unknown operation %q; use one of %s", params.Operation, strings.Join(operations, ", "))
+			}
+			if errors.Is(err, context.DeadlineExceeded) {
+				return nil, fmt.Errorf("%s timed out after %s", params.Operation, timeout)
+			}
+			if err != nil {
+				return nil, err
+			}
+			return json.Marshal(output)
+		}
+}
+
+// load returns the input: decoded data, or CSV rows as an array of objects
+func load(params DataInput) (*document, error) {
+	if params.CSV != "" {
+		t, err := loadCSV(params)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]interface{}, len(t.rows))
+		for i, row := range t.rows {
+			values[i] = row
+		}
+		ranks := make(map[string]int, len(t.columns))
+		for i, column := range t.columns {
+			ranks[column] = i
+		}
+		return &document{value: values, ranks: ranks}, nil
+	}
+	if len(bytes.TrimSpace(params.Data)) == 0 {
+		return nil, fmt.Errorf("%s needs data or csv", params.Operation)
+	}
+	return decodeDocument(params.Data)
+}
+
+func loadCSV(params DataInput) (*table, error) {
+	delimiter, err := parseDelimiter(params.Delimiter, params.CSV)
+	if err != nil {
+		return nil, err
+	}
+	return parseCSV(params.CSV, delimiter, params.NoHeader, !params.KeepStrings)
+}
+
+// selectPath applies params.Path to the document. A path producing one
+// value returns it; one producing several returns them as an array.
+func selectPath(ctx context.Context, config Config, params DataInput, doc *document) (interface{}, error) {
+	if strings.TrimSpace(params.Path) == "" {
+		return doc.value, nil
+	}
+	q, err := compileQuery(config, params.Path, params.Variables)
+	if err != nil {
+		return nil, err
+	}
+	results, err := q.all(ctx, doc.value)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 1 {
+		return results[0], nil
+	}
+	if results == nil {
+		results = []interface{}{}
+	}
+	return results, nil
+}
+
+// loadTable returns the rows of the input
+func loadTable(ctx context.Context, config Config, params DataInput) (*table, map[string]int, error) {
+	if params.CSV != "" {
+		t, err := loadCSV(params)
+		return t, nil, err
+	}
+	doc, err := load(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	value, err := selectPath(ctx, config, params, doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch v := value.(type) {
+	case []interface{}:
+		t, err := newTable(v, doc.ranks)
+		return t, doc.ranks, err
+	case map[string]interface{}:
+		if paths := arrayFields(v); len(paths) > 0 {
+			return nil, nil, fmt.Errorf("data is an object; set path to its array of rows, one of %s", strings.Join(paths, ", "))
+		}
+	}
+	return nil, nil, fmt.Errorf("data is %s, not an array of rows; use path or query to select them", describe(value))
+}
+
+// runQuery collects the results of a jq program up to the output limit
+func runQuery(ctx context.Context, config Config, params DataInput, output *DataOutput) error {
+	if strings.TrimSpace(params.Query) == "" {
+		return fmt.Errorf("query needs a query")
+	}
+	q, err := compileQuery(config, params.Query, params.Variables)
+	if err != nil {
+		return err
+	}
+	doc, err := load(params)
+	if err != nil {
+		return err
+	}
+
+	results := []json.RawMessage{}
+	size := 0
+	// cut is set when the only result was an array cut to fit
+	cut := false
+	var encodeErr error
+	err = q.run(ctx, doc.value, func(value interface{}) bool {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			encodeErr = fmt.Errorf("result %d cannot be written as JSON: %w", len(results)+1, err)
+			return false
+		}
+		if size+len(encoded) <= config.MaxOutputBytes {
+			results = append(results, encoded)
+			size += len(encoded)
+			return true
+		}
+		output.Truncated = true
+		// A large first array is cut rather than dropped
+		if items, ok := value.([]interface{}); ok && len(results) == 0 {
+			partial, kept := fitArray(items, config.MaxOutputBytes)
+			results, cut = append(results, partial), true
+			output.Notes = append(output.Notes, fmt.Sprintf("the result has %d items; showing the first %d", len(items), kept))
+			return false
+		}
+		output.Notes = append(output.Notes, fmt.Sprintf("results stopped after %d at the %d byte limit", len(results), config.MaxOutputBytes))
+		return false
+	})
+	if err != nil {
+		return err
+	}
+	if encodeErr != nil {
+		return encodeErr
+	}
+
+	switch {
+	case len(results) == 1 && (cut || !output.Truncated):
+		output.Result = results[0]
+	case len(results) == 0:
+		output.Notes = append(output.Notes, "the query produced no results")
+	default:
+		output.Results = results
+	}
+	return nil
+}
+
+// fitArray encodes the leading items of an array that fit in maxBytes
+func fitArray(items []interface{}, maxBytes int) (json.RawMessage, int) {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, item := range items {
+		encoded, err := json.Marshal(item)
+		if err != nil || buf.Len()+len(encoded)+2 > maxBytes {
+			buf.WriteByte(']')
+			return buf.Bytes(), i
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(encoded)
+	}
+	buf.WriteByte(']')
+	return buf.Bytes(), len(items)
+}
+
+// runTable transforms rows and returns them as JSON or CSV
+func runTable(ctx context.Context, config Config, params DataInput, output *DataOutput) error {
+	format := strings.ToLower(params.Format)
+	switch params.Operation {
+	case OpCSVToJSON:
+		if params.CSV == "" {
+			return fmt.Errorf("csvToJson needs csv")
+		}
+		format = "json"
+	case OpJSONToCSV:
+		if params.CSV != "" {
+			return fmt.Errorf("jsonToCsv needs data, not csv")
+		}
+		format = "csv"
+	}
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		return fmt.Errorf("format must be json or csv")
+	}
+	if params.Limit < 0 {
+		return fmt.Errorf("limit must not be negative")
+	}
+
+	t, ranks, err := loadTable(ctx, config, params)
+	if err != nil {
+		return err
+	}
+	if len(params.Where) > 0 {
+		if err := t.filter(params.Where); err != nil {
+			return err
+		}
+	}
+	if len(params.GroupBy) > 0 || len(params.Aggregates) > 0 {
+		notes, err := t.group(params.GroupBy, params.Aggregates)
+		if err != nil {
+			return err
+		}
+		output.Notes = append(output.Notes, notes...)
+	}
+	if len(params.SortBy) > 0 {
+		if err := t.sortBy(params.SortBy); err != nil {
+			return err
+		}
+	}
+	if params.Limit > 0 && params.Limit < len(t.rows) {
+		output.Notes = append(output.Notes, fmt.Sprintf("limit kept %d of %d rows", params.Limit, len(t.rows)))
+		t.rows = t.rows[:params.Limit]
+	}
+	if len(params.Columns) > 0 {
+		if err := t.project(params.Columns); err != nil {
+			return err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var written int
+	if format == "csv" {
+		delimiter := ','
+		if params.Delimiter != "" {
+			if delimiter, err = parseDelimiter(params.Delimiter, ""); err != nil {
+				return err
+			}
+		}
+		output.CSV, written, err = writeCSV(t, delimiter, config.MaxOutputBytes, ranks)
+	} else {
+		output.Columns = t.columns
+		output.Rows, written, err = t.marshalRows(config.MaxOutputBytes)
+	}
+	if err != nil {
+		return err
+	}
+	output.RowCount = &written
+	if written < len(t.rows) {
+		output.Truncated = true
+		output.Notes = append(output.Notes, fmt.Sprintf("showing %d of %d rows, up to the %d byte limit; narrow them with where, columns or limit", written, len(t.rows), config.MaxOutputBytes))
+	}
+	return nil
+}
+
+// runSchema summarizes the input: column statistics for rows of objects,
+// and the shape of anything else
+func runSchema(ctx context.Context, config Config, params DataInput, output *DataOutput) error {
+	if params.CSV != "" {
+		t, err := loadCSV(params)
+		if err != nil {
+			return err
+		}
+		output.Summary = summarizeTable(t)
+		return nil
+	}
+	doc, err := load(params)
+	if err != nil {
+		return err
+	}
+	value, err := selectPath(ctx, config, params, doc)
+	if err != nil {
+		return err
+	}
+	if items, ok := value.([]interface{}); ok && len(items) > 0 {
+		if t, err := newTable(items, doc.ranks); err == nil {
+			output.Summary = summarizeTable(t)
+			return nil
+		}
+	}
+	output.Summary = summarizeShape(value, doc.ranks)
+	return nil
+}
@@ -0,0 +1,212 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/go-tools-agent/internal/capped"
+	"github.com/go-tools-agent/internal/sandbox"
+	"github.com/itchyny/gojq"
+)
+
+// query is a jq program with the values of its variables. A program such
+// as def f: [f]; f can use memory without bound, so it runs in a worker
+// process, a copy of this binary started under sandbox limits.
+type query struct {
+	text      string
+	variables map[string]interface{}
+	config    Config
+}
+
+// compileQuery checks a jq program before it is run. Variables are
+// available as $name.
+func compileQuery(config Config, text string, variables map[string]interface{}) (*query, error) {
+	if _, _, err := compile(text, variables); err != nil {
+		return nil, err
+	}
+	return &query{text: text, variables: variables, config: config}, nil
+}
+
+// compile parses a jq program and orders the values of its variables. The
+// environment is hidden, so $ENV and env cannot reveal secrets.
+func compile(text string, variables map[string]interface{}) (*gojq.Code, []interface{}, error) {
+	parsed, err := gojq.Parse(text)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid query %q: %w", text, err)
+	}
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	values := make([]interface{}, len(names))
+	for i, name := range names {
+		values[i] = variables[name]
+		names[i] = "$" + name
+	}
+	code, err := gojq.Compile(parsed,
+		gojq.WithVariables(names),
+		gojq.WithEnvironLoader(func() []string { return nil }),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid query %q: %w", text, err)
+	}
+	return code, values, nil
+}
+
+// run applies the query to input in a worker and passes each result to
+// yield until it returns false
+func (q *query) run(ctx context.Context, input interface{}, yield func(interface{}) bool) error {
+	if !initialized {
+		return ErrInitNotCalled
+	}
+	request, err := json.Marshal(workerRequest{Query: q.text, Variables: q.variables, Input: input})
+	if err != nil {
+		return fmt.Errorf("failed to encode query input: %w", err)
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find query worker: %w", err)
+	}
+
+	// Cancelling kills the worker once its results are no longer wanted
+	workerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	limits := sandbox.Config{
+		Enabled:    true,
+		CPUSeconds: uint64(q.config.TimeoutSeconds),
+		DataBytes:  uint64(q.config.MaxMemoryBytes),
+	}
+	cmd, err := sandbox.Command(workerCtx, limits, os.TempDir(), executable, workerArg)
+	if err != nil {
+		return fmt.Errorf("failed to prepare query worker: %w", err)
+	}
+	// The first lines of stderr say why a worker failed; a runtime crash
+	// is followed by stack traces that are not needed
+	stderr := capped.NewBuffer(16 << 10)
+	cmd.Stdin = bytes.NewReader(request)
+	cmd.Stderr = stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start query worker: %w", err)
+	}
+
+	stopped, readErr := readResults(stdout, 2*max(q.config.MaxInputBytes, q.config.MaxOutputBytes), yield)
+	if stopped {
+		cancel()
+	}
+	waitErr := cmd.Wait()
+
+	switch {
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case readErr != nil:
+		return readErr
+	case stopped || waitErr == nil:
+		return nil
+	}
+
+	report := cmd.Report(workerCtx, stderr.Bytes())
+	for _, limit := range report.LimitsExceeded {
+		switch limit {
+		case sandbox.LimitMemory:
+			return fmt.Errorf("query used more than the %d byte memory limit", q.config.MaxMemoryBytes)
+		case sandbox.LimitCPU:
+			return fmt.Errorf("query used more than %d seconds of CPU time", q.config.TimeoutSeconds)
+		}
+	}
+	message, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n")
+	if exitErr, ok := waitErr.(*exec.ExitError); ok && sandbox.IsInitFailure(exitErr, stderr.Bytes()) {
+		return fmt.Errorf("failed to start sandbox: %s", message)
+	}
+	return fmt.Errorf("query worker failed: %v: %s", waitErr, message)
+}
+
+// readResults passes the values a worker writes to yield. It reports
+// whether it stopped before the worker finished, because yield returned
+// false or the output could not be read.
+func readResults(r io.Reader, maxLine int, yield func(interface{}) bool) (bool, error) {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := readLine(reader, maxLine)
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return true, err
+		}
+		var result workerResult
+		if err := json.Unmarshal(line, &result); err != nil {
+			return true, fmt.Errorf("invalid output from query worker: %w", err)
+		}
+		if result.Error != "" {
+			return false, fmt.Errorf("query failed: %s", result.Error)
+		}
+		dec := json.NewDecoder(bytes.NewReader(result.Value))
+		dec.UseNumber()
+		var value interface{}
+		if err := dec.Decode(&value); err != nil {
+			return true, fmt.Errorf("invalid output from query worker: %w", err)
+		}
+		if !yield(value) {
+			return true, nil
+		}
+	}
+}
+
+// readLine reads a line of at most limit bytes. A line cut short by the
+// end of the output is dropped.
+func readLine(reader *bufio.Reader, limit int) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		line = append(line, chunk...)
+		if len(line) > limit {
+			return nil, fmt.Errorf("a query result is larger than the %d byte limit", limit)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return line, nil
+	}
+}
+
+// all collects every result of the query. Like a loaded document, the
+// results may take at most MaxInputBytes encoded as JSON.
+func (q *query) all(ctx context.Context, input interface{}) ([]interface{}, error) {
+	var results []interface{}
+	size := 0
+	var limitErr error
+	err := q.run(ctx, input, func(value interface{}) bool {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			limitErr = fmt.Errorf("result %d cannot be written as JSON: %w", len(results)+1, err)
+			return false
+		}
+		// Count a separator per result, as in an array
+		if size += len(encoded) + 1; size > q.config.MaxInputBytes {
+			limitErr = fmt.Errorf("the results are larger than the %d byte input limit", q.config.MaxInputBytes)
+			return false
+		}
+		results = append(results, value)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, limitErr
+}
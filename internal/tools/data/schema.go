@@ -0,0 +1,262 @@
+package data
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Summary describes the structure of data without returning all of it
+type Summary struct {
+	// Kind is table for rows of fields and json for anything else
+	Kind string `json:"kind"`
+	// RowCount and Columns describe a table
+	RowCount *int            `json:"rowCount,omitempty"`
+	Columns  []ColumnSummary `json:"columns,omitempty"`
+	// Shape describes other JSON
+	Shape *Shape `json:"shape,omitempty"`
+}
+
+// ColumnSummary describes the values of a table column
+type ColumnSummary struct {
+	Name string `json:"name"`
+	// Types lists the JSON types of the column's values, null aside
+	Types []string `json:"types"`
+	// Count is the number of values that are not null
+	Count int `json:"count"`
+	// Nulls counts null and missing values
+	Nulls int `json:"nulls,omitempty"`
+	// Distinct counts different values, up to maxDistinct
+	Distinct       int  `json:"distinct"`
+	DistinctCapped bool `json:"distinctCapped,omitempty"`
+	// Min and Max are the smallest and largest numbers, or strings for
+	// columns without numbers
+	Min interface{} `json:"min,omitempty"`
+	Max interface{} `json:"max,omitempty"`
+	// Mean is the mean of the numbers
+	Mean *float64 `json:"mean,omitempty"`
+	// Examples are a few different values
+	Examples []interface{} `json:"examples,omitempty"`
+}
+
+// Shape describes JSON values found at one place in a document
+type Shape struct {
+	// Type is the JSON type, or several joined with |, such as string|null
+	Type string `json:"type"`
+	// Length is the length of an array; when several arrays were seen,
+	// MinLength and MaxLength give the range instead
+	Length    *int `json:"length,omitempty"`
+	MinLength *int `json:"minLength,omitempty"`
+	MaxLength *int `json:"maxLength,omitempty"`
+	// Items describes the items of arrays
+	Items *Shape `json:"items,omitempty"`
+	// Fields describes the fields of objects; MoreFields counts those left out
+	Fields     []*Field `json:"fields,omitempty"`
+	MoreFields int      `json:"moreFields,omitempty"`
+	// Example is a value, for strings, numbers and booleans
+	Example interface{} `json:"example,omitempty"`
+}
+
+// Field is a field of objects
+type Field struct {
+	Name string `json:"name"`
+	// Optional is set when some objects lack the field
+	Optional bool `json:"optional,omitempty"`
+	*Shape
+}
+
+// Summary limits
+const (
+	maxDistinct   = 10000
+	maxExamples   = 3
+	maxExample    = 80
+	maxFields     = 200
+	maxShapeDepth = 32
+)
+
+// summarizeTable describes each column of a table
+func summarizeTable(t *table) *Summary {
+	rows := len(t.rows)
+	summary := &Summary{Kind: "table", RowCount: &rows, Columns: []ColumnSummary{}}
+	for _, column := range t.columns {
+		c := ColumnSummary{Name: column, Types: []string{}}
+		seen := make(map[string]bool)
+		types := make(map[string]bool)
+		var min, max, minText, maxText interface{}
+		total, numbers := 0.0, 0
+		for _, row := range t.rows {
+			value := row[column]
+			if value == nil {
+				c.Nulls++
+				continue
+			}
+			c.Count++
+			if name := typeName(value); !types[name] {
+				types[name] = true
+				c.Types = append(c.Types, name)
+			}
+			if k := key(value); !seen[k] {
+				if len(seen) < maxDistinct {
+					seen[k] = true
+					if len(c.Examples) < maxExamples {
+						c.Examples = append(c.Examples, example(value))
+					}
+				} else {
+					c.DistinctCapped = true
+				}
+			}
+			if n, ok := number(value); ok {
+				total += n
+				numbers++
+				if min == nil || compare(value, min) < 0 {
+					min = value
+				}
+				if max == nil || compare(value, max) > 0 {
+					max = value
+				}
+			} else if _, ok := value.(string); ok {
+				if minText == nil || compare(value, minText) < 0 {
+					minText = value
+				}
+				if maxText == nil || compare(value, maxText) > 0 {
+					maxText = value
+				}
+			}
+		}
+		c.Distinct = len(seen)
+		if numbers > 0 {
+			mean := tidy(total / float64(numbers))
+			c.Min, c.Max, c.Mean = min, max, &mean
+		} else if minText != nil {
+			c.Min, c.Max = example(minText), example(maxText)
+		}
+		summary.Columns = append(summary.Columns, c)
+	}
+	return summary
+}
+
+// example shortens long strings and stands in for arrays and objects with
+// their size
+func example(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if utf8.RuneCountInString(v) > maxExample {
+			return string([]rune(v)[:maxExample]) + "…"
+		}
+	case []interface{}:
+		return "array of " + plural(len(v), "item")
+	case map[string]interface{}:
+		return "object with " + plural(len(v), "field")
+	}
+	return value
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return strconv.Itoa(n) + " " + noun + "s"
+}
+
+// shapeBuilder accumulates the values seen at one place in a document
+type shapeBuilder struct {
+	types          []string
+	arrays         int
+	minLen, maxLen int
+	items          *shapeBuilder
+	objects        int
+	fields         map[string]*shapeBuilder
+	present        map[string]int
+	example        interface{}
+}
+
+// add records a value. Below maxShapeDepth only types are recorded.
+func (b *shapeBuilder) add(value interface{}, depth int) {
+	name := typeName(value)
+	if !contains(b.types, name) {
+		b.types = append(b.types, name)
+	}
+	if depth >= maxShapeDepth {
+		return
+	}
+	switch v := value.(type) {
+	case []interface{}:
+		if b.arrays == 0 || len(v) < b.minLen {
+			b.minLen = len(v)
+		}
+		if len(v) > b.maxLen {
+			b.maxLen = len(v)
+		}
+		b.arrays++
+		for _, item := range v {
+			if b.items == nil {
+				b.items = &shapeBuilder{}
+			}
+			b.items.add(item, depth+1)
+		}
+	case map[string]interface{}:
+		b.objects++
+		if b.fields == nil {
+			b.fields = make(map[string]*shapeBuilder)
+			b.present = make(map[string]int)
+		}
+		for k, child := range v {
+			field, ok := b.fields[k]
+			if !ok {
+				field = &shapeBuilder{}
+				b.fields[k] = field
+			}
+			b.present[k]++
+			field.add(child, depth+1)
+		}
+	case nil:
+	default:
+		if b.example == nil {
+			b.example = example(value)
+		}
+	}
+}
+
+// shape describes the values recorded, with fields in the order of ranks
+func (b *shapeBuilder) shape(ranks map[string]int) *Shape {
+	// List null last, so the main type comes first
+	types := append([]string{}, b.types...)
+	sort.SliceStable(types, func(i, j int) bool { return types[i] != "null" && types[j] == "null" })
+	s := &Shape{Type: strings.Join(types, "|"), Example: b.example}
+	if b.arrays == 1 {
+		s.Length = &b.maxLen
+	} else if b.arrays > 1 {
+		s.MinLength, s.MaxLength = &b.minLen, &b.maxLen
+	}
+	if b.items != nil {
+		s.Items = b.items.shape(ranks)
+	}
+	if b.fields != nil {
+		names := make([]string, 0, len(b.fields))
+		for name := range b.fields {
+			names = append(names, name)
+		}
+		orderKeys(names, ranks)
+		if len(names) > maxFields {
+			s.MoreFields = len(names) - maxFields
+			names = names[:maxFields]
+		}
+		s.Fields = []*Field{}
+		for _, name := range names {
+			s.Fields = append(s.Fields, &Field{
+				Name:     name,
+				Optional: b.present[name] < b.objects,
+				Shape:    b.fields[name].shape(ranks),
+			})
+		}
+	}
+	return s
+}
+
+// summarizeShape describes the structure of any JSON value
+func summarizeShape(value interface{}, ranks map[string]int) *Summary {
+	b := &shapeBuilder{}
+	b.add(value, 0)
+	return &Summary{Kind: "json", Shape: b.shape(ranks)}
+}
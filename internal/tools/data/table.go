@@ -0,0 +1,556 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// table is rows of fields with their columns in order
+type table struct {
+	columns []string
+	rows    []map[string]interface{}
+}
+
+// newTable builds a table from JSON values, which must all be objects.
+// Columns are every key, in the order of ranks.
+func newTable(values []interface{}, ranks map[string]int) (*table, error) {
+	t := &table{rows: make([]map[string]interface{}, 0, len(values))}
+	seen := make(map[string]bool)
+	for i, value := range values {
+		row, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("row %d is %s, not an object; use path to select an array of objects, or query to reshape the data", i+1, describe(value))
+		}
+		for k := range row {
+			if !seen[k] {
+				seen[k] = true
+				t.columns = append(t.columns, k)
+			}
+		}
+		t.rows = append(t.rows, row)
+	}
+	orderKeys(t.columns, ranks)
+	return t, nil
+}
+
+// describe names a value's type with an article, for errors
+func describe(value interface{}) string {
+	switch name := typeName(value); name {
+	case "null":
+		return name
+	case "array", "object":
+		return "an " + name
+	default:
+		return "a " + name
+	}
+}
+
+// arrayFields lists the paths of the arrays among an object's fields, to
+// suggest a path
+func arrayFields(object map[string]interface{}) []string {
+	var paths []string
+	for k, v := range object {
+		switch child := v.(type) {
+		case []interface{}:
+			paths = append(paths, "."+k)
+		case map[string]interface{}:
+			for _, path := range arrayFields(child) {
+				if strings.Count(path, ".") < 3 {
+					paths = append(paths, "."+k+path)
+				}
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Condition keeps the rows whose field passes a test
+type Condition struct {
+	// Field names the field, with dots for nested fields
+	Field string `json:"field"`
+	// Op is the test
+	Op string `json:"op"`
+	// Value is compared with the field; in and notIn take an array
+	Value interface{} `json:"value,omitempty"`
+	// IgnoreCase compares strings without regard to case
+	IgnoreCase bool `json:"ignoreCase,omitempty"`
+}
+
+// conditionOps are the tests of a condition
+var conditionOps = []string{"eq", "ne", "gt", "gte", "lt", "lte", "contains", "startsWith", "endsWith", "in", "notIn", "exists", "missing", "matches"}
+
+// conditionAliases maps symbols to tests
+var conditionAliases = map[string]string{
+	"=": "eq", "==": "eq", "!=": "ne", "<>": "ne", ">": "gt", ">=": "gte", "<": "lt", "<=": "lte",
+}
+
+// test compiles a condition into a function of a row
+func (c Condition) test() (func(map[string]interface{}) bool, error) {
+	if c.Field == "" {
+		return nil, fmt.Errorf("a where condition needs a field")
+	}
+	op := c.Op
+	if alias, ok := conditionAliases[op]; ok {
+		op = alias
+	}
+	value := c.Value
+	fold := func(v interface{}) interface{} {
+		if s, ok := v.(string); ok && c.IgnoreCase {
+			return strings.ToLower(s)
+		}
+		return v
+	}
+	value = fold(value)
+
+	// compared builds tests that need the field and the value to be ordered
+	compared := func(pass func(int) bool) func(map[string]interface{}) bool {
+		return func(row map[string]interface{}) bool {
+			field, ok := lookup(row, c.Field)
+			if !ok {
+				return false
+			}
+			result, ok := order(fold(field), value)
+			return ok && pass(result)
+		}
+	}
+	// text builds tests of a string field against a string value
+	text := func(pass func(field, value string) bool) (func(map[string]interface{}) bool, error) {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s on %s needs a string value", op, c.Field)
+		}
+		return func(row map[string]interface{}) bool {
+			field, _ := lookup(row, c.Field)
+			f, ok := fold(field).(string)
+			return ok && pass(f, s)
+		}, nil
+	}
+
+	switch op {
+	case "eq", "ne":
+		want := op == "eq"
+		return func(row map[string]interface{}) bool {
+			field, _ := lookup(row, c.Field)
+			return equal(fold(field), value) == want
+		}, nil
+	case "gt":
+		return compared(func(r int) bool { return r > 0 }), nil
+	case "gte":
+		return compared(func(r int) bool { return r >= 0 }), nil
+	case "lt":
+		return compared(func(r int) bool { return r < 0 }), nil
+	case "lte":
+		return compared(func(r int) bool { return r <= 0 }), nil
+	case "contains":
+		return func(row map[string]interface{}) bool {
+			field, _ := lookup(row, c.Field)
+			switch f := fold(field).(type) {
+			case string:
+				s, ok := value.(string)
+				return ok && strings.Contains(f, s)
+			case []interface{}:
+				for _, item := range f {
+					if equal(fold(item), value) {
+						return true
+					}
+				}
+			}
+			return false
+		}, nil
+	case "startsWith":
+		return text(strings.HasPrefix)
+	case "endsWith":
+		return text(strings.HasSuffix)
+	case "in", "notIn":
+		list, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s on %s needs an array value", op, c.Field)
+		}
+		want := op == "in"
+		return func(row map[string]interface{}) bool {
+			field, _ := lookup(row, c.Field)
+			field = fold(field)
+			for _, item := range list {
+				if equal(field, fold(item)) {
+					return want
+				}
+			}
+			return !want
+		}, nil
+	case "exists", "missing":
+		want := op == "exists"
+		return func(row map[string]interface{}) bool {
+			field, ok := lookup(row, c.Field)
+			return (ok && field != nil) == want
+		}, nil
+	case "matches":
+		pattern, ok := c.Value.(string)
+		if !ok {
+			return nil, fmt.Errorf("matches on %s needs a regular expression", c.Field)
+		}
+		if c.IgnoreCase {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression for %s: %w", c.Field, err)
+		}
+		return func(row map[string]interface{}) bool {
+			field, _ := lookup(row, c.Field)
+			s, ok := field.(string)
+			return ok && re.MatchString(s)
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown op %q for %s; use one of %s", c.Op, c.Field, strings.Join(conditionOps, ", "))
+}
+
+// filter keeps the rows that pass every condition
+func (t *table) filter(conditions []Condition) error {
+	tests := make([]func(map[string]interface{}) bool, len(conditions))
+	for i, c := range conditions {
+		test, err := c.test()
+		if err != nil {
+			return err
+		}
+		tests[i] = test
+	}
+	kept := t.rows[:0]
+rows:
+	for _, row := range t.rows {
+		for _, test := range tests {
+			if !test(row) {
+				continue rows
+			}
+		}
+		kept = append(kept, row)
+	}
+	t.rows = kept
+	return nil
+}
+
+// Aggregate computes a value over the rows of a group
+type Aggregate struct {
+	// Op is the function
+	Op string `json:"op"`
+	// Field is the input; count without a field counts rows
+	Field string `json:"field,omitempty"`
+	// As names the result column, by default op_field
+	As string `json:"as,omitempty"`
+}
+
+// aggregateOps are the aggregate functions
+var aggregateOps = []string{"count", "countDistinct", "sum", "avg", "min", "max", "median", "first", "last", "values"}
+
+// maxValues caps the list of the values aggregate
+const maxValues = 100
+
+// name returns the aggregate's column name
+func (a Aggregate) name() string {
+	switch {
+	case a.As != "":
+		return a.As
+	case a.Field == "":
+		return a.Op
+	}
+	return a.Op + "_" + a.Field
+}
+
+// compute applies the aggregate to rows. It returns a note when values
+// that are not numbers were skipped.
+func (a Aggregate) compute(rows []map[string]interface{}) (interface{}, string) {
+	if a.Op == "count" && a.Field == "" {
+		return len(rows), ""
+	}
+	var values []interface{}
+	var numbers []float64
+	skipped := 0
+	for _, row := range rows {
+		value, ok := lookup(row, a.Field)
+		if !ok || value == nil {
+			continue
+		}
+		values = append(values, value)
+		if n, ok := numeric(value); ok {
+			numbers = append(numbers, n)
+		} else {
+			skipped++
+		}
+	}
+	note := ""
+	skip := func() {
+		if skipped > 0 {
+			note = fmt.Sprintf("%s skipped %d values of %s that are not numbers", a.name(), skipped, a.Field)
+		}
+	}
+
+	switch a.Op {
+	case "count":
+		return len(values), ""
+	case "countDistinct":
+		seen := make(map[string]bool)
+		for _, v := range values {
+			seen[key(v)] = true
+		}
+		return len(seen), ""
+	case "values":
+		seen := make(map[string]bool)
+		var distinct []interface{}
+		for _, v := range values {
+			if k := key(v); !seen[k] {
+				seen[k] = true
+				distinct = append(distinct, v)
+			}
+		}
+		if len(distinct) > maxValues {
+			return distinct[:maxValues], fmt.Sprintf("%s lists the first %d of %d values", a.name(), maxValues, len(distinct))
+		}
+		return distinct, ""
+	case "first", "last":
+		if len(values) == 0 {
+			return nil, ""
+		}
+		if a.Op == "first" {
+			return values[0], ""
+		}
+		return values[len(values)-1], ""
+	case "min", "max":
+		if len(values) == 0 {
+			return nil, ""
+		}
+		best := values[0]
+		for _, v := range values[1:] {
+			if c := compare(v, best); (a.Op == "min" && c < 0) || (a.Op == "max" && c > 0) {
+				best = v
+			}
+		}
+		return best, ""
+	case "sum":
+		skip()
+		total := 0.0
+		for _, n := range numbers {
+			total += n
+		}
+		return tidy(total), note
+	case "avg":
+		skip()
+		if len(numbers) == 0 {
+			return nil, note
+		}
+		total := 0.0
+		for _, n := range numbers {
+			total += n
+		}
+		return tidy(total / float64(len(numbers))), note
+	case "median":
+		skip()
+		if len(numbers) == 0 {
+			return nil, note
+		}
+		sort.Float64s(numbers)
+		mid := len(numbers) / 2
+		if len(numbers)%2 == 1 {
+			return numbers[mid], note
+		}
+		return tidy((numbers[mid-1] + numbers[mid]) / 2), note
+	}
+	return nil, ""
+}
+
+// tidy removes floating point noise such as 0.30000000000000004
+func tidy(f float64) float64 {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return f
+	}
+	tidied, _ := strconv.ParseFloat(strconv.FormatFloat(f, 'g', 15, 64), 64)
+	return tidied
+}
+
+// group replaces the rows with one row per distinct combination of the
+// groupBy fields, in order of first appearance, holding those fields and
+// the aggregates. Without groupBy, all rows form one group.
+func (t *table) group(by []string, aggregates []Aggregate) ([]string, error) {
+	if len(aggregates) == 0 {
+		aggregates = []Aggregate{{Op: "count"}}
+	}
+	columns := append([]string{}, by...)
+	for _, a := range aggregates {
+		if !contains(aggregateOps, a.Op) {
+			return nil, fmt.Errorf("unknown aggregate op %q; use one of %s", a.Op, strings.Join(aggregateOps, ", "))
+		}
+		if a.Field == "" && a.Op != "count" {
+			return nil, fmt.Errorf("aggregate %s needs a field", a.Op)
+		}
+		name := a.name()
+		if contains(columns, name) {
+			return nil, fmt.Errorf("two result columns are named %s; set as to tell them apart", name)
+		}
+		columns = append(columns, name)
+	}
+
+	var firstSeen []string
+	groups := make(map[string][]map[string]interface{})
+	for _, row := range t.rows {
+		values := make([]interface{}, len(by))
+		for i, field := range by {
+			values[i], _ = lookup(row, field)
+		}
+		k := key(values...)
+		if _, ok := groups[k]; !ok {
+			firstSeen = append(firstSeen, k)
+		}
+		groups[k] = append(groups[k], row)
+	}
+	if len(by) == 0 && len(firstSeen) == 0 {
+		firstSeen = append(firstSeen, key())
+	}
+
+	var notes []string
+	noted := make(map[string]bool)
+	rows := make([]map[string]interface{}, 0, len(firstSeen))
+	for _, k := range firstSeen {
+		members := groups[k]
+		row := make(map[string]interface{}, len(columns))
+		for _, field := range by {
+			row[field], _ = lookup(members[0], field)
+		}
+		for _, a := range aggregates {
+			value, note := a.compute(members)
+			row[a.name()] = value
+			if note != "" && !noted[a.name()] {
+				noted[a.name()] = true
+				notes = append(notes, note)
+			}
+		}
+		rows = append(rows, row)
+	}
+	t.columns, t.rows = columns, rows
+	return notes, nil
+}
+
+// sortBy orders the rows by fields, descending for fields with a leading
+// minus. Null and missing values come last.
+func (t *table) sortBy(keys []string) error {
+	type sortKey struct {
+		field      string
+		descending bool
+	}
+	parsed := make([]sortKey, len(keys))
+	for i, k := range keys {
+		switch {
+		case strings.HasPrefix(k, "-"):
+			parsed[i] = sortKey{field: k[1:], descending: true}
+		case strings.HasPrefix(k, "+"):
+			parsed[i] = sortKey{field: k[1:]}
+		default:
+			parsed[i] = sortKey{field: k}
+		}
+		if parsed[i].field == "" {
+			return fmt.Errorf("invalid sort key %q", k)
+		}
+	}
+	sort.SliceStable(t.rows, func(i, j int) bool {
+		for _, k := range parsed {
+			a, _ := lookup(t.rows[i], k.field)
+			b, _ := lookup(t.rows[j], k.field)
+			if (a == nil) != (b == nil) {
+				return b == nil
+			}
+			c := compare(a, b)
+			if c == 0 {
+				continue
+			}
+			if k.descending {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+	return nil
+}
+
+// project keeps the named columns, in the given order. Dotted names pick
+// nested fields into columns of that name.
+func (t *table) project(columns []string) error {
+	for _, column := range columns {
+		found := false
+		for _, row := range t.rows {
+			if _, ok := lookup(row, column); ok {
+				found = true
+				break
+			}
+		}
+		if !found && len(t.rows) > 0 {
+			return fmt.Errorf("no row has the column %s; columns: %s", column, strings.Join(t.columns, ", "))
+		}
+	}
+	rows := make([]map[string]interface{}, len(t.rows))
+	for i, row := range t.rows {
+		rows[i] = make(map[string]interface{}, len(columns))
+		for _, column := range columns {
+			if value, ok := lookup(row, column); ok {
+				rows[i][column] = value
+			}
+		}
+	}
+	t.columns, t.rows = columns, rows
+	return nil
+}
+
+// marshalRows writes rows as a JSON array of objects with their keys in
+// column order. Rows that would take it past maxBytes are left out; it
+// returns the number of rows written.
+func (t *table) marshalRows(maxBytes int) (json.RawMessage, int, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, row := range t.rows {
+		size := buf.Len()
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('{')
+		first := true
+		for _, column := range t.columns {
+			value, ok := row[column]
+			if !ok {
+				continue
+			}
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+			name, _ := json.Marshal(column)
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return nil, 0, fmt.Errorf("row %d, column %s: %w", i+1, column, err)
+			}
+			buf.Write(name)
+			buf.WriteByte(':')
+			buf.Write(encoded)
+		}
+		buf.WriteByte('}')
+		if buf.Len()+1 > maxBytes {
+			buf.Truncate(size)
+			buf.WriteByte(']')
+			return buf.Bytes(), i, nil
+		}
+	}
+	buf.WriteByte(']')
+	return buf.Bytes(), len(t.rows), nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
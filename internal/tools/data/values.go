@@ -0,0 +1,298 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// document is decoded JSON input. Go maps forget the order of object keys,
+// so ranks keeps the order in which each key first appeared, for columns.
+type document struct {
+	value interface{}
+	ranks map[string]int
+}
+
+// decodeDocument decodes one JSON value, keeping numbers exact. JSON passed
+// as a string, as models sometimes do, is decoded once more.
+func decodeDocument(raw []byte) (*document, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, fmt.Errorf("data is not valid JSON: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("data holds more than one JSON value; wrap them in an array")
+	}
+	if s, ok := value.(string); ok {
+		if trimmed := strings.TrimSpace(s); strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+			if doc, err := decodeDocument([]byte(trimmed)); err == nil {
+				return doc, nil
+			}
+		}
+	}
+	return &document{value: value, ranks: keyRanks(raw)}, nil
+}
+
+// keyRanks numbers the object keys of a JSON text in order of first
+// appearance
+func keyRanks(raw []byte) map[string]int {
+	ranks := make(map[string]int)
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	// objects records, for each open container, whether it is an object
+	var objects []bool
+	expectKey := false
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return ranks
+		}
+		switch t := token.(type) {
+		case json.Delim:
+			switch t {
+			case '{':
+				objects = append(objects, true)
+				expectKey = true
+				continue
+			case '[':
+				objects = append(objects, false)
+				expectKey = false
+				continue
+			default:
+				objects = objects[:len(objects)-1]
+			}
+		case string:
+			if expectKey {
+				if _, ok := ranks[t]; !ok {
+					ranks[t] = len(ranks)
+				}
+				expectKey = false
+				continue
+			}
+		}
+		// A value ended, so an enclosing object expects its next key
+		expectKey = len(objects) > 0 && objects[len(objects)-1]
+	}
+}
+
+// orderKeys sorts keys by rank; keys without one, such as those a query
+// made up, follow in alphabetical order
+func orderKeys(keys []string, ranks map[string]int) {
+	sort.SliceStable(keys, func(i, j int) bool {
+		ri, iok := ranks[keys[i]]
+		rj, jok := ranks[keys[j]]
+		switch {
+		case iok && jok:
+			return ri < rj
+		case iok != jok:
+			return iok
+		default:
+			return keys[i] < keys[j]
+		}
+	})
+}
+
+// number returns v as a float64 if it is a JSON number. Values come from
+// the JSON decoder as json.Number and from queries as int, float64 or
+// *big.Int.
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(n).Float64()
+		return f, true
+	}
+	return 0, false
+}
+
+// numberPattern matches JSON numbers, which excludes values such as 007
+// or +5 that are better kept as text
+var numberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// numeric returns v as a float64 if it is a number or a string holding one
+func numeric(v interface{}) (float64, bool) {
+	if s, ok := v.(string); ok {
+		s = strings.TrimSpace(s)
+		if !numberPattern.MatchString(s) {
+			return 0, false
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		return f, err == nil
+	}
+	return number(v)
+}
+
+// typeName names the JSON type of v
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	if _, ok := number(v); ok {
+		return "number"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// lookup returns a row's field. A dotted field such as address.city
+// reaches into nested objects, and numeric parts index arrays, unless the
+// row has a key with the dotted name itself.
+func lookup(row map[string]interface{}, field string) (interface{}, bool) {
+	if value, ok := row[field]; ok {
+		return value, true
+	}
+	if !strings.Contains(field, ".") {
+		return nil, false
+	}
+	var current interface{} = row
+	for _, part := range strings.Split(field, ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[part]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []interface{}:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			current = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// typeOrder ranks types for sorting: numbers, strings, booleans, arrays
+// and objects, then null
+func typeOrder(v interface{}) int {
+	switch typeName(v) {
+	case "number":
+		return 0
+	case "string":
+		return 1
+	case "boolean":
+		return 2
+	case "null":
+		return 4
+	}
+	return 3
+}
+
+// compare orders any two values: by type, then by value
+func compare(a, b interface{}) int {
+	ta, tb := typeOrder(a), typeOrder(b)
+	if ta != tb {
+		return ta - tb
+	}
+	switch ta {
+	case 0:
+		x, _ := number(a)
+		y, _ := number(b)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	case 1:
+		return strings.Compare(a.(string), b.(string))
+	case 2:
+		x, y := a.(bool), b.(bool)
+		switch {
+		case x == y:
+			return 0
+		case !x:
+			return -1
+		}
+		return 1
+	case 3:
+		x, _ := json.Marshal(a)
+		y, _ := json.Marshal(b)
+		return bytes.Compare(x, y)
+	}
+	return 0
+}
+
+// order compares a field with a condition's value when they can be
+// ordered: two numbers, a number and a numeric string, or two strings
+func order(field, value interface{}) (int, bool) {
+	x, xok := number(field)
+	y, yok := number(value)
+	if xok || yok {
+		if !xok {
+			x, xok = numeric(field)
+		}
+		if !yok {
+			y, yok = numeric(value)
+		}
+		if !xok || !yok {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	}
+	s, sok := field.(string)
+	t, tok := value.(string)
+	if sok && tok {
+		return strings.Compare(s, t), true
+	}
+	return 0, false
+}
+
+// equal reports whether a field equals a condition's value, treating
+// numbers and numeric strings alike
+func equal(field, value interface{}) bool {
+	if c, ok := order(field, value); ok {
+		return c == 0
+	}
+	if typeOrder(field) != typeOrder(value) {
+		return false
+	}
+	return compare(field, value) == 0
+}
+
+// key identifies a tuple of values, for grouping and distinct counts
+func key(values ...interface{}) string {
+	normalized := make([]interface{}, len(values))
+	for i, v := range values {
+		// 1 and 1.0 are the same number
+		if f, ok := number(v); ok {
+			v = f
+		}
+		normalized[i] = v
+	}
+	encoded, _ := json.Marshal(normalized)
+	return string(encoded)
+}
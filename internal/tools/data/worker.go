@@ -0,0 +1,94 @@
+package data
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/itchyny/gojq"
+)
+
+// workerArg is the argument that starts this binary as a query worker
+const workerArg = "__go_tools_data_query__"
+
+var initialized bool
+
+// ErrInitNotCalled is returned when a query is run but the program never
+// called Init
+var ErrInitNotCalled = errors.New("data: Init must be called at the start of main")
+
+// workerRequest is sent to a query worker on stdin
+type workerRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+	Input     interface{}            `json:"input"`
+}
+
+// workerResult is a line of a query worker's output: a result, or the
+// error that ended the query
+type workerResult struct {
+	Value json.RawMessage `json:"value,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
+
+// Init must be called at the start of main, after sandbox.Init. When the
+// process was started as a query worker it runs the query and exits;
+// otherwise it returns immediately.
+func Init() {
+	initialized = true
+	if len(os.Args) < 2 || os.Args[1] != workerArg {
+		return
+	}
+	if err := serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "data: %v\n", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// serve runs the query read from r and writes each result to w as a line
+// of JSON
+func serve(r io.Reader, w io.Writer) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var request workerRequest
+	if err := dec.Decode(&request); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	code, values, err := compile(request.Query, request.Variables)
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(w)
+	enc := json.NewEncoder(out)
+	iter := code.Run(request.Input, values...)
+	for {
+		value, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, ok := value.(error); ok {
+			var halt *gojq.HaltError
+			if errors.As(err, &halt) && halt.Value() == nil {
+				break
+			}
+			if err := enc.Encode(workerResult{Error: err.Error()}); err != nil {
+				return err
+			}
+			break
+		}
+		// gojq writes values the way jq does, such as NaN as null
+		encoded, err := gojq.Marshal(value)
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(workerResult{Value: encoded}); err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
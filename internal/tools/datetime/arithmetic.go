@@ -0,0 +1,166 @@
+package datetime
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// addPeriod adds the calendar part of p, then the clock part. Months that
+// lack the day, such as February 30, end on their last day instead of
+// spilling into the next month. Days keep the wall clock time across
+// daylight saving changes.
+func addPeriod(t time.Time, p period) (time.Time, []string) {
+	var notes []string
+	result := t
+	if months := p.years*12 + p.months; months != 0 {
+		var clamped bool
+		result, clamped = addMonths(result, months)
+		if clamped {
+			notes = append(notes, fmt.Sprintf("%s has no day %d; used its last day", result.Format("January 2006"), t.Day()))
+		}
+	}
+	if p.days != 0 {
+		result = result.AddDate(0, 0, p.days)
+	}
+	return result.Add(p.clock), notes
+}
+
+// addMonths adds months, clamping the day to the end of the month, and
+// reports whether it did
+func addMonths(t time.Time, months int) (time.Time, bool) {
+	year, month, day := t.Date()
+	first := time.Date(year, month+time.Month(months), 1, 0, 0, 0, 0, t.Location())
+	last := daysIn(first.Year(), first.Month())
+	clamped := day > last
+	if clamped {
+		day = last
+	}
+	return time.Date(first.Year(), first.Month(), day, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location()), clamped
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// civilDays counts the calendar dates from a's date to b's date
+func civilDays(a, b time.Time) int {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return int(time.Date(by, bm, bd, 0, 0, 0, 0, time.UTC).Sub(time.Date(ay, am, ad, 0, 0, 0, 0, time.UTC)).Hours() / 24)
+}
+
+// diff breaks the time from start to end into calendar parts, the largest
+// first, and totals
+func diff(start, end time.Time) *Difference {
+	d := &Difference{}
+	if end.Before(start) {
+		start, end = end, start
+		d.Negative = true
+	}
+
+	months := (end.Year()-start.Year())*12 + int(end.Month()-start.Month())
+	anchor, _ := addMonths(start, months)
+	for months > 0 && anchor.After(end) {
+		months--
+		anchor, _ = addMonths(start, months)
+	}
+	days := civilDays(anchor, end)
+	dayAnchor := anchor.AddDate(0, 0, days)
+	for days > 0 && dayAnchor.After(end) {
+		days--
+		dayAnchor = anchor.AddDate(0, 0, days)
+	}
+	rest := end.Sub(dayAnchor)
+
+	d.Years, d.Months, d.Days = months/12, months%12, days
+	d.Hours = int(rest / time.Hour)
+	d.Minutes = int(rest % time.Hour / time.Minute)
+	d.Seconds = int(rest % time.Minute / time.Second)
+	d.ISO = isoDuration(d)
+
+	elapsed := end.Sub(start)
+	d.TotalDays = civilDays(start, end)
+	d.TotalWeeks = math.Round(float64(d.TotalDays)/7*100) / 100
+	d.TotalHours = math.Round(elapsed.Hours()*1000) / 1000
+	d.TotalSeconds = elapsed.Seconds()
+	if d.Negative {
+		d.TotalDays, d.TotalWeeks, d.TotalHours, d.TotalSeconds = -d.TotalDays, -d.TotalWeeks, -d.TotalHours, -d.TotalSeconds
+	}
+	return d
+}
+
+// isoDuration writes the parts of a difference as an ISO 8601 duration
+func isoDuration(d *Difference) string {
+	var b strings.Builder
+	if d.Negative {
+		b.WriteByte('-')
+	}
+	b.WriteByte('P')
+	for _, part := range []struct {
+		value int
+		unit  string
+	}{{d.Years, "Y"}, {d.Months, "M"}, {d.Days, "D"}} {
+		if part.value != 0 {
+			fmt.Fprintf(&b, "%d%s", part.value, part.unit)
+		}
+	}
+	if d.Hours != 0 || d.Minutes != 0 || d.Seconds != 0 {
+		b.WriteByte('T')
+		for _, part := range []struct {
+			value int
+			unit  string
+		}{{d.Hours, "H"}, {d.Minutes, "M"}, {d.Seconds, "S"}} {
+			if part.value != 0 {
+				fmt.Fprintf(&b, "%d%s", part.value, part.unit)
+			}
+		}
+	}
+	if b.Len() <= 2 && strings.HasSuffix(b.String(), "P") {
+		return "PT0S"
+	}
+	return b.String()
+}
+
+// addBusinessDays moves n business days forward, or back when n is
+// negative, keeping the time of day
+func addBusinessDays(c *calendar, t time.Time, n int) (time.Time, error) {
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	// Bound the walk in case the holidays leave no business days
+	limit := 10 * maxBusinessDays
+	for i := 0; n > 0 && i < limit; i++ {
+		t = t.AddDate(0, 0, step)
+		if c.isBusinessDay(t) {
+			n--
+		}
+	}
+	if n > 0 {
+		return time.Time{}, fmt.Errorf("the calendar has fewer business days than requested within %d days", limit)
+	}
+	return t, nil
+}
+
+// businessDaysBetween counts the business days after start's date up to
+// and including end's date, negated when end is before start
+func businessDaysBetween(c *calendar, start, end time.Time) (int, error) {
+	days := civilDays(start, end)
+	sign := 1
+	if days < 0 {
+		start, end, days, sign = end, start, -days, -1
+	}
+	if days > 2*maxBusinessDays {
+		return 0, fmt.Errorf("the dates are more than %d days apart", 2*maxBusinessDays)
+	}
+	count := 0
+	year, month, day := start.Date()
+	for i := 1; i <= days; i++ {
+		if c.isBusinessDay(time.Date(year, month, day+i, 12, 0, 0, 0, start.Location())) {
+			count++
+		}
+	}
+	return sign * count, nil
+}
@@ -0,0 +1,136 @@
+package datetime
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Config holds the settings for the datetime tool
+type Config struct {
+	// DefaultZone is the IANA time zone for values without an offset when a
+	// call names none. "Local" is the host's zone.
+	DefaultZone string `json:"defaultZone"`
+	// Weekend lists the days that are never business days
+	Weekend []string `json:"weekend"`
+	// Holidays are named holiday calendars. Each entry is a date,
+	// 2026-12-25, or a date recurring every year, 12-25, optionally followed
+	// by a space and the holiday's name.
+	Holidays map[string][]string `json:"holidays,omitempty"`
+	// DefaultCalendar is the holiday calendar used when a call names none
+	DefaultCalendar string `json:"defaultCalendar,omitempty"`
+}
+
+// DefaultConfig returns UTC, a Saturday and Sunday weekend and no holidays
+func DefaultConfig() Config {
+	return Config{
+		DefaultZone: "UTC",
+		Weekend:     []string{"Saturday", "Sunday"},
+	}
+}
+
+// Validate checks the zone, weekend days and holiday calendars
+func (c Config) Validate() error {
+	if _, err := loadZone(c.DefaultZone); err != nil {
+		return fmt.Errorf("defaultZone: %w", err)
+	}
+	if _, err := parseWeekend(c.Weekend); err != nil {
+		return err
+	}
+	for name, entries := range c.Holidays {
+		if _, err := parseHolidays(entries); err != nil {
+			return fmt.Errorf("holidays %s: %w", name, err)
+		}
+	}
+	if c.DefaultCalendar != "" {
+		if _, ok := c.Holidays[c.DefaultCalendar]; !ok {
+			return fmt.Errorf("defaultCalendar %s is not one of the holiday calendars", c.DefaultCalendar)
+		}
+	}
+	return nil
+}
+
+// parseWeekend reads weekday names such as Saturday or sat
+func parseWeekend(names []string) (map[time.Weekday]bool, error) {
+	weekend := make(map[time.Weekday]bool)
+	for _, name := range names {
+		day, ok := weekdays[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("unknown weekend day %q", name)
+		}
+		weekend[day] = true
+	}
+	if len(weekend) == 7 {
+		return nil, fmt.Errorf("every day of the week is a weekend day")
+	}
+	return weekend, nil
+}
+
+var weekdays = func() map[string]time.Weekday {
+	days := make(map[string]time.Weekday)
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		days[name] = day
+		days[name[:3]] = day
+	}
+	return days
+}()
+
+// holidays maps dates to holiday names. Recurring holidays are keyed by
+// month and day alone.
+type holidays struct {
+	dates  map[string]string
+	annual map[string]string
+}
+
+// parseHolidays reads calendar entries: 2026-12-25 or 12-25, optionally
+// followed by a name
+func parseHolidays(entries []string) (*holidays, error) {
+	h := &holidays{dates: make(map[string]string), annual: make(map[string]string)}
+	for _, entry := range entries {
+		date, name, _ := strings.Cut(strings.TrimSpace(entry), " ")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			name = "holiday"
+		}
+		if _, err := time.Parse("2006-01-02", date); err == nil {
+			h.dates[date] = name
+			continue
+		}
+		// Parsing in a leap year accepts 02-29
+		if _, err := time.Parse("2006-01-02", "2024-"+date); err == nil && len(date) == 5 {
+			h.annual[date] = name
+			continue
+		}
+		return nil, fmt.Errorf("invalid holiday %q; use YYYY-MM-DD or MM-DD, optionally followed by a name", entry)
+	}
+	return h, nil
+}
+
+// calendar decides which days are business days
+type calendar struct {
+	weekend  map[time.Weekday]bool
+	holidays []*holidays
+}
+
+// holiday returns the name of the holiday on t's date, if any
+func (c *calendar) holiday(t time.Time) (string, bool) {
+	date := t.Format("2006-01-02")
+	for _, h := range c.holidays {
+		if name, ok := h.dates[date]; ok {
+			return name, true
+		}
+		if name, ok := h.annual[date[5:]]; ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+func (c *calendar) isBusinessDay(t time.Time) bool {
+	if c.weekend[t.Weekday()] {
+		return false
+	}
+	_, holiday := c.holiday(t)
+	return !holiday
+}
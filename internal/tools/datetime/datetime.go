@@ -0,0 +1,428 @@
+package datetime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DatetimeInput represents the input schema for the datetime tool
+type DatetimeInput struct {
+	Operation string `json:"operation"`
+	// Value is the time to work on, and the start of diff and
+	// businessDaysBetween
+	Value string `json:"value,omitempty"`
+	// End is the end of diff and businessDaysBetween
+	End string `json:"end,omitempty"`
+	// Zone is the IANA zone for values without an offset, and for results
+	Zone string `json:"zone,omitempty"`
+	// To is the zone to convert or format into
+	To string `json:"to,omitempty"`
+	// Format is a preset, strftime pattern or Go layout
+	Format string `json:"format,omitempty"`
+	// DayFirst reads 03/04/2026 as 3 April instead of March 4
+	DayFirst bool `json:"dayFirst,omitempty"`
+	// Duration is the amount to add or subtract
+	Duration string `json:"duration,omitempty"`
+	// Days is the number of business days to add
+	Days *int `json:"days,omitempty"`
+	// Calendar names the holiday calendar, and Holidays adds dates to it
+	Calendar string   `json:"calendar,omitempty"`
+	Holidays []string `json:"holidays,omitempty"`
+}
+
+// Operations of the datetime tool
+const (
+	OpNow                 = "now"
+	OpParse               = "parse"
+	OpFormat              = "format"
+	OpAdd                 = "add"
+	OpSubtract            = "subtract"
+	OpDiff                = "diff"
+	OpConvert             = "convert"
+	OpDayOfWeek           = "dayOfWeek"
+	OpIsBusinessDay       = "isBusinessDay"
+	OpAddBusinessDays     = "addBusinessDays"
+	OpBusinessDaysBetween = "businessDaysBetween"
+)
+
+var operations = []string{
+	OpNow, OpParse, OpFormat, OpAdd, OpSubtract, OpDiff, OpConvert, OpDayOfWeek,
+	OpIsBusinessDay, OpAddBusinessDays, OpBusinessDaysBetween,
+}
+
+// DatetimeOutput represents the output schema for the datetime tool
+type DatetimeOutput struct {
+	Operation string `json:"operation"`
+	// Result is the time an operation produced or read
+	Result *Moment `json:"result,omitempty"`
+	// From is the input of add, subtract, convert and addBusinessDays
+	From *Moment `json:"from,omitempty"`
+	// Formatted is the result of format, or of other operations when a
+	// format is given
+	Formatted string `json:"formatted,omitempty"`
+	// Layout is the format parse recognized
+	Layout string `json:"layout,omitempty"`
+	// Difference is the result of diff
+	Difference *Difference `json:"difference,omitempty"`
+	// BusinessDays is the result of businessDaysBetween
+	BusinessDays *int `json:"businessDays,omitempty"`
+	// IsBusinessDay and Holiday are the result of isBusinessDay
+	IsBusinessDay *bool  `json:"isBusinessDay,omitempty"`
+	Holiday       string `json:"holiday,omitempty"`
+	// Notes explain assumptions, such as a missing year or a clamped day
+	Notes []string `json:"notes,omitempty"`
+}
+
+// Moment describes a point in time in a zone
+type Moment struct {
+	Time      string `json:"time"`
+	Date      string `json:"date"`
+	Clock     string `json:"clock"`
+	Weekday   string `json:"weekday"`
+	DayOfYear int    `json:"dayOfYear"`
+	ISOWeek   string `json:"isoWeek"`
+	Zone      string `json:"zone"`
+	// Abbreviation and Offset are the zone's at this moment, which change
+	// with daylight saving time
+	Abbreviation string `json:"abbreviation"`
+	Offset       string `json:"offset"`
+	Unix         int64  `json:"unix"`
+}
+
+func newMoment(t time.Time) *Moment {
+	year, week := t.ISOWeek()
+	return &Moment{
+		Time:         t.Format(time.RFC3339Nano),
+		Date:         t.Format("2006-01-02"),
+		Clock:        t.Format("15:04:05"),
+		Weekday:      t.Weekday().String(),
+		DayOfYear:    t.YearDay(),
+		ISOWeek:      fmt.Sprintf("%d-W%02d", year, week),
+		Zone:         t.Location().String(),
+		Abbreviation: t.Format("MST"),
+		Offset:       t.Format("-07:00"),
+		Unix:         t.Unix(),
+	}
+}
+
+// Difference is the time from a start to an end. Added to the start in
+// order, the parts from Years to Seconds reach the end. The totals count
+// elapsed time, except TotalDays, which counts calendar dates.
+type Difference struct {
+	Negative bool `json:"negative,omitempty"`
+	Years    int  `json:"years"`
+	Months   int  `json:"months"`
+	Days     int  `json:"days"`
+	Hours    int  `json:"hours"`
+	Minutes  int  `json:"minutes"`
+	Seconds  int  `json:"seconds"`
+	// ISO is the difference as an ISO 8601 duration
+	ISO          string  `json:"iso"`
+	TotalDays    int     `json:"totalDays"`
+	TotalWeeks   float64 `json:"totalWeeks"`
+	TotalHours   float64 `json:"totalHours"`
+	TotalSeconds float64 `json:"totalSeconds"`
+}
+
+// maxBusinessDays bounds business-day arithmetic
+const maxBusinessDays = 100000
+
+// NewDatetimeToolWithConfig creates a datetime tool
+func NewDatetimeToolWithConfig(config Config) (string, string, json.RawMessage, func(context.Context, json.RawMessage) (json.RawMessage, error)) {
+	defaultZone, _ := loadZone(config.DefaultZone)
+	weekend, _ := parseWeekend(config.Weekend)
+	calendars := make(map[string]*holidays, len(config.Holidays))
+	var calendarNames []string
+	for name, entries := range config.Holidays {
+		calendars[name], _ = parseHolidays(entries)
+		calendarNames = append(calendarNames, name)
+	}
+	sort.Strings(calendarNames)
+
+	calendarSchema := map[string]interface{}{
+		"type":        "string",
+		"description": "Holiday calendar for business days",
+	}
+	if len(calendarNames) > 0 {
+		calendarSchema["enum"] = calendarNames
+		if config.DefaultCalendar != "" {
+			calendarSchema["description"] = fmt.Sprintf("Holiday calendar for business days (default %s)", config.DefaultCalendar)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"operation": map[string]interface{}{
+				"type": "string",
+				"enum": operations,
+				"description": "now: current time; parse: read value; format: format value with format; add/subtract: value plus or minus duration; " +
+					"diff: from value to end; convert: value into zone to; dayOfWeek: weekday, ISO week and day of year of value; " +
+					"isBusinessDay: whether value is a business day; addBusinessDays: value plus days business days; " +
+					"businessDaysBetween: business days after value up to and including end",
+			},
+			"value": map[string]interface{}{
+				"type": "string",
+				"description": "A time or date, such as 2026-03-03, 2026-03-03T14:30:00+01:00, March 3 2026 2:30 PM, March 3, " +
+					"today, tomorrow or a Unix timestamp",
+			},
+			"end": map[string]interface{}{
+				"type":        "string",
+				"description": "diff and businessDaysBetween: the end, in the same formats as value",
+			},
+			"zone": map[string]interface{}{
+				"type":        "string",
+				"description": fmt.Sprintf("IANA time zone such as Europe/Berlin, for values without an offset and for results (default %s)", config.DefaultZone),
+			},
+			"to": map[string]interface{}{
+				"type":        "string",
+				"description": "convert and format: the IANA time zone to convert into",
+			},
+			"format": map[string]interface{}{
+				"type": "string",
+				"description": "format, or any operation to also format its result: rfc3339, rfc1123, date, time, datetime, human, unix, unixMilli, a strftime pattern such as %d.%m.%Y %H:%M, " +
+					"or a Go layout such as Jan 2, 2006",
+			},
+			"dayFirst": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Read numeric dates such as 03/04/2026 as day/month instead of month/day",
+			},
+			"duration": map[string]interface{}{
+				"type":        "string",
+				"description": "add and subtract: words such as 90 days or 1 year 2 months 3 hours, ISO 8601 such as P1Y2M10DT2H, or 1h30m",
+			},
+			"days": map[string]interface{}{
+				"type":        "integer",
+				"description": "addBusinessDays: number of business days, negative to go back",
+			},
+			"calendar": calendarSchema,
+			"holidays": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Additional holidays as YYYY-MM-DD, or MM-DD for every year",
+			},
+		},
+		"required": []string{"operation"},
+	}
+
+	schemaJSON, _ := json.Marshal(schema)
+
+	return "datetime",
+		"Computes with dates, times and time zones: current time, parsing and formatting, adding durations and calendar periods, " +
+			"differences, time zone conversion, weekdays and business days. Use it instead of working out dates yourself.",
+		schemaJSON,
+		func(ctx context.Context, input json.RawMessage) (json.RawMessage, error) {
+			var params DatetimeInput
+			if err := json.Unmarshal(input, &params); err != nil {
+				return nil, fmt.Errorf("invalid input: %w", err)
+			}
+
+			zone := defaultZone
+			explicitZone := strings.TrimSpace(params.Zone) != ""
+			if explicitZone {
+				var err error
+				if zone, err = loadZone(params.Zone); err != nil {
+					return nil, err
+				}
+			}
+			now := time.Now()
+			output := &DatetimeOutput{Operation: params.Operation}
+			// result is the time the operation produced, if any
+			var result time.Time
+
+			// parse reads a value. With an explicit zone, values with their
+			// own offset are shown in that zone.
+			parse := func(name, value string) (time.Time, error) {
+				if strings.TrimSpace(value) == "" {
+					return time.Time{}, fmt.Errorf("%s needs %s", params.Operation, name)
+				}
+				p, err := parseTime(value, zone, now, params.DayFirst)
+				if err != nil {
+					return time.Time{}, err
+				}
+				output.Notes = append(output.Notes, p.notes...)
+				if params.Operation == OpParse {
+					output.Layout = p.layout
+				}
+				if explicitZone {
+					return p.time.In(zone), nil
+				}
+				return p.time, nil
+			}
+
+			cal := func() (*calendar, error) {
+				c := &calendar{weekend: weekend}
+				name := params.Calendar
+				if name == "" {
+					name = config.DefaultCalendar
+				}
+				if name != "" {
+					h, ok := calendars[name]
+					if !ok {
+						return nil, fmt.Errorf("unknown calendar %q", name)
+					}
+					c.holidays = append(c.holidays, h)
+				}
+				if len(params.Holidays) > 0 {
+					h, err := parseHolidays(params.Holidays)
+					if err != nil {
+						return nil, err
+					}
+					c.holidays = append(c.holidays, h)
+				}
+				return c, nil
+			}
+
+			switch params.Operation {
+			case OpNow:
+				result = now.In(zone)
+
+			case OpParse, OpDayOfWeek:
+				t, err := parse("value", params.Value)
+				if err != nil {
+					return nil, err
+				}
+				result = t
+
+			case OpFormat:
+				t, err := parse("value", params.Value)
+				if err != nil {
+					return nil, err
+				}
+				if params.To != "" {
+					to, err := loadZone(params.To)
+					if err != nil {
+						return nil, err
+					}
+					t = t.In(to)
+				}
+				if params.Format == "" {
+					return nil, fmt.Errorf("format needs a format")
+				}
+				if output.Formatted, err = formatTime(t, params.Format); err != nil {
+					return nil, err
+				}
+
+			case OpAdd, OpSubtract:
+				t, err := parse("value", params.Value)
+				if err != nil {
+					return nil, err
+				}
+				if strings.TrimSpace(params.Duration) == "" {
+					return nil, fmt.Errorf("%s needs a duration", params.Operation)
+				}
+				p, err := parsePeriod(params.Duration)
+				if err != nil {
+					return nil, err
+				}
+				if params.Operation == OpSubtract {
+					p = p.negate()
+				}
+				var notes []string
+				result, notes = addPeriod(t, p)
+				output.From = newMoment(t)
+				output.Notes = append(output.Notes, notes...)
+
+			case OpDiff:
+				start, err := parse("value", params.Value)
+				if err != nil {
+					return nil, err
+				}
+				end, err := parse("end", params.End)
+				if err != nil {
+					return nil, err
+				}
+				output.Difference = diff(start, end.In(start.Location()))
+
+			case OpConvert:
+				t, err := parse("value", params.Value)
+				if err != nil {
+					return nil, err
+				}
+				if params.To == "" {
+					return nil, fmt.Errorf("convert needs a zone to convert to")
+				}
+				to, err := loadZone(params.To)
+				if err != nil {
+					return nil, err
+				}
+				output.From, result = newMoment(t), t.In(to)
+
+			case OpIsBusinessDay:
+				t, err := parse("value", params.Value)
+				if err != nil {
+					return nil, err
+				}
+				c, err := cal()
+				if err != nil {
+					return nil, err
+				}
+				business := c.isBusinessDay(t)
+				result, output.IsBusinessDay = t, &business
+				output.Holiday, _ = c.holiday(t)
+
+			case OpAddBusinessDays:
+				t, err := parse("value", params.Value)
+				if err != nil {
+					return nil, err
+				}
+				if params.Days == nil {
+					return nil, fmt.Errorf("addBusinessDays needs days")
+				}
+				if *params.Days > maxBusinessDays || *params.Days < -maxBusinessDays {
+					return nil, fmt.Errorf("days must be between -%d and %d", maxBusinessDays, maxBusinessDays)
+				}
+				c, err := cal()
+				if err != nil {
+					return nil, err
+				}
+				result, err = addBusinessDays(c, t, *params.Days)
+				if err != nil {
+					return nil, err
+				}
+				if *params.Days == 0 && !c.isBusinessDay(t) {
+					output.Notes = append(output.Notes, "adding 0 days keeps a day that is not a business day")
+				}
+				output.From = newMoment(t)
+
+			case OpBusinessDaysBetween:
+				start, err := parse("value", params.Value)
+				if err != nil {
+					return nil, err
+				}
+				end, err := parse("end", params.End)
+				if err != nil {
+					return nil, err
+				}
+				c, err := cal()
+				if err != nil {
+					return nil, err
+				}
+				count, err := businessDaysBetween(c, start, end.In(start.Location()))
+				if err != nil {
+					return nil, err
+				}
+				output.BusinessDays = &count
+
+			case "":
+				return nil, fmt.Errorf("operation is required")
+			default:
+				return nil, fmt.Errorf("unknown operation %q; use one of %s", params.Operation, strings.Join(operations, ", "))
+			}
+
+			if !result.IsZero() {
+				output.Result = newMoment(result)
+				if params.Format != "" {
+					var err error
+					if output.Formatted, err = formatTime(result, params.Format); err != nil {
+						return nil, err
+					}
+				}
+			}
+			return json.Marshal(output)
+		}
+}
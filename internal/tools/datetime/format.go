@@ -0,0 +1,145 @@
+package datetime
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// presets are named formats
+var presets = map[string]string{
+	"rfc3339":     time.RFC3339,
+	"rfc3339nano": time.RFC3339Nano,
+	"iso8601":     time.RFC3339,
+	"rfc1123":     time.RFC1123,
+	"rfc1123z":    time.RFC1123Z,
+	"rfc822":      time.RFC822,
+	"rfc850":      time.RFC850,
+	"ansic":       time.ANSIC,
+	"kitchen":     time.Kitchen,
+	"date":        "2006-01-02",
+	"time":        "15:04:05",
+	"datetime":    "2006-01-02 15:04:05",
+	"human":       "Monday, January 2, 2006 at 3:04 PM MST",
+}
+
+// formatTime formats t with a preset name, unix or unixMilli, a strftime
+// pattern such as %d/%m/%Y, or a Go layout such as 02 Jan 2006
+func formatTime(t time.Time, format string) (string, error) {
+	switch name := strings.ToLower(strings.TrimSpace(format)); name {
+	case "unix":
+		return strconv.FormatInt(t.Unix(), 10), nil
+	case "unixmilli":
+		return strconv.FormatInt(t.UnixMilli(), 10), nil
+	default:
+		if layout, ok := presets[name]; ok {
+			return t.Format(layout), nil
+		}
+	}
+	if strings.Contains(format, "%") {
+		return strftime(t, format)
+	}
+	// A layout without any reference component formats to itself
+	if formatted := t.Format(format); formatted != format {
+		return formatted, nil
+	}
+	return "", fmt.Errorf("format %q is not a preset, a strftime pattern such as %%Y-%%m-%%d, or a Go layout such as 2006-01-02", format)
+}
+
+// strftime formats t with C-style directives
+func strftime(t time.Time, format string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		if c != '%' {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		if i == len(format) {
+			return "", fmt.Errorf("format %q ends with a lone %%", format)
+		}
+		switch format[i] {
+		case 'Y':
+			fmt.Fprintf(&b, "%04d", t.Year())
+		case 'y':
+			fmt.Fprintf(&b, "%02d", t.Year()%100)
+		case 'C':
+			fmt.Fprintf(&b, "%02d", t.Year()/100)
+		case 'm':
+			fmt.Fprintf(&b, "%02d", int(t.Month()))
+		case 'd':
+			fmt.Fprintf(&b, "%02d", t.Day())
+		case 'e':
+			fmt.Fprintf(&b, "%2d", t.Day())
+		case 'H':
+			fmt.Fprintf(&b, "%02d", t.Hour())
+		case 'I':
+			fmt.Fprintf(&b, "%02d", (t.Hour()+11)%12+1)
+		case 'M':
+			fmt.Fprintf(&b, "%02d", t.Minute())
+		case 'S':
+			fmt.Fprintf(&b, "%02d", t.Second())
+		case 'L':
+			fmt.Fprintf(&b, "%03d", t.Nanosecond()/1e6)
+		case 'f':
+			fmt.Fprintf(&b, "%06d", t.Nanosecond()/1e3)
+		case 'p':
+			b.WriteString(t.Format("PM"))
+		case 'b', 'h':
+			b.WriteString(t.Format("Jan"))
+		case 'B':
+			b.WriteString(t.Format("January"))
+		case 'a':
+			b.WriteString(t.Format("Mon"))
+		case 'A':
+			b.WriteString(t.Format("Monday"))
+		case 'j':
+			fmt.Fprintf(&b, "%03d", t.YearDay())
+		case 'u':
+			fmt.Fprintf(&b, "%d", isoWeekday(t))
+		case 'w':
+			fmt.Fprintf(&b, "%d", int(t.Weekday()))
+		case 'V':
+			_, week := t.ISOWeek()
+			fmt.Fprintf(&b, "%02d", week)
+		case 'G':
+			year, _ := t.ISOWeek()
+			fmt.Fprintf(&b, "%04d", year)
+		case 'Z':
+			b.WriteString(t.Format("MST"))
+		case 'z':
+			b.WriteString(t.Format("-0700"))
+		case 's':
+			fmt.Fprintf(&b, "%d", t.Unix())
+		case 'F':
+			b.WriteString(t.Format("2006-01-02"))
+		case 'T':
+			b.WriteString(t.Format("15:04:05"))
+		case 'R':
+			b.WriteString(t.Format("15:04"))
+		case 'D':
+			b.WriteString(t.Format("01/02/06"))
+		case 'c':
+			b.WriteString(t.Format(time.ANSIC))
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case '%':
+			b.WriteByte('%')
+		default:
+			return "", fmt.Errorf("unsupported directive %%%c in format %q", format[i], format)
+		}
+	}
+	return b.String(), nil
+}
+
+// isoWeekday numbers the days from Monday, 1, to Sunday, 7
+func isoWeekday(t time.Time) int {
+	if t.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(t.Weekday())
+}
@@ -0,0 +1,328 @@
+package datetime
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	// Embedded zone data, so IANA zones work on hosts without it
+	_ "time/tzdata"
+)
+
+// offsetPattern matches fixed offsets such as +05:30, UTC-8 or GMT+1
+var offsetPattern = regexp.MustCompile(`^(?i:UTC|GMT)?([+-])(\d{1,2})(?::?(\d{2}))?$`)
+
+// loadZone resolves an IANA zone name, UTC, Local or a fixed offset
+func loadZone(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	switch strings.ToUpper(name) {
+	case "UTC", "Z", "GMT":
+		return time.UTC, nil
+	case "LOCAL":
+		return time.Local, nil
+	}
+	if m := offsetPattern.FindStringSubmatch(name); m != nil {
+		hours, _ := strconv.Atoi(m[2])
+		minutes, _ := strconv.Atoi(m[3])
+		if hours > 14 || minutes > 59 {
+			return nil, fmt.Errorf("invalid offset %s", name)
+		}
+		seconds := hours*3600 + minutes*60
+		if m[1] == "-" {
+			seconds = -seconds
+		}
+		return time.FixedZone(fmt.Sprintf("UTC%s%02d:%02d", m[1], hours, minutes), seconds), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "" {
+		return nil, fmt.Errorf("unknown time zone %q; use an IANA name such as Europe/Berlin or America/New_York, or an offset such as +05:30", name)
+	}
+	return loc, nil
+}
+
+// layouts are tried in order for values that are not relative words or
+// timestamps. Layouts without a year take the current one.
+var layouts = []struct {
+	layout string
+	noYear bool
+}{
+	{layout: time.RFC3339Nano},
+	{layout: "2006-01-02T15:04:05Z0700"},
+	{layout: "2006-01-02T15:04:05"},
+	{layout: "2006-01-02T15:04"},
+	{layout: "2006-01-02 15:04:05Z07:00"},
+	{layout: "2006-01-02 15:04:05 -0700"},
+	{layout: "2006-01-02 15:04:05 MST"},
+	{layout: "2006-01-02 15:04:05"},
+	{layout: "2006-01-02 15:04"},
+	{layout: "2006-01-02 3:04 PM"},
+	{layout: "2006-01-02"},
+	{layout: "2006/01/02 15:04:05"},
+	{layout: "2006/01/02 15:04"},
+	{layout: "2006/01/02"},
+	{layout: "20060102T150405Z0700"},
+	{layout: "20060102T150405"},
+	{layout: time.RFC1123Z},
+	{layout: time.RFC1123},
+	{layout: time.RFC850},
+	{layout: time.RFC822Z},
+	{layout: time.RFC822},
+	{layout: time.ANSIC},
+	{layout: time.UnixDate},
+	{layout: time.RubyDate},
+	{layout: "02.01.2006 15:04:05"},
+	{layout: "02.01.2006 15:04"},
+	{layout: "02.01.2006"},
+	{layout: "Monday, January 2, 2006 3:04 PM"},
+	{layout: "Monday, January 2, 2006 15:04"},
+	{layout: "Monday, January 2, 2006"},
+	{layout: "Monday, 2 January 2006"},
+	{layout: "Mon, Jan 2, 2006"},
+	{layout: "Mon Jan 2 2006"},
+	{layout: "January 2, 2006 3:04:05 PM"},
+	{layout: "January 2, 2006 3:04 PM"},
+	{layout: "January 2, 2006 15:04:05"},
+	{layout: "January 2, 2006 15:04"},
+	{layout: "January 2, 2006"},
+	{layout: "January 2 2006"},
+	{layout: "2 January 2006 15:04"},
+	{layout: "2 January 2006"},
+	{layout: "January 2006"},
+	{layout: "Jan 2, 2006 3:04 PM"},
+	{layout: "Jan 2, 2006 15:04"},
+	{layout: "Jan 2, 2006"},
+	{layout: "Jan 2 2006"},
+	{layout: "2 Jan 2006 15:04"},
+	{layout: "2 Jan 2006"},
+	{layout: "Jan 2006"},
+	{layout: "January 2", noYear: true},
+	{layout: "Jan 2", noYear: true},
+	{layout: "2 January", noYear: true},
+	{layout: "2 Jan", noYear: true},
+	{layout: "Monday, January 2", noYear: true},
+}
+
+// Numeric dates with slashes or dashes, which differ between the US and
+// most other places
+var (
+	monthFirstLayouts = []string{"01/02/2006 15:04:05", "01/02/2006 15:04", "01/02/2006 3:04 PM", "01/02/2006", "01-02-2006", "01/02/06"}
+	dayFirstLayouts   = []string{"02/01/2006 15:04:05", "02/01/2006 15:04", "02/01/2006 3:04 PM", "02/01/2006", "02-01-2006", "02/01/06"}
+	numericDate       = regexp.MustCompile(`^(\d{1,2})[/-](\d{1,2})[/-]\d{2,4}`)
+)
+
+var (
+	ordinalSuffix = regexp.MustCompile(`(?i)\b(\d{1,2})(st|nd|rd|th)\b`)
+	meridiem      = regexp.MustCompile(`(?i)(\d)\s*([ap])\.?m\.?$`)
+	clockOnly     = regexp.MustCompile(`^\d{1,2}(:\d{2}(:\d{2})?( [AP]M)?| [AP]M)$`)
+	spaces        = regexp.MustCompile(`\s+`)
+)
+
+// parsed is a parsed value with the layout that matched
+type parsed struct {
+	time   time.Time
+	layout string
+	notes  []string
+}
+
+// parseTime reads a time in one of many formats. Values without an offset
+// are in loc. Besides the layouts above it accepts now, today, tomorrow,
+// yesterday, Unix timestamps in seconds or milliseconds, and a time of
+// day, which is taken as today.
+func parseTime(value string, loc *time.Location, now time.Time, dayFirst bool) (*parsed, error) {
+	v := strings.TrimSpace(value)
+	if v == "" {
+		return nil, fmt.Errorf("a time value is required")
+	}
+	now = now.In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+	switch strings.ToLower(v) {
+	case "now":
+		return &parsed{time: now, layout: "now"}, nil
+	case "today":
+		return &parsed{time: today, layout: "today"}, nil
+	case "tomorrow":
+		return &parsed{time: today.AddDate(0, 0, 1), layout: "tomorrow"}, nil
+	case "yesterday":
+		return &parsed{time: today.AddDate(0, 0, -1), layout: "yesterday"}, nil
+	}
+
+	if digits := strings.TrimPrefix(v, "-"); digits != "" && strings.Trim(digits, "0123456789") == "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case len(digits) == 8 && !strings.HasPrefix(v, "-"):
+			if t, err := time.ParseInLocation("20060102", v, loc); err == nil {
+				return &parsed{time: t, layout: "20060102"}, nil
+			}
+		case len(digits) >= 12:
+			return &parsed{time: time.UnixMilli(n).In(loc), layout: "unix milliseconds"}, nil
+		}
+		return &parsed{time: time.Unix(n, 0).In(loc), layout: "unix seconds"}, nil
+	}
+
+	// Normalize ordinals, "at", a.m./p.m. and spacing
+	v = ordinalSuffix.ReplaceAllString(v, "$1")
+	v = spaces.ReplaceAllString(v, " ")
+	v = strings.Replace(v, " at ", " ", 1)
+	v = meridiem.ReplaceAllStringFunc(v, func(s string) string {
+		m := meridiem.FindStringSubmatch(s)
+		return m[1] + " " + strings.ToUpper(m[2]) + "M"
+	})
+
+	if clockOnly.MatchString(v) {
+		for _, layout := range []string{"15:04", "15:04:05", "3:04 PM", "3:04:05 PM", "3 PM"} {
+			if t, err := time.ParseInLocation(layout, v, loc); err == nil {
+				t = time.Date(today.Year(), today.Month(), today.Day(), t.Hour(), t.Minute(), t.Second(), 0, loc)
+				return &parsed{time: t, layout: layout, notes: []string{"no date given; used today"}}, nil
+			}
+		}
+	}
+
+	for _, l := range layouts {
+		t, err := time.ParseInLocation(l.layout, v, loc)
+		if err != nil {
+			continue
+		}
+		result := &parsed{time: t, layout: l.layout}
+		if l.noYear {
+			result.time = time.Date(now.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+			result.notes = append(result.notes, fmt.Sprintf("no year given; used %d", now.Year()))
+		}
+		return result, nil
+	}
+
+	if m := numericDate.FindStringSubmatch(v); m != nil {
+		first, second := monthFirstLayouts, dayFirstLayouts
+		order := "month/day"
+		if dayFirst {
+			first, second = second, first
+			order = "day/month"
+		}
+		for _, candidates := range [][]string{first, second} {
+			for _, layout := range candidates {
+				t, err := time.ParseInLocation(layout, v, loc)
+				if err != nil {
+					continue
+				}
+				result := &parsed{time: t, layout: layout}
+				a, _ := strconv.Atoi(m[1])
+				b, _ := strconv.Atoi(m[2])
+				if a != b && a <= 12 && b <= 12 {
+					result.notes = append(result.notes, fmt.Sprintf("%s is ambiguous; read as %s, set dayFirst to change this", value, order))
+				}
+				return result, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("could not parse %q as a time; use a format such as 2026-03-03, 2026-03-03T14:30:00+01:00 or March 3, 2026 2:30 PM", value)
+}
+
+// period is an amount of time to add: calendar years, months and days,
+// which follow the calendar and daylight saving time, and an exact clock
+// duration
+type period struct {
+	years, months, days int
+	clock               time.Duration
+}
+
+func (p period) negate() period {
+	return period{years: -p.years, months: -p.months, days: -p.days, clock: -p.clock}
+}
+
+func (p period) isZero() bool {
+	return p == period{}
+}
+
+var (
+	isoPeriod  = regexp.MustCompile(`^([+-])?P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+	periodPart = regexp.MustCompile(`([+-]?\d+(?:\.\d+)?)\s*([a-zA-Z]+)`)
+	periodGlue = regexp.MustCompile(`^(?i:[\s,+]|and)*$`)
+)
+
+// periodUnits maps unit words to their kind
+var periodUnits = map[string]string{
+	"y": "year", "yr": "year", "yrs": "year", "year": "year", "years": "year",
+	"mo": "month", "mon": "month", "mons": "month", "month": "month", "months": "month",
+	"w": "week", "wk": "week", "wks": "week", "week": "week", "weeks": "week",
+	"d": "day", "day": "day", "days": "day",
+	"h": "hour", "hr": "hour", "hrs": "hour", "hour": "hour", "hours": "hour",
+	"m": "minute", "min": "minute", "mins": "minute", "minute": "minute", "minutes": "minute",
+	"s": "second", "sec": "second", "secs": "second", "second": "second", "seconds": "second",
+	"ms": "millisecond", "millisecond": "millisecond", "milliseconds": "millisecond",
+}
+
+// parsePeriod reads an ISO 8601 duration (P1Y2M10DT2H30M), a Go duration
+// (1h30m) or words (90 days, 1 year 2 months, 3 weeks and 2 hours)
+func parsePeriod(s string) (period, error) {
+	s = strings.TrimSpace(s)
+	if m := isoPeriod.FindStringSubmatch(strings.ToUpper(s)); m != nil && s != "P" && !strings.HasSuffix(strings.ToUpper(s), "T") {
+		var p period
+		atoi := func(s string) int { n, _ := strconv.Atoi(s); return n }
+		p.years, p.months = atoi(m[2]), atoi(m[3])
+		p.days = atoi(m[4])*7 + atoi(m[5])
+		seconds, _ := strconv.ParseFloat(m[8], 64)
+		p.clock = time.Duration(atoi(m[6]))*time.Hour + time.Duration(atoi(m[7]))*time.Minute +
+			time.Duration(seconds*float64(time.Second))
+		if m[1] == "-" {
+			p = p.negate()
+		}
+		return p, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return period{clock: d}, nil
+	}
+
+	var p period
+	matches := periodPart.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return p, fmt.Errorf("could not parse duration %q; use words such as 90 days or 1 year 2 months, or ISO 8601 such as P1Y2M10DT2H", s)
+	}
+	last := 0
+	for _, m := range matches {
+		if !periodGlue.MatchString(s[last:m[0]]) {
+			return p, fmt.Errorf("could not parse duration %q at %q", s, s[last:m[0]])
+		}
+		last = m[1]
+		number, _ := strconv.ParseFloat(s[m[2]:m[3]], 64)
+		unit, ok := periodUnits[strings.ToLower(s[m[4]:m[5]])]
+		if !ok {
+			return p, fmt.Errorf("unknown unit %q in duration %q", s[m[4]:m[5]], s)
+		}
+		whole := number == math.Trunc(number)
+		switch unit {
+		case "year", "month", "week", "day":
+			if !whole {
+				return p, fmt.Errorf("%s must be a whole number of %ss; use a smaller unit for fractions", s[m[0]:m[1]], unit)
+			}
+		}
+		switch unit {
+		case "year":
+			p.years += int(number)
+		case "month":
+			p.months += int(number)
+		case "week":
+			p.days += int(number) * 7
+		case "day":
+			p.days += int(number)
+		case "hour":
+			p.clock += time.Duration(number * float64(time.Hour))
+		case "minute":
+			p.clock += time.Duration(number * float64(time.Minute))
+		case "second":
+			p.clock += time.Duration(number * float64(time.Second))
+		case "millisecond":
+			p.clock += time.Duration(number * float64(time.Millisecond))
+		}
+	}
+	if !periodGlue.MatchString(s[last:]) {
+		return p, fmt.Errorf("could not parse duration %q at %q", s, s[last:])
+	}
+	return p, nil
+}
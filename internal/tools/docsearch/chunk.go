@@ -0,0 +1,99 @@
+package docsearch
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// span is a chunk's byte range in a document's text
+type span struct {
+	start, end int
+}
+
+// split cuts text into chunks of about size bytes, where each chunk repeats
+// the last overlap bytes of the previous one. Chunks end at a paragraph,
+// line, sentence or word break where one is near, and start at a word.
+func split(text string, size, overlap int) []span {
+	var spans []span
+	start := 0
+	for start < len(text) {
+		end := len(text)
+		if end-start > size {
+			end = breakBefore(text, start, start+size)
+		}
+		if s, e := trimSpan(text, start, end); s < e {
+			spans = append(spans, span{s, e})
+		}
+		if end == len(text) {
+			break
+		}
+
+		next := wordStart(text, end-overlap, end)
+		if next <= start {
+			next = end
+		}
+		start = next
+	}
+	return spans
+}
+
+// breakBefore finds where to end a chunk that may not go past limit,
+// preferring the strongest break in the second half of the chunk
+func breakBefore(text string, start, limit int) int {
+	limit = runeStart(text, limit)
+	window := text[start+(limit-start)/2 : limit]
+	offset := limit - len(window)
+	for _, sep := range []string{"\n\n", "\n", ". ", "? ", "! ", "; ", " "} {
+		if i := strings.LastIndex(window, sep); i >= 0 {
+			return offset + i + len(sep)
+		}
+	}
+	return limit
+}
+
+// wordStart returns the first word start at or after i, or i itself when
+// there is none before limit
+func wordStart(text string, i, limit int) int {
+	i = runeStart(text, i)
+	if i == 0 || isSpace(text[i-1]) {
+		return i
+	}
+	for j := i; j < limit; j++ {
+		if isSpace(text[j]) {
+			return j + 1
+		}
+	}
+	return i
+}
+
+// runeStart moves i back to the start of the rune it points into
+func runeStart(text string, i int) int {
+	for i > 0 && i < len(text) && !utf8.RuneStart(text[i]) {
+		i--
+	}
+	return i
+}
+
+// trimSpan drops leading and trailing white space from a span
+func trimSpan(text string, start, end int) (int, int) {
+	for start < end && isSpace(text[start]) {
+		start++
+	}
+	for end > start && isSpace(text[end-1]) {
+		end--
+	}
+	return start, end
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+// tokenize splits text into lower-case words of letters and digits, for
+// BM25 and the hash embedder
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
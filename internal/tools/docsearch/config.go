@@ -0,0 +1,137 @@
+package docsearch
+
+import (
+	"fmt"
+	"os"
+)
+
+// Config holds the settings for the document search tool and its indexer
+type Config struct {
+	// Dir holds the documents to index: Markdown, text, HTML and PDF files
+	// at any depth
+	Dir string `json:"dir"`
+	// Index is the index file the indexer writes and the tool reads; the
+	// tool is disabled when it is empty
+	Index string `json:"index"`
+	// ChunkSize is the length of a chunk in bytes, and ChunkOverlap how much
+	// of it is repeated at the start of the next chunk
+	ChunkSize    int `json:"chunkSize"`
+	ChunkOverlap int `json:"chunkOverlap"`
+	// MaxFileBytes skips larger files when indexing
+	MaxFileBytes int64 `json:"maxFileBytes"`
+	// Embedder turns chunks and queries into vectors
+	Embedder EmbedderConfig `json:"embedder"`
+	// TopK is the number of results when the query does not ask for a
+	// number, and MaxTopK the most it may ask for
+	TopK    int `json:"topK"`
+	MaxTopK int `json:"maxTopK"`
+	// VectorWeight is the share of the vector score in hybrid ranking; the
+	// rest is the BM25 score
+	VectorWeight float64 `json:"vectorWeight"`
+}
+
+// Embedder providers
+const (
+	// ProviderHash embeds locally by hashing words and character trigrams.
+	// It needs no model or network and always gives the same vectors.
+	ProviderHash = "hash"
+	// ProviderOpenAI uses an OpenAI-compatible embeddings API
+	ProviderOpenAI = "openai"
+)
+
+// EmbedderConfig selects and configures the embedder
+type EmbedderConfig struct {
+	// Provider is "hash" or "openai"
+	Provider string `json:"provider"`
+	// Model is the embedding model for the openai provider
+	Model string `json:"model,omitempty"`
+	// BaseURL points the openai provider at a compatible server
+	BaseURL string `json:"baseURL,omitempty"`
+	// APIKey for the openai provider, with ${NAME} references expanded.
+	// OPENAI_API_KEY is used when it is empty.
+	APIKey string `json:"apiKey,omitempty"`
+	// Dimensions is the vector size: 384 by default for hash, and for
+	// openai only set for models that can shorten their vectors
+	Dimensions int `json:"dimensions,omitempty"`
+	// BatchSize is the number of chunks embedded per request
+	BatchSize int `json:"batchSize,omitempty"`
+}
+
+// defaultHashDimensions is the vector size of the hash embedder
+const defaultHashDimensions = 384
+
+// DefaultConfig returns the default settings, with the tool disabled and
+// the local embedder
+func DefaultConfig() Config {
+	return Config{
+		ChunkSize:    1200,
+		ChunkOverlap: 200,
+		MaxFileBytes: 20 << 20,
+		Embedder: EmbedderConfig{
+			Provider:  ProviderHash,
+			BatchSize: 64,
+		},
+		TopK:         5,
+		MaxTopK:      20,
+		VectorWeight: 0.5,
+	}
+}
+
+// Validate checks the chunking, ranking and embedder settings
+func (c Config) Validate() error {
+	if c.Index != "" && c.Dir == "" {
+		return fmt.Errorf("dir is required with an index")
+	}
+	if c.ChunkSize < 100 {
+		return fmt.Errorf("chunkSize must be at least 100")
+	}
+	if c.ChunkOverlap < 0 || c.ChunkOverlap >= c.ChunkSize/2 {
+		return fmt.Errorf("chunkOverlap must be between 0 and half of chunkSize")
+	}
+	if c.MaxFileBytes <= 0 {
+		return fmt.Errorf("maxFileBytes must be positive")
+	}
+	if c.TopK <= 0 || c.MaxTopK < c.TopK {
+		return fmt.Errorf("topK must be positive and maxTopK at least topK")
+	}
+	if c.VectorWeight < 0 || c.VectorWeight > 1 {
+		return fmt.Errorf("vectorWeight must be between 0 and 1")
+	}
+	return c.Embedder.Validate()
+}
+
+// Validate checks the provider and its settings
+func (c EmbedderConfig) Validate() error {
+	if c.Dimensions < 0 || c.BatchSize <= 0 {
+		return fmt.Errorf("embedder dimensions must not be negative and batchSize must be positive")
+	}
+	switch c.Provider {
+	case ProviderHash:
+	case ProviderOpenAI:
+		if c.Model == "" {
+			return fmt.Errorf("the openai embedder needs a model")
+		}
+	default:
+		return fmt.Errorf("unknown embedder provider %q", c.Provider)
+	}
+	return nil
+}
+
+// apiKey returns the expanded API key, falling back to OPENAI_API_KEY
+func (c EmbedderConfig) apiKey() string {
+	if c.APIKey != "" {
+		return os.ExpandEnv(c.APIKey)
+	}
+	return os.Getenv("OPENAI_API_KEY")
+}
+
+// Secrets returns the embedder's API key, so callers can redact it
+func Secrets(config Config) []string {
+	if config.Embedder.Provider != ProviderOpenAI {
+		return nil
+	}
+	if key := config.Embedder.apiKey(); key != "" {
+		return []string{key}
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package docsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DocSearchInput represents the input schema for the document search tool
+type DocSearchInput struct {
+	Query string `json:"query"`
+	// TopK is the number of chunks to return
+	TopK int `json:"topK,omitempty"`
+	// Mode is hybrid, bm25 or vector
+	Mode string `json:"mode,omitempty"`
+	// Path limits the search to a file or directory
+	Path string `json:"path,omitempty"`
+}
+
+// DocSearchOutput represents the output schema for the document search tool
+type DocSearchOutput struct {
+	Query   string         `json:"query"`
+	Mode    string         `json:"mode"`
+	Results []SearchResult `json:"results"`
+	// Documents and Chunks are the size of the index, built at Indexed
+	Documents int       `json:"documents"`
+	Chunks    int       `json:"chunks"`
+	Indexed   time.Time `json:"indexed"`
+}
+
+// SearchResult is a matching chunk
+type SearchResult struct {
+	// Path is the document's path in the indexed directory
+	Path  string `json:"path"`
+	Title string `json:"title,omitempty"`
+	// Start and End are byte offsets of the chunk in the document's text,
+	// which for Markdown and text files is the file itself
+	Start int `json:"start"`
+	End   int `json:"end"`
+	// Page is the PDF page the chunk starts on
+	Page int `json:"page,omitempty"`
+	// Score ranks the results; BM25 and Vector are its parts
+	Score  float64 `json:"score"`
+	BM25   float64 `json:"bm25,omitempty"`
+	Vector float64 `json:"vector,omitempty"`
+	Text   string  `json:"text"`
+}
+
+// indexFile loads the index and reloads it when the indexer replaces it,
+// so the agent need not be restarted after reindexing
+type indexFile struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	index   *Index
+}
+
+func (f *indexFile) get() (*Index, error) {
+	info, err := os.Stat(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no document index at %s; build it with the docindex command", f.path)
+	} else if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.index != nil && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.index, nil
+	}
+	index, err := LoadIndex(f.path)
+	if err != nil {
+		return nil, err
+	}
+	f.index, f.modTime, f.size = index, info.ModTime(), info.Size()
+	return index, nil
+}
+
+// NewDocSearchToolWithConfig creates a tool searching the index at
+// config.Index. Queries are embedded with embedder, which must be the one
+// the index was built with.
+func NewDocSearchToolWithConfig(config Config, embedder Embedder) (string, string, json.RawMessage, func(context.Context, json.RawMessage) (json.RawMessage, error)) {
+	file := &indexFile{path: config.Index}
+
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "What to look for, as a question or keywords",
+			},
+			"topK": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Number of passages to return (default %d)", config.TopK),
+				"minimum":     1,
+				"maximum":     config.MaxTopK,
+			},
+			"mode": map[string]interface{}{
+				"type": "string",
+				"enum": []string{ModeHybrid, ModeBM25, ModeVector},
+				"description": "hybrid (default) combines keyword and similarity ranking; bm25 only matches keywords, " +
+					"which suits exact names and codes; vector only ranks by similarity",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Only search this document or directory, such as guides/ or faq.md",
+			},
+		},
+		"required": []string{"query"},
+	}
+
+	schemaJSON, _ := json.Marshal(schema)
+
+	return "docsearch",
+		"Searches our own documentation and returns the most relevant passages with their source path and offsets. " +
+			"Prefer it over Wikipedia and web searches for questions about our products, processes and internal knowledge.",
+		schemaJSON,
+		func(ctx context.Context, input json.RawMessage) (json.RawMessage, error) {
+			var params DocSearchInput
+			if err := json.Unmarshal(input, &params); err != nil {
+				return nil, fmt.Errorf("invalid input: %w", err)
+			}
+			if strings.TrimSpace(params.Query) == "" {
+				return nil, fmt.Errorf("query is required")
+			}
+			if params.TopK == 0 {
+				params.TopK = config.TopK
+			}
+			if params.TopK < 0 || params.TopK > config.MaxTopK {
+				return nil, fmt.Errorf("topK must be between 1 and %d", config.MaxTopK)
+			}
+			switch params.Mode {
+			case "":
+				params.Mode = ModeHybrid
+			case ModeHybrid, ModeBM25, ModeVector:
+			default:
+				return nil, fmt.Errorf("unknown mode %q; use %s, %s or %s", params.Mode, ModeHybrid, ModeBM25, ModeVector)
+			}
+
+			index, err := file.get()
+			if err != nil {
+				return nil, err
+			}
+
+			var vector []float32
+			if params.Mode != ModeBM25 {
+				if index.Embedder != embedder.ID() {
+					return nil, fmt.Errorf("the index was built with the %s embedder but %s is configured; rebuild it, or use mode %s",
+						index.Embedder, embedder.ID(), ModeBM25)
+				}
+				vectors, err := embedder.Embed(ctx, []string{params.Query})
+				if err != nil {
+					return nil, err
+				}
+				vector = vectors[0]
+			}
+
+			var filter func(Chunk) bool
+			if prefix := strings.Trim(params.Path, "/"); prefix != "" {
+				filter = func(chunk Chunk) bool {
+					path := index.Documents[chunk.Document].Path
+					return path == prefix || strings.HasPrefix(path, prefix+"/")
+				}
+			}
+
+			output := DocSearchOutput{
+				Query:     params.Query,
+				Mode:      params.Mode,
+				Results:   []SearchResult{},
+				Documents: len(index.Documents),
+				Chunks:    len(index.Chunks),
+				Indexed:   index.Built,
+			}
+			for _, h := range index.search(params.Query, vector, params.Mode, params.TopK, config.VectorWeight, filter) {
+				chunk := index.Chunks[h.chunk]
+				doc := index.Documents[chunk.Document]
+				output.Results = append(output.Results, SearchResult{
+					Path:   doc.Path,
+					Title:  doc.Title,
+					Start:  chunk.Start,
+					End:    chunk.End,
+					Page:   chunk.Page,
+					Score:  round(h.score),
+					BM25:   round(h.bm25),
+					Vector: round(h.vector),
+					Text:   chunk.Text,
+				})
+			}
+			return json.Marshal(output)
+		}
+}
+
+// round keeps scores readable
+func round(score float64) float64 {
+	return math.Round(score*10000) / 10000
+}
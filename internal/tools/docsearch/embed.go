@@ -0,0 +1,184 @@
+package docsearch
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Embedder turns texts into vectors whose dot product measures how similar
+// the texts are. Implementations must return unit-length vectors and be safe
+// for concurrent use.
+type Embedder interface {
+	// ID names the model and its settings. Vectors are only compared with
+	// vectors from an embedder with the same ID.
+	ID() string
+	// Embed returns one vector per text
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// NewEmbedder creates the embedder a config selects
+func NewEmbedder(config EmbedderConfig) (Embedder, error) {
+	switch config.Provider {
+	case ProviderHash:
+		dimensions := config.Dimensions
+		if dimensions == 0 {
+			dimensions = defaultHashDimensions
+		}
+		return &hashEmbedder{dimensions: dimensions}, nil
+	case ProviderOpenAI:
+		key := config.apiKey()
+		if key == "" {
+			return nil, fmt.Errorf("the openai embedder needs an API key")
+		}
+		clientConfig := openai.DefaultConfig(key)
+		if config.BaseURL != "" {
+			clientConfig.BaseURL = config.BaseURL
+		}
+		return &openAIEmbedder{
+			client:     openai.NewClientWithConfig(clientConfig),
+			model:      config.Model,
+			dimensions: config.Dimensions,
+			batchSize:  config.BatchSize,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown embedder provider %q", config.Provider)
+	}
+}
+
+// hashEmbedder is the local fallback. Words and their character trigrams
+// are hashed into a fixed number of dimensions, so texts sharing words or
+// word parts get similar vectors. It captures spelling, not meaning, but is
+// deterministic and needs no model.
+type hashEmbedder struct {
+	dimensions int
+}
+
+func (e *hashEmbedder) ID() string {
+	return fmt.Sprintf("%s-%d", ProviderHash, e.dimensions)
+}
+
+func (e *hashEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = e.vector(text)
+	}
+	return vectors, nil
+}
+
+// vector adds a signed, sublinearly weighted count for each feature. Whole
+// words weigh more than trigrams.
+func (e *hashEmbedder) vector(text string) []float32 {
+	// Features are kept in order of appearance so the float sums, and so
+	// the vectors, are the same on every run
+	var features []string
+	weights := make(map[string]float64)
+	add := func(feature string, weight float64) {
+		if _, ok := weights[feature]; !ok {
+			features = append(features, feature)
+		}
+		weights[feature] += weight
+	}
+	for _, word := range tokenize(text) {
+		add("w:"+word, 1)
+		runes := []rune("^" + word + "$")
+		for i := 0; i+3 <= len(runes); i++ {
+			add("t:"+string(runes[i:i+3]), 0.5)
+		}
+	}
+
+	vector := make([]float64, e.dimensions)
+	for _, feature := range features {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		value := 1 + math.Log(weights[feature])
+		if weights[feature] < 1 {
+			value = weights[feature]
+		}
+		if sum>>63 == 1 {
+			value = -value
+		}
+		vector[sum%uint64(e.dimensions)] += value
+	}
+	return normalize(vector)
+}
+
+// openAIEmbedder calls an OpenAI-compatible embeddings API
+type openAIEmbedder struct {
+	client     *openai.Client
+	model      string
+	dimensions int
+	batchSize  int
+}
+
+func (e *openAIEmbedder) ID() string {
+	if e.dimensions > 0 {
+		return fmt.Sprintf("%s:%s-%d", ProviderOpenAI, e.model, e.dimensions)
+	}
+	return ProviderOpenAI + ":" + e.model
+}
+
+func (e *openAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += e.batchSize {
+		batch := texts[start:min(start+e.batchSize, len(texts))]
+		resp, err := e.client.CreateEmbeddings(ctx, openai.EmbeddingRequest{
+			Input:      batch,
+			Model:      openai.EmbeddingModel(e.model),
+			Dimensions: e.dimensions,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("embedding request failed: %w", err)
+		}
+		if len(resp.Data) != len(batch) {
+			return nil, fmt.Errorf("embedding API returned %d vectors for %d texts", len(resp.Data), len(batch))
+		}
+		result := make([][]float32, len(batch))
+		for _, data := range resp.Data {
+			if data.Index < 0 || data.Index >= len(batch) {
+				return nil, fmt.Errorf("embedding API returned an invalid index %d", data.Index)
+			}
+			vector := make([]float64, len(data.Embedding))
+			for i, value := range data.Embedding {
+				vector[i] = float64(value)
+			}
+			result[data.Index] = normalize(vector)
+		}
+		vectors = append(vectors, result...)
+	}
+	return vectors, nil
+}
+
+// normalize scales a vector to unit length
+func normalize(vector []float64) []float32 {
+	var norm float64
+	for _, value := range vector {
+		norm += value * value
+	}
+	norm = math.Sqrt(norm)
+	result := make([]float32, len(vector))
+	if norm == 0 {
+		return result
+	}
+	for i, value := range vector {
+		result[i] = float32(value / norm)
+	}
+	return result
+}
+
+// dot returns the dot product of two vectors, which is their cosine
+// similarity when both have unit length
+func dot(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
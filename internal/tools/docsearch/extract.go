@@ -0,0 +1,126 @@
+package docsearch
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	httptool "github.com/go-tools-agent/internal/tools/http"
+	"github.com/ledongthuc/pdf"
+)
+
+// extractors convert a file to plain text, by extension
+var extractors = map[string]func(content []byte) (*document, error){
+	".md":       textDocument,
+	".markdown": textDocument,
+	".txt":      textDocument,
+	".text":     textDocument,
+	".html":     htmlDocument,
+	".htm":      htmlDocument,
+	".pdf":      pdfDocument,
+}
+
+// supported reports whether a file can be indexed
+func supported(path string) bool {
+	_, ok := extractors[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// document is the text extracted from a file. Offsets in results refer to
+// this text, which for Markdown and text files is the file itself.
+type document struct {
+	title string
+	text  string
+	// pages holds the offset where each PDF page starts
+	pages []int
+}
+
+// page returns the 1-based page an offset is on, or 0 for documents
+// without pages
+func (d *document) page(offset int) int {
+	page := 0
+	for i, start := range d.pages {
+		if start > offset {
+			break
+		}
+		page = i + 1
+	}
+	return page
+}
+
+func textDocument(content []byte) (*document, error) {
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+	if bytes.IndexByte(content, 0) >= 0 {
+		return nil, fmt.Errorf("file is binary")
+	}
+	text := string(content)
+	if !utf8.ValidString(text) {
+		text = strings.ToValidUTF8(text, "�")
+	}
+	return &document{title: markdownTitle(text), text: text}, nil
+}
+
+// markdownTitle returns the first level-one heading
+func markdownTitle(text string) string {
+	for _, line := range strings.SplitN(text, "\n", 50) {
+		if strings.HasPrefix(line, "# ") {
+			return strings.TrimSpace(line[2:])
+		}
+	}
+	return ""
+}
+
+func htmlDocument(content []byte) (*document, error) {
+	title, text, err := httptool.HTMLText(content)
+	if err != nil {
+		return nil, err
+	}
+	return &document{title: title, text: text}, nil
+}
+
+// pdfDocument extracts the text of each page. Scanned pages without a text
+// layer come out empty.
+func pdfDocument(content []byte) (doc *document, err error) {
+	// The PDF reader panics on some malformed files
+	defer func() {
+		if r := recover(); r != nil {
+			doc, err = nil, fmt.Errorf("failed to read PDF: %v", r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	doc = &document{}
+	var text strings.Builder
+	fonts := make(map[string]*pdf.Font)
+	for i := 1; i <= reader.NumPage(); i++ {
+		if text.Len() > 0 {
+			text.WriteString("\n\n")
+		}
+		doc.pages = append(doc.pages, text.Len())
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		for _, name := range page.Fonts() {
+			if _, ok := fonts[name]; !ok {
+				font := page.Font(name)
+				fonts[name] = &font
+			}
+		}
+		pageText, err := page.GetPlainText(fonts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read page %d: %w", i, err)
+		}
+		text.WriteString(strings.ToValidUTF8(strings.TrimSpace(pageText), "�"))
+	}
+	doc.text = text.String()
+	if title := reader.Trailer().Key("Info").Key("Title"); !title.IsNull() {
+		doc.title = strings.TrimSpace(title.Text())
+	}
+	return doc, nil
+}
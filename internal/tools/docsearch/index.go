@@ -0,0 +1,375 @@
+package docsearch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// indexVersion changes whenever the index format does
+const indexVersion = 1
+
+// Index is the search index the indexer writes and the tool reads. It holds
+// every chunk's text and vector; BM25 statistics are computed on load.
+type Index struct {
+	Version int
+	// Embedder is the ID of the embedder that made the vectors
+	Embedder     string
+	ChunkSize    int
+	ChunkOverlap int
+	Built        time.Time
+	Documents    []Document
+	Chunks       []Chunk
+
+	terms     []map[string]int
+	lengths   []int
+	docFreq   map[string]int
+	avgLength float64
+}
+
+// Document is an indexed file
+type Document struct {
+	// Path is relative to the indexed directory, with forward slashes
+	Path    string
+	Title   string
+	Size    int64
+	ModTime time.Time
+	SHA256  string
+}
+
+// Chunk is a piece of a document's text
+type Chunk struct {
+	Document int
+	// Start and End are byte offsets in the document's extracted text
+	Start, End int
+	// Page is the 1-based PDF page the chunk starts on
+	Page   int
+	Text   string
+	Vector []float32
+}
+
+// BuildStats summarizes an indexing run
+type BuildStats struct {
+	// Documents is the number of indexed files, of which Unchanged were
+	// taken over from the previous index without extracting them again
+	Documents int
+	Unchanged int
+	Chunks    int
+	// Skipped lists the files that could not be indexed, with the reason
+	Skipped []string
+}
+
+// Build indexes the supported files in config.Dir. Files that are unchanged
+// since previous, which may be nil, keep their chunks and vectors, so only
+// new and modified files are embedded again.
+func Build(ctx context.Context, config Config, embedder Embedder, previous *Index) (*Index, BuildStats, error) {
+	index := &Index{
+		Version:      indexVersion,
+		Embedder:     embedder.ID(),
+		ChunkSize:    config.ChunkSize,
+		ChunkOverlap: config.ChunkOverlap,
+		Built:        time.Now().UTC(),
+	}
+	var stats BuildStats
+
+	// Chunks of the previous index, by document hash, when it was built
+	// the same way
+	reusable := make(map[string][]Chunk)
+	if previous != nil && previous.Embedder == index.Embedder &&
+		previous.ChunkSize == index.ChunkSize && previous.ChunkOverlap == index.ChunkOverlap {
+		for _, chunk := range previous.Chunks {
+			doc := previous.Documents[chunk.Document]
+			key := doc.Path + "\x00" + doc.SHA256
+			reusable[key] = append(reusable[key], chunk)
+		}
+	}
+	titles := make(map[string]string)
+	if previous != nil {
+		for _, doc := range previous.Documents {
+			titles[doc.Path+"\x00"+doc.SHA256] = doc.Title
+		}
+	}
+
+	skip := func(path string, err error) {
+		log.Printf("⚠️  Skipping %s: %v", path, err)
+		stats.Skipped = append(stats.Skipped, fmt.Sprintf("%s: %v", path, err))
+	}
+
+	err := filepath.WalkDir(config.Dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if strings.HasPrefix(entry.Name(), ".") && path != config.Dir {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || !supported(path) {
+			return nil
+		}
+		rel, err := filepath.Rel(config.Dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		info, err := os.Stat(path)
+		if err != nil {
+			skip(rel, err)
+			return nil
+		}
+		if info.Size() > config.MaxFileBytes {
+			skip(rel, fmt.Errorf("larger than %d bytes", config.MaxFileBytes))
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			skip(rel, err)
+			return nil
+		}
+		sum := sha256.Sum256(content)
+		doc := Document{Path: rel, Size: info.Size(), ModTime: info.ModTime().UTC(), SHA256: hex.EncodeToString(sum[:])}
+		key := doc.Path + "\x00" + doc.SHA256
+
+		chunks, ok := reusable[key]
+		if ok {
+			doc.Title = titles[key]
+			stats.Unchanged++
+		} else {
+			doc.Title, chunks, err = chunkDocument(ctx, config, embedder, path, content)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				skip(rel, err)
+				return nil
+			}
+		}
+
+		for _, chunk := range chunks {
+			chunk.Document = len(index.Documents)
+			index.Chunks = append(index.Chunks, chunk)
+		}
+		index.Documents = append(index.Documents, doc)
+		return nil
+	})
+	if err != nil {
+		return nil, stats, err
+	}
+
+	stats.Documents = len(index.Documents)
+	stats.Chunks = len(index.Chunks)
+	index.prepare()
+	return index, stats, nil
+}
+
+// chunkDocument extracts, splits and embeds one file
+func chunkDocument(ctx context.Context, config Config, embedder Embedder, path string, content []byte) (string, []Chunk, error) {
+	doc, err := extractors[strings.ToLower(filepath.Ext(path))](content)
+	if err != nil {
+		return "", nil, err
+	}
+	spans := split(doc.text, config.ChunkSize, config.ChunkOverlap)
+	if len(spans) == 0 {
+		return doc.title, nil, nil
+	}
+
+	chunks := make([]Chunk, len(spans))
+	texts := make([]string, len(spans))
+	for i, s := range spans {
+		texts[i] = doc.text[s.start:s.end]
+		chunks[i] = Chunk{Start: s.start, End: s.end, Page: doc.page(s.start), Text: texts[i]}
+	}
+	vectors, err := embedder.Embed(ctx, texts)
+	if err != nil {
+		return "", nil, err
+	}
+	for i := range chunks {
+		chunks[i].Vector = vectors[i]
+	}
+	return doc.title, chunks, nil
+}
+
+// Save writes the index to path, replacing it atomically so the tool never
+// reads a partial index
+func (ix *Index) Save(path string) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create index dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".index-*")
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(tmp).Encode(ix); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// LoadIndex reads an index written by Save
+func LoadIndex(path string) (*Index, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var index Index
+	if err := gob.NewDecoder(file).Decode(&index); err != nil {
+		return nil, fmt.Errorf("failed to read index %s: %w", path, err)
+	}
+	if index.Version != indexVersion {
+		return nil, fmt.Errorf("index %s has version %d, expected %d; rebuild it", path, index.Version, indexVersion)
+	}
+	index.prepare()
+	return &index, nil
+}
+
+// prepare computes the BM25 term statistics
+func (ix *Index) prepare() {
+	ix.terms = make([]map[string]int, len(ix.Chunks))
+	ix.lengths = make([]int, len(ix.Chunks))
+	ix.docFreq = make(map[string]int)
+	total := 0
+	for i, chunk := range ix.Chunks {
+		terms := make(map[string]int)
+		tokens := tokenize(chunk.Text)
+		for _, token := range tokens {
+			terms[token]++
+		}
+		for term := range terms {
+			ix.docFreq[term]++
+		}
+		ix.terms[i] = terms
+		ix.lengths[i] = len(tokens)
+		total += len(tokens)
+	}
+	if len(ix.Chunks) > 0 {
+		ix.avgLength = float64(total) / float64(len(ix.Chunks))
+	}
+}
+
+// Ranking modes
+const (
+	ModeHybrid = "hybrid"
+	ModeBM25   = "bm25"
+	ModeVector = "vector"
+)
+
+// BM25 parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// hit is a ranked chunk
+type hit struct {
+	chunk  int
+	score  float64
+	bm25   float64
+	vector float64
+}
+
+// search ranks the chunks accepted by filter for a query and returns the
+// best k. In hybrid mode the BM25 scores are divided by the best one and
+// the vector similarities scaled to the range 0 to 1, and the two are
+// mixed by vectorWeight.
+func (ix *Index) search(query string, vector []float32, mode string, k int, vectorWeight float64, filter func(Chunk) bool) []hit {
+	var queryTerms []string
+	seen := make(map[string]bool)
+	for _, term := range tokenize(query) {
+		if !seen[term] {
+			seen[term] = true
+			queryTerms = append(queryTerms, term)
+		}
+	}
+
+	hits := make([]hit, 0, len(ix.Chunks))
+	maxBM25, minVector, maxVector := 0.0, math.Inf(1), math.Inf(-1)
+	n := float64(len(ix.Chunks))
+	for i, chunk := range ix.Chunks {
+		if filter != nil && !filter(chunk) {
+			continue
+		}
+		h := hit{chunk: i}
+		if mode != ModeVector {
+			for _, term := range queryTerms {
+				tf := float64(ix.terms[i][term])
+				if tf == 0 {
+					continue
+				}
+				df := float64(ix.docFreq[term])
+				idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+				norm := bm25K1 * (1 - bm25B + bm25B*float64(ix.lengths[i])/ix.avgLength)
+				h.bm25 += idf * tf * (bm25K1 + 1) / (tf + norm)
+			}
+			maxBM25 = math.Max(maxBM25, h.bm25)
+		}
+		if mode != ModeBM25 {
+			h.vector = dot(vector, chunk.Vector)
+			minVector = math.Min(minVector, h.vector)
+			maxVector = math.Max(maxVector, h.vector)
+		}
+		hits = append(hits, h)
+	}
+
+	for i := range hits {
+		h := &hits[i]
+		switch mode {
+		case ModeBM25:
+			h.score = h.bm25
+		case ModeVector:
+			h.score = h.vector
+		default:
+			var bm25, vec float64
+			if maxBM25 > 0 {
+				bm25 = h.bm25 / maxBM25
+			}
+			if maxVector > minVector {
+				vec = (h.vector - minVector) / (maxVector - minVector)
+			} else {
+				vec = math.Max(h.vector, 0)
+			}
+			h.score = vectorWeight*vec + (1-vectorWeight)*bm25
+		}
+	}
+
+	// Chunks without a matching term or any similarity are not results
+	matched := hits[:0]
+	for _, h := range hits {
+		if h.score > 0 {
+			matched = append(matched, h)
+		}
+	}
+	hits = matched
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].score > hits[j].score })
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits
+}
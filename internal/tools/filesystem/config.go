@@ -0,0 +1,38 @@
+package filesystem
+
+import "fmt"
+
+// Config holds the settings for the filesystem tool
+type Config struct {
+	// Root is the workspace directory every path is confined to; the tool
+	// is disabled when it is empty
+	Root string `json:"root"`
+	// ReadOnly allows only list, read and search
+	ReadOnly bool `json:"readOnly"`
+	// MaxFileBytes caps the size of files that are read, searched or
+	// written
+	MaxFileBytes int `json:"maxFileBytes"`
+	// MaxReadBytes caps the content returned by a single read; longer
+	// ranges are paginated by line
+	MaxReadBytes int `json:"maxReadBytes"`
+	// MaxResults caps the entries of a listing and the matches of a search
+	MaxResults int `json:"maxResults"`
+}
+
+// DefaultConfig returns the default filesystem settings, with the tool
+// disabled
+func DefaultConfig() Config {
+	return Config{
+		MaxFileBytes: 1 << 20,
+		MaxReadBytes: 64 << 10,
+		MaxResults:   500,
+	}
+}
+
+// Validate checks the limits
+func (c Config) Validate() error {
+	if c.MaxFileBytes <= 0 || c.MaxReadBytes <= 0 || c.MaxResults <= 0 {
+		return fmt.Errorf("maxFileBytes, maxReadBytes and maxResults must be positive")
+	}
+	return nil
+}
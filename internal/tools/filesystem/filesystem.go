@@ -0,0 +1,222 @@
+package filesystem
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// FilesystemInput represents the input schema for the filesystem tool
+type FilesystemInput struct {
+	// Operation is one of list, read, write, append, search, move or delete
+	Operation string `json:"operation"`
+	// Path is relative to the workspace root; / is the root itself
+	Path string `json:"path"`
+	// Content is the text to write or append
+	Content string `json:"content,omitempty"`
+	// StartLine and EndLine select a range of lines to read, 1-based and
+	// inclusive
+	StartLine int `json:"startLine,omitempty"`
+	EndLine   int `json:"endLine,omitempty"`
+	// Recursive lists a whole tree, or deletes a non-empty directory
+	Recursive bool `json:"recursive,omitempty"`
+	// Glob selects the files to search, such as *.md or src/**/*.go
+	Glob string `json:"glob,omitempty"`
+	// Regex is the pattern to search file contents for
+	Regex string `json:"regex,omitempty"`
+	// Destination is the new path for move
+	Destination string `json:"destination,omitempty"`
+	// Overwrite lets move replace an existing file
+	Overwrite bool `json:"overwrite,omitempty"`
+}
+
+// Operations of the filesystem tool
+const (
+	OpList   = "list"
+	OpRead   = "read"
+	OpWrite  = "write"
+	OpAppend = "append"
+	OpSearch = "search"
+	OpMove   = "move"
+	OpDelete = "delete"
+)
+
+// FilesystemOutput represents the output schema for the filesystem tool
+type FilesystemOutput struct {
+	Operation string `json:"operation"`
+	Path      string `json:"path"`
+	// Entries lists a directory
+	Entries []Entry `json:"entries,omitempty"`
+	// Content holds the lines StartLine to EndLine of the file read.
+	// NextLine is set when the range was cut short by the size limit.
+	Content    string `json:"content,omitempty"`
+	StartLine  int    `json:"startLine,omitempty"`
+	EndLine    int    `json:"endLine,omitempty"`
+	TotalLines int    `json:"totalLines,omitempty"`
+	NextLine   int    `json:"nextLine,omitempty"`
+	// Matches lists the files, or lines, a search found
+	Matches []Match `json:"matches,omitempty"`
+	// Truncated is set when entries or matches were cut at the limit
+	Truncated bool `json:"truncated,omitempty"`
+	// Change records what a write, append, move or delete did
+	Change *Change `json:"change,omitempty"`
+}
+
+// Entry is a file or directory in a listing
+type Entry struct {
+	Path string `json:"path"`
+	// Type is file, dir or symlink. Symbolic links are listed but not
+	// followed.
+	Type    string `json:"type"`
+	Size    int64  `json:"size"`
+	ModTime string `json:"modTime"`
+}
+
+// Match is a file whose path matched the glob, with the matching line when
+// a regex was given
+type Match struct {
+	Path string `json:"path"`
+	Line int    `json:"line,omitempty"`
+	Text string `json:"text,omitempty"`
+}
+
+// Change records a modification of the workspace. It is part of the tool
+// output, so every write is kept in the run's steps.
+type Change struct {
+	Path        string `json:"path"`
+	Destination string `json:"destination,omitempty"`
+	// Created is set when the file did not exist before
+	Created bool `json:"created,omitempty"`
+	// Bytes and SHA256 describe the file afterwards
+	Bytes  int64  `json:"bytes"`
+	SHA256 string `json:"sha256,omitempty"`
+	// PreviousBytes and PreviousSHA256 describe the file it replaced
+	PreviousBytes  int64  `json:"previousBytes,omitempty"`
+	PreviousSHA256 string `json:"previousSha256,omitempty"`
+	// RemovedFiles counts the files deleted with a directory
+	RemovedFiles int `json:"removedFiles,omitempty"`
+}
+
+// NewFilesystemToolWithConfig creates a filesystem tool confined to
+// config.Root
+func NewFilesystemToolWithConfig(config Config) (string, string, json.RawMessage, func(context.Context, json.RawMessage) (json.RawMessage, error)) {
+	allowed := []string{OpList, OpRead, OpSearch}
+	description := "Lists, reads and searches files in the agent's workspace directory. "
+	if !config.ReadOnly {
+		allowed = append(allowed, OpWrite, OpAppend, OpMove, OpDelete)
+		description = "Lists, reads, searches, writes, moves and deletes files in the agent's workspace directory. "
+	}
+	description += "Paths are relative to the workspace root, and files are read as text by line range"
+
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"operation": map[string]interface{}{
+				"type":        "string",
+				"enum":        allowed,
+				"description": "The operation to perform",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "File or directory path relative to the workspace root; / or empty is the root",
+			},
+			"startLine": map[string]interface{}{
+				"type":        "integer",
+				"description": "read: first line to return, starting at 1",
+			},
+			"endLine": map[string]interface{}{
+				"type":        "integer",
+				"description": "read: last line to return; defaults to the end of the file",
+			},
+			"recursive": map[string]interface{}{
+				"type":        "boolean",
+				"description": "list: include subdirectories",
+			},
+			"glob": map[string]interface{}{
+				"type":        "string",
+				"description": "search: files to consider, such as *.md or docs/**/*.txt; a pattern without / matches file names at any depth",
+			},
+			"regex": map[string]interface{}{
+				"type":        "string",
+				"description": "search: regular expression to find in file contents; without it, search lists the files matching glob",
+			},
+		},
+		"required": []string{"operation"},
+	}
+	if !config.ReadOnly {
+		properties := schema["properties"].(map[string]interface{})
+		properties["recursive"] = map[string]interface{}{
+			"type":        "boolean",
+			"description": "list: include subdirectories; delete: delete a directory with its contents",
+		}
+		properties["content"] = map[string]interface{}{
+			"type":        "string",
+			"description": "write and append: text to write; directories are created as needed",
+		}
+		properties["destination"] = map[string]interface{}{
+			"type":        "string",
+			"description": "move: the new path",
+		}
+		properties["overwrite"] = map[string]interface{}{
+			"type":        "boolean",
+			"description": "move: replace an existing file at destination",
+		}
+	}
+
+	schemaJSON, _ := json.Marshal(schema)
+
+	return "filesystem",
+		description,
+		schemaJSON,
+		func(ctx context.Context, input json.RawMessage) (json.RawMessage, error) {
+			var params FilesystemInput
+			if err := json.Unmarshal(input, &params); err != nil {
+				return nil, fmt.Errorf("invalid input: %w", err)
+			}
+			params.Operation = strings.ToLower(strings.TrimSpace(params.Operation))
+
+			w, err := openWorkspace(config.Root)
+			if err != nil {
+				return nil, err
+			}
+			ops := &operations{config: config, workspace: w}
+
+			var output *FilesystemOutput
+			switch params.Operation {
+			case OpList:
+				output, err = ops.list(params)
+			case OpRead:
+				output, err = ops.read(params)
+			case OpSearch:
+				output, err = ops.search(ctx, params)
+			case OpWrite, OpAppend, OpMove, OpDelete:
+				if config.ReadOnly {
+					return nil, fmt.Errorf("the workspace is read-only; %s is not allowed", params.Operation)
+				}
+				switch params.Operation {
+				case OpWrite:
+					output, err = ops.write(params, false)
+				case OpAppend:
+					output, err = ops.write(params, true)
+				case OpMove:
+					output, err = ops.move(params)
+				default:
+					output, err = ops.remove(params)
+				}
+				if err == nil {
+					log.Printf("📝 Filesystem %s: %s", params.Operation, output.Path)
+				}
+			case "":
+				return nil, fmt.Errorf("operation is required")
+			default:
+				return nil, fmt.Errorf("unknown operation %q", params.Operation)
+			}
+			if err != nil {
+				return nil, err
+			}
+			output.Operation = params.Operation
+			return json.Marshal(output)
+		}
+}
@@ -0,0 +1,425 @@
+package filesystem
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxMatchText caps the line text returned for a search match
+const maxMatchText = 300
+
+// operations carries out a single call within the workspace
+type operations struct {
+	config    Config
+	workspace *workspace
+}
+
+// list returns the entries of a directory, or of the whole tree below it
+func (o *operations) list(params FilesystemInput) (*FilesystemOutput, error) {
+	full, err := o.workspace.resolve(params.Path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(full)
+	if err != nil {
+		return nil, notFound(params.Path, err)
+	}
+	output := &FilesystemOutput{Path: o.workspace.relative(full), Entries: []Entry{}}
+	if !info.IsDir() {
+		output.Entries = append(output.Entries, o.entry(full, info))
+		return output, nil
+	}
+
+	add := func(path string, d fs.DirEntry) error {
+		if len(output.Entries) >= o.config.MaxResults {
+			output.Truncated = true
+			return filepath.SkipAll
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		output.Entries = append(output.Entries, o.entry(path, info))
+		return nil
+	}
+	if params.Recursive {
+		err = filepath.WalkDir(full, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || path == full {
+				return nil
+			}
+			return add(path, d)
+		})
+	} else {
+		var entries []fs.DirEntry
+		if entries, err = os.ReadDir(full); err == nil {
+			for _, d := range entries {
+				if add(filepath.Join(full, d.Name()), d) != nil {
+					break
+				}
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+func (o *operations) entry(path string, info fs.FileInfo) Entry {
+	entry := Entry{
+		Path:    o.workspace.relative(path),
+		Type:    "file",
+		Size:    info.Size(),
+		ModTime: info.ModTime().UTC().Format(time.RFC3339),
+	}
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		entry.Type = "symlink"
+	case info.IsDir():
+		entry.Type = "dir"
+		entry.Size = 0
+	}
+	return entry
+}
+
+// read returns a range of lines of a text file. Ranges longer than
+// MaxReadBytes end early, with NextLine set to continue from.
+func (o *operations) read(params FilesystemInput) (*FilesystemOutput, error) {
+	full, err := o.workspace.resolve(params.Path)
+	if err != nil {
+		return nil, err
+	}
+	content, err := o.readText(params.Path, full)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	output := &FilesystemOutput{Path: o.workspace.relative(full), TotalLines: len(lines)}
+	if len(lines) == 0 {
+		return output, nil
+	}
+
+	start, end := params.StartLine, params.EndLine
+	if start <= 0 {
+		start = 1
+	}
+	if end <= 0 || end > len(lines) {
+		end = len(lines)
+	}
+	if start > len(lines) {
+		return nil, fmt.Errorf("startLine %d is past the end of the file, which has %d lines", start, len(lines))
+	}
+	if end < start {
+		return nil, fmt.Errorf("endLine %d is before startLine %d", end, start)
+	}
+
+	var b strings.Builder
+	last := start - 1
+	for i := start; i <= end; i++ {
+		line := lines[i-1]
+		if b.Len()+len(line) > o.config.MaxReadBytes {
+			if i == start {
+				// A single line longer than the limit is cut
+				b.WriteString(truncateUTF8(line, o.config.MaxReadBytes))
+				last = i
+			}
+			break
+		}
+		b.WriteString(line)
+		last = i
+	}
+	output.Content = b.String()
+	output.StartLine = start
+	output.EndLine = last
+	if last < end {
+		output.NextLine = last + 1
+	}
+	return output, nil
+}
+
+// readText reads a regular file within the size limit and rejects binary
+// content
+func (o *operations) readText(name, full string) ([]byte, error) {
+	info, err := os.Stat(full)
+	if err != nil {
+		return nil, notFound(name, err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory; use list", name)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", name)
+	}
+	if info.Size() > int64(o.config.MaxFileBytes) {
+		return nil, fmt.Errorf("%s is %d bytes, limit is %d bytes", name, info.Size(), o.config.MaxFileBytes)
+	}
+	content, err := os.ReadFile(full)
+	if err != nil {
+		return nil, err
+	}
+	if isBinary(content) {
+		return nil, fmt.Errorf("%s is a binary file", name)
+	}
+	return content, nil
+}
+
+// search finds files below path whose workspace path matches the glob and,
+// when a regex is given, the lines matching it
+func (o *operations) search(ctx context.Context, params FilesystemInput) (*FilesystemOutput, error) {
+	if params.Glob == "" && params.Regex == "" {
+		return nil, fmt.Errorf("search needs a glob, a regex or both")
+	}
+	var pattern *regexp.Regexp
+	if params.Regex != "" {
+		var err error
+		if pattern, err = regexp.Compile(params.Regex); err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+	}
+	full, err := o.workspace.resolve(params.Path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(full); err != nil {
+		return nil, notFound(params.Path, err)
+	}
+
+	output := &FilesystemOutput{Path: o.workspace.relative(full), Matches: []Match{}}
+	err = filepath.WalkDir(full, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		rel := o.workspace.relative(path)
+		if params.Glob != "" && !matchGlob(params.Glob, rel) {
+			return nil
+		}
+		if pattern == nil {
+			if len(output.Matches) >= o.config.MaxResults {
+				output.Truncated = true
+				return filepath.SkipAll
+			}
+			output.Matches = append(output.Matches, Match{Path: rel})
+			return nil
+		}
+
+		// Files that cannot be searched as text are skipped
+		content, err := o.readText(rel, path)
+		if err != nil {
+			return nil
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		scanner.Buffer(make([]byte, 64<<10), o.config.MaxFileBytes+1)
+		for number := 1; scanner.Scan(); number++ {
+			line := scanner.Text()
+			if !pattern.MatchString(line) {
+				continue
+			}
+			if len(output.Matches) >= o.config.MaxResults {
+				output.Truncated = true
+				return filepath.SkipAll
+			}
+			output.Matches = append(output.Matches, Match{Path: rel, Line: number, Text: truncateUTF8(line, maxMatchText)})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// write replaces a file's content, or appends to it, creating the file and
+// its directories as needed
+func (o *operations) write(params FilesystemInput, appending bool) (*FilesystemOutput, error) {
+	full, err := o.workspace.resolve(params.Path)
+	if err != nil {
+		return nil, err
+	}
+	if full == o.workspace.root {
+		return nil, fmt.Errorf("path is required")
+	}
+
+	change := &Change{Path: o.workspace.relative(full)}
+	var previous []byte
+	info, err := os.Stat(full)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		change.Created = true
+	case err != nil:
+		return nil, err
+	case !info.Mode().IsRegular():
+		return nil, fmt.Errorf("%s is not a regular file", params.Path)
+	default:
+		// Files over the limit are replaced without being read
+		change.PreviousBytes = info.Size()
+		if info.Size() <= int64(o.config.MaxFileBytes) {
+			if previous, err = os.ReadFile(full); err != nil {
+				return nil, err
+			}
+			change.PreviousSHA256 = digest(previous)
+		}
+	}
+
+	content := []byte(params.Content)
+	size := int64(len(content))
+	if appending {
+		size += change.PreviousBytes
+		content = append(previous, content...)
+	}
+	if size > int64(o.config.MaxFileBytes) {
+		return nil, fmt.Errorf("%s would be %d bytes, limit is %d bytes", params.Path, size, o.config.MaxFileBytes)
+	}
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory for %s: %w", params.Path, err)
+	}
+	if err := os.WriteFile(full, content, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", params.Path, err)
+	}
+
+	change.Bytes = int64(len(content))
+	change.SHA256 = digest(content)
+	return &FilesystemOutput{Path: change.Path, Change: change}, nil
+}
+
+// move renames a file or directory, creating the destination's directories
+// as needed
+func (o *operations) move(params FilesystemInput) (*FilesystemOutput, error) {
+	if params.Destination == "" {
+		return nil, fmt.Errorf("destination is required")
+	}
+	source, err := o.workspace.resolveEntry(params.Path)
+	if err != nil {
+		return nil, err
+	}
+	destination, err := o.workspace.resolveEntry(params.Destination)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Lstat(source)
+	if err != nil {
+		return nil, notFound(params.Path, err)
+	}
+	if info.IsDir() && strings.HasPrefix(destination+string(filepath.Separator), source+string(filepath.Separator)) {
+		return nil, fmt.Errorf("cannot move %s into itself", params.Path)
+	}
+
+	change := &Change{Path: o.workspace.relative(source), Destination: o.workspace.relative(destination)}
+	if existing, err := os.Lstat(destination); err == nil {
+		if !params.Overwrite {
+			return nil, fmt.Errorf("%s already exists; set overwrite to replace it", params.Destination)
+		}
+		if existing.IsDir() {
+			return nil, fmt.Errorf("%s is a directory and cannot be overwritten", params.Destination)
+		}
+		change.PreviousBytes = existing.Size()
+	}
+	if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory for %s: %w", params.Destination, err)
+	}
+	if err := os.Rename(source, destination); err != nil {
+		return nil, fmt.Errorf("failed to move %s: %w", params.Path, err)
+	}
+	if !info.IsDir() {
+		change.Bytes = info.Size()
+	}
+	return &FilesystemOutput{Path: change.Path, Change: change}, nil
+}
+
+// remove deletes a file, a symbolic link or, when recursive is set, a
+// directory with its contents
+func (o *operations) remove(params FilesystemInput) (*FilesystemOutput, error) {
+	full, err := o.workspace.resolveEntry(params.Path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Lstat(full)
+	if err != nil {
+		return nil, notFound(params.Path, err)
+	}
+
+	change := &Change{Path: o.workspace.relative(full)}
+	switch {
+	case info.IsDir():
+		if entries, _ := os.ReadDir(full); len(entries) > 0 && !params.Recursive {
+			return nil, fmt.Errorf("%s is not empty; set recursive to delete it with its contents", params.Path)
+		}
+		filepath.WalkDir(full, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				change.RemovedFiles++
+			}
+			return nil
+		})
+		err = os.RemoveAll(full)
+	case info.Mode().IsRegular():
+		if content, readErr := os.ReadFile(full); readErr == nil {
+			change.PreviousBytes = int64(len(content))
+			change.PreviousSHA256 = digest(content)
+		}
+		err = os.Remove(full)
+	default:
+		err = os.Remove(full)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete %s: %w", params.Path, err)
+	}
+	return &FilesystemOutput{Path: change.Path, Change: change}, nil
+}
+
+func notFound(name string, err error) error {
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s does not exist", name)
+	}
+	return err
+}
+
+func digest(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// isBinary treats content with NUL bytes or invalid UTF-8 near the start as
+// binary
+func isBinary(content []byte) bool {
+	head := content
+	if len(head) > 8000 {
+		head = head[:8000]
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return true
+	}
+	// The sample may end inside a multi-byte character
+	for i := 0; i < utf8.UTFMax && len(head) > 0 && !utf8.Valid(head); i++ {
+		head = head[:len(head)-1]
+	}
+	return !utf8.Valid(head)
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a character
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,130 @@
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// workspace resolves tool paths inside the root directory
+type workspace struct {
+	// root is the absolute root with symlinks resolved
+	root string
+}
+
+// openWorkspace resolves the configured root. It is resolved on every call,
+// so the directory may be created or replaced while the agent runs.
+func openWorkspace(root string) (*workspace, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	real, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return nil, fmt.Errorf("workspace %s is not available: %w", root, err)
+	}
+	if info, err := os.Stat(real); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("workspace %s is not a directory", root)
+	}
+	return &workspace{root: real}, nil
+}
+
+// resolve maps a workspace path, such as docs/notes.md or /docs/notes.md,
+// to a host path. Paths that leave the root, directly or through a
+// symbolic link, are rejected. The path itself need not exist.
+func (w *workspace) resolve(name string) (string, error) {
+	rel := filepath.Clean(filepath.FromSlash(strings.TrimLeft(name, "/")))
+	if rel != "." && !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("path %s is outside the workspace", name)
+	}
+	full := filepath.Join(w.root, rel)
+
+	// Resolve the longest existing prefix; the rest will be created by us
+	existing, rest := full, ""
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = filepath.Dir(existing)
+	}
+	real, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return "", fmt.Errorf("path %s: %w", name, err)
+	}
+	if !w.contains(real) {
+		return "", fmt.Errorf("path %s leads outside the workspace through a symbolic link", name)
+	}
+	return filepath.Join(real, rest), nil
+}
+
+// resolveEntry is resolve for operations on a directory entry itself, such
+// as move and delete: only the parent is resolved, so a symbolic link is
+// removed or renamed rather than its target. The root itself is rejected.
+func (w *workspace) resolveEntry(name string) (string, error) {
+	rel := filepath.Clean(filepath.FromSlash(strings.TrimLeft(name, "/")))
+	if rel == "." {
+		return "", fmt.Errorf("the workspace root cannot be moved or deleted")
+	}
+	if !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("path %s is outside the workspace", name)
+	}
+	parent, err := w.resolve(filepath.ToSlash(filepath.Dir(rel)))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(parent, filepath.Base(rel)), nil
+}
+
+// contains reports whether a resolved host path is inside the root
+func (w *workspace) contains(real string) bool {
+	rel, err := filepath.Rel(w.root, real)
+	return err == nil && (rel == "." || filepath.IsLocal(rel))
+}
+
+// relative returns the workspace path of a host path, with forward slashes
+func (w *workspace) relative(full string) string {
+	rel, err := filepath.Rel(w.root, full)
+	if err != nil || rel == "." {
+		return "/"
+	}
+	return filepath.ToSlash(rel)
+}
+
+// matchGlob matches a workspace path against a glob. A * matches within a
+// path segment and ** across segments. Patterns without a slash match the
+// base name, so *.go finds Go files at any depth.
+func matchGlob(pattern, name string) bool {
+	pattern = strings.TrimLeft(pattern, "/")
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,126 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cacheableStatus are the status codes that are cacheable by default
+// (RFC 9110 section 15.1)
+var cacheableStatus = map[int]bool{
+	200: true, 203: true, 204: true, 206: true, 300: true, 301: true, 308: true,
+	404: true, 405: true, 410: true, 414: true, 501: true,
+}
+
+// CacheTTL decides whether a result of the HTTP tool with the default
+// policy may be cached; see CacheTTLWithConfig
+func CacheTTL(input, output json.RawMessage) (time.Duration, bool) {
+	return CacheTTLWithConfig(DefaultConfig())(input, output)
+}
+
+// CacheTTLWithConfig returns a function that decides whether a result of
+// the HTTP tool built from config may be cached and for how long,
+// following the response's cache headers the way a shared cache would.
+// Only GET and HEAD requests are cached, and only when the response gives
+// an explicit lifetime. Requests that carry credentials, whether in their
+// headers or from a matching credential profile, are never cached.
+func CacheTTLWithConfig(config Config) func(input, output json.RawMessage) (time.Duration, bool) {
+	return func(input, output json.RawMessage) (time.Duration, bool) {
+		var params HTTPRequestInput
+		if err := json.Unmarshal(input, &params); err != nil {
+			return 0, false
+		}
+		if method := strings.ToUpper(params.Method); method != http.MethodGet && method != http.MethodHead {
+			return 0, false
+		}
+		for key := range params.Headers {
+			if strings.EqualFold(key, "Authorization") || strings.EqualFold(key, "Cookie") {
+				return 0, false
+			}
+		}
+		u, err := url.Parse(params.URL)
+		if err != nil {
+			return 0, false
+		}
+		for _, profile := range config.Credentials {
+			if _, ok := profile.matches(u); ok {
+				return 0, false
+			}
+		}
+		return responseTTL(output)
+	}
+}
+
+// responseTTL returns how long a response may be cached, from its status
+// and cache headers
+func responseTTL(output json.RawMessage) (time.Duration, bool) {
+	var result HTTPRequestOutput
+	if err := json.Unmarshal(output, &result); err != nil || !cacheableStatus[result.StatusCode] {
+		return 0, false
+	}
+	header := make(http.Header, len(result.Headers))
+	for key, value := range result.Headers {
+		header.Set(key, value)
+	}
+	if header.Get("Set-Cookie") != "" || header.Get("Vary") == "*" {
+		return 0, false
+	}
+
+	directives := cacheControl(header.Get("Cache-Control"))
+	if _, ok := directives["no-store"]; ok {
+		return 0, false
+	}
+	if _, ok := directives["no-cache"]; ok {
+		return 0, false
+	}
+	if _, ok := directives["private"]; ok {
+		return 0, false
+	}
+
+	age := 0
+	if value, err := strconv.Atoi(header.Get("Age")); err == nil && value > 0 {
+		age = value
+	}
+	for _, name := range []string{"s-maxage", "max-age"} {
+		if value, ok := directives[name]; ok {
+			seconds, err := strconv.Atoi(value)
+			if err != nil || seconds-age <= 0 {
+				return 0, false
+			}
+			return time.Duration(seconds-age) * time.Second, true
+		}
+	}
+
+	if expires := header.Get("Expires"); expires != "" {
+		expiresAt, err := http.ParseTime(expires)
+		if err != nil {
+			// Invalid dates, such as "0", mean already expired
+			return 0, false
+		}
+		now := time.Now()
+		if date, err := http.ParseTime(header.Get("Date")); err == nil {
+			now = date
+		}
+		if ttl := expiresAt.Sub(now); ttl > 0 {
+			return ttl, true
+		}
+	}
+	return 0, false
+}
+
+// cacheControl parses a Cache-Control header into lower-case directives
+// and their values
+func cacheControl(value string) map[string]string {
+	directives := make(map[string]string)
+	for _, part := range strings.Split(value, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			directives[name] = strings.Trim(strings.TrimSpace(arg), `"`)
+		}
+	}
+	return directives
+}
@@ -0,0 +1,66 @@
+package http
+
+import "fmt"
+
+// Config holds the settings for the HTTP request tool
+type Config struct {
+	// Policy decides which URLs may be requested
+	Policy Policy `json:"policy"`
+	// TimeoutSeconds bounds each request, including redirects
+	TimeoutSeconds int `json:"timeoutSeconds"`
+	// MaxResponseBytes caps how much of a response body is downloaded
+	MaxResponseBytes int64 `json:"maxResponseBytes"`
+	// MaxBodyBytes caps the body text returned to the model after
+	// extraction
+	MaxBodyBytes int `json:"maxBodyBytes"`
+	// Credentials are attached to requests by URL prefix
+	Credentials []CredentialProfile `json:"credentials"`
+	// Retry controls retries of failed idempotent requests
+	Retry RetryConfig `json:"retry"`
+	// RateLimit limits request rates per host
+	RateLimit RateLimitConfig `json:"rateLimit"`
+	// MaxIdleConnsPerHost and IdleConnTimeoutSeconds size the connection
+	// pool shared by all calls of the tool
+	MaxIdleConnsPerHost    int `json:"maxIdleConnsPerHost"`
+	IdleConnTimeoutSeconds int `json:"idleConnTimeoutSeconds"`
+}
+
+// DefaultConfig returns the default HTTP tool settings
+func DefaultConfig() Config {
+	return Config{
+		Policy:         DefaultPolicy(),
+		TimeoutSeconds: 30,
+
+		MaxResponseBytes: 5 << 20,
+		MaxBodyBytes:     32 << 10,
+
+		Retry:                  DefaultRetryConfig(),
+		MaxIdleConnsPerHost:    10,
+		IdleConnTimeoutSeconds: 90,
+	}
+}
+
+// Validate checks the policy and limits
+func (c Config) Validate() error {
+	if c.TimeoutSeconds <= 0 {
+		return fmt.Errorf("timeoutSeconds must be positive")
+	}
+	if c.MaxResponseBytes <= 0 || c.MaxBodyBytes <= 0 {
+		return fmt.Errorf("maxResponseBytes and maxBodyBytes must be positive")
+	}
+	if c.MaxIdleConnsPerHost < 0 || c.IdleConnTimeoutSeconds < 0 {
+		return fmt.Errorf("maxIdleConnsPerHost and idleConnTimeoutSeconds must not be negative")
+	}
+	if err := c.Retry.Validate(); err != nil {
+		return err
+	}
+	if err := c.RateLimit.Validate(); err != nil {
+		return err
+	}
+	for _, profile := range c.Credentials {
+		if err := profile.Validate(); err != nil {
+			return err
+		}
+	}
+	return c.Policy.Validate()
+}
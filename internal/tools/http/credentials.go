@@ -0,0 +1,226 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Credential types
+const (
+	CredentialBearer = "bearer"
+	CredentialBasic  = "basic"
+	CredentialAPIKey = "apiKey"
+	CredentialHMAC   = "hmac"
+)
+
+// CredentialProfile holds credentials that are attached to requests whose
+// URL starts with one of the profile's prefixes. Secret fields may refer to
+// environment variables as ${NAME} so they stay out of the config file.
+type CredentialProfile struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	URLPrefixes []string `json:"urlPrefixes"`
+
+	// Token is sent as "Authorization: Bearer <token>"
+	Token string `json:"token,omitempty"`
+	// Username and Password are sent as HTTP basic auth
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// Header and Value send an API key in a custom header
+	Header string `json:"header,omitempty"`
+	Value  string `json:"value,omitempty"`
+	// Secret signs requests with HMAC-SHA256 over the method, request URI,
+	// timestamp and body hash, each on its own line. The hex signature is
+	// sent in SignatureHeader and the Unix timestamp in TimestampHeader;
+	// KeyID, if set, is sent in KeyIDHeader.
+	Secret          string `json:"secret,omitempty"`
+	KeyID           string `json:"keyId,omitempty"`
+	SignatureHeader string `json:"signatureHeader,omitempty"`
+	TimestampHeader string `json:"timestampHeader,omitempty"`
+	KeyIDHeader     string `json:"keyIdHeader,omitempty"`
+}
+
+// Validate checks that the profile has the fields its type needs
+func (p CredentialProfile) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("credential profile needs a name")
+	}
+	if len(p.URLPrefixes) == 0 {
+		return fmt.Errorf("credential profile %s needs at least one urlPrefix", p.Name)
+	}
+	for _, prefix := range p.URLPrefixes {
+		u, err := url.Parse(prefix)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("credential profile %s has an invalid urlPrefix %q: it needs a scheme and host", p.Name, prefix)
+		}
+	}
+
+	var missing bool
+	switch p.Type {
+	case CredentialBearer:
+		missing = p.Token == ""
+	case CredentialBasic:
+		missing = p.Username == ""
+	case CredentialAPIKey:
+		missing = p.Header == "" || p.Value == ""
+	case CredentialHMAC:
+		missing = p.Secret == ""
+	default:
+		return fmt.Errorf("credential profile %s has unknown type %q", p.Name, p.Type)
+	}
+	if missing {
+		return fmt.Errorf("credential profile %s is missing fields for type %s", p.Name, p.Type)
+	}
+	return nil
+}
+
+// resolve expands ${NAME} references in the secret fields
+func (p CredentialProfile) resolve() CredentialProfile {
+	p.Token = os.ExpandEnv(p.Token)
+	p.Username = os.ExpandEnv(p.Username)
+	p.Password = os.ExpandEnv(p.Password)
+	p.Value = os.ExpandEnv(p.Value)
+	p.Secret = os.ExpandEnv(p.Secret)
+	p.KeyID = os.ExpandEnv(p.KeyID)
+	if p.SignatureHeader == "" {
+		p.SignatureHeader = "X-Signature"
+	}
+	if p.TimestampHeader == "" {
+		p.TimestampHeader = "X-Timestamp"
+	}
+	if p.KeyIDHeader == "" {
+		p.KeyIDHeader = "X-Key-Id"
+	}
+	return p
+}
+
+// secrets returns the values that must never be shown to the model,
+// including encoded forms that may be echoed back by a server
+func (p CredentialProfile) secrets() []string {
+	switch p.Type {
+	case CredentialBearer:
+		return []string{p.Token}
+	case CredentialBasic:
+		return []string{p.Password, base64.StdEncoding.EncodeToString([]byte(p.Username + ":" + p.Password))}
+	case CredentialAPIKey:
+		return []string{p.Value}
+	case CredentialHMAC:
+		return []string{p.Secret}
+	}
+	return nil
+}
+
+// Secrets returns every credential secret in the config, with environment
+// references expanded, so callers can redact them from logs and steps
+func Secrets(config Config) []string {
+	var secrets []string
+	for _, profile := range config.Credentials {
+		secrets = append(secrets, profile.resolve().secrets()...)
+	}
+	return secrets
+}
+
+// matches reports whether u falls under one of the profile's prefixes.
+// Scheme and host must match exactly, and the path must be the prefix
+// path or lie below it, so /api does not match /api-admin.
+func (p CredentialProfile) matches(u *url.URL) (int, bool) {
+	best, found := 0, false
+	for _, raw := range p.URLPrefixes {
+		prefix, err := url.Parse(raw)
+		if err != nil {
+			continue
+		}
+		if !strings.EqualFold(prefix.Scheme, u.Scheme) || !strings.EqualFold(prefix.Host, u.Host) {
+			continue
+		}
+		if !pathUnder(u.EscapedPath(), prefix.EscapedPath()) {
+			continue
+		}
+		if length := len(raw); !found || length > best {
+			best, found = length, true
+		}
+	}
+	return best, found
+}
+
+// pathUnder reports whether path is prefix or a path below it
+func pathUnder(path, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || strings.HasSuffix(prefix, "/") || path[len(prefix)] == '/'
+}
+
+// apply adds the credentials to req
+func (p CredentialProfile) apply(req *http.Request) error {
+	switch p.Type {
+	case CredentialBearer:
+		req.Header.Set("Authorization", "Bearer "+p.Token)
+	case CredentialBasic:
+		req.SetBasicAuth(p.Username, p.Password)
+	case CredentialAPIKey:
+		req.Header.Set(p.Header, p.Value)
+	case CredentialHMAC:
+		var body []byte
+		if req.GetBody != nil {
+			reader, err := req.GetBody()
+			if err != nil {
+				return fmt.Errorf("failed to read body for signing: %w", err)
+			}
+			body, err = io.ReadAll(reader)
+			reader.Close()
+			if err != nil {
+				return fmt.Errorf("failed to read body for signing: %w", err)
+			}
+		}
+		bodyHash := sha256.Sum256(body)
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		payload := strings.Join([]string{req.Method, req.URL.RequestURI(), timestamp, hex.EncodeToString(bodyHash[:])}, "\n")
+
+		mac := hmac.New(sha256.New, []byte(p.Secret))
+		mac.Write([]byte(payload))
+		req.Header.Set(p.TimestampHeader, timestamp)
+		req.Header.Set(p.SignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+		if p.KeyID != "" {
+			req.Header.Set(p.KeyIDHeader, p.KeyID)
+		}
+	}
+	return nil
+}
+
+// credentialTransport attaches the best matching profile to every request,
+// including each redirect, so credentials never follow a redirect to a URL
+// outside their prefixes
+type credentialTransport struct {
+	base     http.RoundTripper
+	profiles []CredentialProfile
+}
+
+func (t *credentialTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var profile *CredentialProfile
+	best := 0
+	for i := range t.profiles {
+		if length, ok := t.profiles[i].matches(req.URL); ok && (profile == nil || length > best) {
+			profile, best = &t.profiles[i], length
+		}
+	}
+	if profile == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	if err := profile.apply(req); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,404 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// contentKind is how a response body is presented to the model
+type contentKind int
+
+const (
+	kindText contentKind = iota
+	kindJSON
+	kindHTML
+	kindBinary
+)
+
+// classify decides how to present a body from its declared content type,
+// falling back to sniffing when the server did not send one
+func classify(contentType string, body []byte) (contentKind, string) {
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	}
+
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return kindJSON, mediaType
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		return kindHTML, mediaType
+	case strings.HasPrefix(mediaType, "text/"),
+		mediaType == "application/xml" || strings.HasSuffix(mediaType, "+xml"),
+		mediaType == "application/javascript", mediaType == "application/x-www-form-urlencoded",
+		mediaType == "application/yaml", mediaType == "application/x-yaml":
+		return kindText, mediaType
+	}
+
+	// Unknown types are shown as text when they look like text
+	if sniffed := http.DetectContentType(body); strings.HasPrefix(sniffed, "text/") {
+		return kindText, mediaType
+	}
+	return kindBinary, mediaType
+}
+
+// decodeText converts a body to UTF-8 using the charset from the content
+// type, a byte order mark or an HTML meta tag
+func decodeText(contentType string, body []byte) string {
+	enc, name, _ := charset.DetermineEncoding(body, contentType)
+	if name != "utf-8" {
+		if decoded, err := enc.NewDecoder().Bytes(body); err == nil {
+			return string(decoded)
+		}
+	}
+	body = bytes.TrimPrefix(body, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(body) {
+		return strings.ToValidUTF8(string(body), "�")
+	}
+	return string(body)
+}
+
+// prettyJSON indents valid JSON and returns anything else unchanged
+func prettyJSON(text string) string {
+	var out bytes.Buffer
+	if err := json.Indent(&out, []byte(text), "", "  "); err != nil {
+		return text
+	}
+	return out.String()
+}
+
+// htmlText renders HTML as readable text. Links are numbered inline and
+// listed with their absolute URLs at the end.
+func htmlText(nodes []*html.Node, base *url.URL) string {
+	r := &htmlRenderer{base: base}
+	for _, n := range nodes {
+		r.render(n)
+	}
+
+	text := r.text()
+	if len(r.links) > 0 {
+		var b strings.Builder
+		b.WriteString(text)
+		b.WriteString("\n\nLinks:")
+		for i, link := range r.links {
+			fmt.Fprintf(&b, "\n[%d] %s", i+1, link)
+		}
+		text = b.String()
+	}
+	return text
+}
+
+// HTMLText renders an HTML document as readable text, the way HTML
+// responses are shown to the model, and returns it with the title
+func HTMLText(content []byte) (title, text string, err error) {
+	doc, err := html.Parse(strings.NewReader(decodeText("text/html", content)))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	return pageTitle(doc), htmlText([]*html.Node{doc}, nil), nil
+}
+
+// pageTitle returns the document title, if any
+func pageTitle(doc *html.Node) string {
+	if n := findFirst(doc, func(n *html.Node) bool { return n.Type == html.ElementNode && n.Data == "title" }); n != nil {
+		return strings.Join(strings.Fields(textContent(n)), " ")
+	}
+	return ""
+}
+
+var (
+	skippedElements = map[string]bool{
+		"head": true, "script": true, "style": true, "noscript": true,
+		"template": true, "svg": true, "iframe": true, "button": true,
+	}
+	blockElements = map[string]bool{
+		"address": true, "article": true, "aside": true, "blockquote": true, "dd": true,
+		"details": true, "div": true, "dl": true, "dt": true, "fieldset": true,
+		"figcaption": true, "figure": true, "footer": true, "form": true, "h1": true,
+		"h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "header": true,
+		"hr": true, "li": true, "main": true, "nav": true, "ol": true, "p": true,
+		"pre": true, "section": true, "summary": true, "table": true, "tr": true, "ul": true,
+	}
+	tightElements = map[string]bool{"li": true, "tr": true, "dt": true, "dd": true}
+)
+
+type htmlRenderer struct {
+	base  *url.URL
+	out   strings.Builder
+	links []string
+	pre   int
+}
+
+func (r *htmlRenderer) render(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		r.writeText(n.Data)
+		return
+	case html.ElementNode:
+	case html.DocumentNode:
+		r.renderChildren(n)
+		return
+	default:
+		return
+	}
+
+	if skippedElements[n.Data] {
+		return
+	}
+
+	switch n.Data {
+	case "br":
+		r.breakLines(1)
+		return
+	case "img":
+		if alt := strings.TrimSpace(attr(n, "alt")); alt != "" {
+			r.writeText("[image: " + alt + "]")
+		}
+		return
+	case "td", "th":
+		r.writeText(" ")
+		r.renderChildren(n)
+		r.writeText(" |")
+		return
+	case "a":
+		r.renderChildren(n)
+		if link := r.resolve(attr(n, "href")); link != "" {
+			r.links = append(r.links, link)
+			fmt.Fprintf(&r.out, " [%d]", len(r.links))
+		}
+		return
+	}
+
+	if blockElements[n.Data] {
+		// List items and table rows sit on consecutive lines; other blocks
+		// are separated by a blank line
+		lines := 2
+		if tightElements[n.Data] {
+			lines = 1
+		}
+		r.breakLines(lines)
+		if n.Data == "li" {
+			r.out.WriteString("- ")
+		}
+		if n.Data == "pre" {
+			r.pre++
+			defer func() { r.pre-- }()
+		}
+		r.renderChildren(n)
+		r.breakLines(lines)
+		return
+	}
+	r.renderChildren(n)
+}
+
+func (r *htmlRenderer) renderChildren(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		r.render(c)
+	}
+}
+
+// breakLines ends the current line and makes sure at least n line breaks
+// separate it from what follows
+func (r *htmlRenderer) breakLines(n int) {
+	out := strings.TrimRight(r.out.String(), " \t")
+	have := len(out) - len(strings.TrimRight(out, "\n"))
+	for ; have < n; have++ {
+		r.out.WriteString("\n")
+	}
+}
+
+// writeText collapses whitespace outside of <pre>
+func (r *htmlRenderer) writeText(s string) {
+	if r.pre > 0 {
+		r.out.WriteString(s)
+		return
+	}
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		if s != "" {
+			r.out.WriteString(" ")
+		}
+		return
+	}
+	if s[0] == ' ' || s[0] == '\n' || s[0] == '\t' || s[0] == '\r' {
+		r.out.WriteString(" ")
+	}
+	r.out.WriteString(strings.Join(fields, " "))
+	if last := s[len(s)-1]; last == ' ' || last == '\n' || last == '\t' || last == '\r' {
+		r.out.WriteString(" ")
+	}
+}
+
+// text trims each line and collapses runs of blank lines
+func (r *htmlRenderer) text() string {
+	var lines []string
+	blank := true
+	for _, line := range strings.Split(r.out.String(), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line == "|" {
+			if !blank {
+				lines = append(lines, "")
+			}
+			blank = true
+			continue
+		}
+		lines = append(lines, line)
+		blank = false
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// resolve makes a link absolute, dropping in-page and script links
+func (r *htmlRenderer) resolve(href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return ""
+	}
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if r.base != nil {
+		u = r.base.ResolveReference(u)
+	}
+	return u.String()
+}
+
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
+
+func findFirst(n *html.Node, match func(*html.Node) bool) *html.Node {
+	if match(n) {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findFirst(c, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// truncateText cuts text to at most limit bytes on a rune boundary and
+// notes how much was dropped
+func truncateText(text string, limit int) (string, bool) {
+	if len(text) <= limit {
+		return text, false
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s\n... [truncated %d bytes]", text[:cut], len(text)-cut), true
+}
+
+// extracted is a response body prepared for the model
+type extracted struct {
+	text      string
+	mediaType string
+	title     string
+	binary    bool
+}
+
+// extractBody decodes a body and converts it to the most readable form for
+// its content type, optionally narrowing it with a JSONPath expression or
+// a CSS selector
+func extractBody(contentType string, base *url.URL, body []byte, size int64, jsonPath, sel string) (*extracted, error) {
+	kind, mediaType := classify(contentType, body)
+	result := &extracted{mediaType: mediaType}
+
+	if jsonPath != "" && kind != kindJSON {
+		// Servers often send JSON as text/plain; accept anything that parses
+		if kind == kindBinary || !json.Valid(bytes.TrimSpace(body)) {
+			return nil, fmt.Errorf("jsonPath requires a JSON response, got %s", mediaType)
+		}
+		kind = kindJSON
+	}
+	if sel != "" && kind != kindHTML {
+		return nil, fmt.Errorf("select requires an HTML response, got %s", mediaType)
+	}
+
+	switch kind {
+	case kindBinary:
+		result.binary = true
+		result.text = fmt.Sprintf("[binary content: %s, %d bytes]", mediaType, size)
+
+	case kindJSON:
+		text := decodeText(contentType, body)
+		if jsonPath == "" {
+			result.text = prettyJSON(text)
+			break
+		}
+		var doc interface{}
+		if err := json.Unmarshal([]byte(text), &doc); err != nil {
+			return nil, fmt.Errorf("response is not valid JSON: %w", err)
+		}
+		value, err := evalJSONPath(doc, jsonPath)
+		if err != nil {
+			return nil, err
+		}
+		pretty, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode jsonPath result: %w", err)
+		}
+		result.text = string(pretty)
+
+	case kindHTML:
+		doc, err := html.Parse(strings.NewReader(decodeText(contentType, body)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse HTML: %w", err)
+		}
+		result.title = pageTitle(doc)
+		if sel == "" {
+			result.text = htmlText([]*html.Node{doc}, base)
+			break
+		}
+		selector, err := parseSelector(sel)
+		if err != nil {
+			return nil, fmt.Errorf("invalid select: %w", err)
+		}
+		nodes := selector.selectNodes(doc)
+		if len(nodes) == 0 {
+			return nil, fmt.Errorf("select %q matched nothing", sel)
+		}
+		sections := make([]string, len(nodes))
+		for i, n := range nodes {
+			sections[i] = htmlText([]*html.Node{n}, base)
+		}
+		result.text = strings.Join(sections, "\n\n---\n\n")
+
+	default:
+		result.text = decodeText(contentType, body)
+	}
+
+	return result, nil
+}
@@ -3,8 +3,10 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"strings"
 	"time"
@@ -13,30 +15,110 @@ import (
 // HTTPRequestInput represents the input schema for the HTTP request tool
 type HTTPRequestInput struct {
 	URL     string            `json:"url"`
-	Method  string           `json:"method"`
+	Method  string            `json:"method"`
 	Headers map[string]string `json:"headers,omitempty"`
-	Body    string           `json:"body,omitempty"`
+	Body    string            `json:"body,omitempty"`
+	// JSONPath narrows a JSON response, e.g. $.items[*].name
+	JSONPath string `json:"jsonPath,omitempty"`
+	// Select narrows an HTML response with a CSS selector, e.g. main article
+	Select string `json:"select,omitempty"`
 }
 
 // HTTPRequestOutput represents the output schema for the HTTP request tool
 type HTTPRequestOutput struct {
 	StatusCode int               `json:"statusCode"`
 	Headers    map[string]string `json:"headers"`
-	Body       string           `json:"body"`
+	Body       string            `json:"body"`
+	// ContentType is the media type of the response, without parameters
+	ContentType string `json:"contentType,omitempty"`
+	// Title is the page title of HTML responses
+	Title string `json:"title,omitempty"`
+	// BodyBytes is the size of the response body as received
+	BodyBytes int64 `json:"bodyBytes"`
+	// Truncated is set when the body exceeded the download or output limit
+	Truncated bool `json:"truncated,omitempty"`
+	// Binary is set when the body is not text; Body then only summarizes it
+	Binary bool `json:"binary,omitempty"`
+	// Attempts is the number of times the request was sent, including
+	// retries
+	Attempts int `json:"attempts"`
 }
 
-// NewHTTPRequestTool creates a new HTTP request tool
+// PolicyErrorOutput is returned instead of a response when the policy denies
+// a request, so the model learns why and can try something else
+type PolicyErrorOutput struct {
+	URL         string `json:"url"`
+	PolicyError string `json:"policyError"`
+}
+
+// NewHTTPRequestTool creates a new HTTP request tool with the default policy
 func NewHTTPRequestTool() (string, string, json.RawMessage, func(context.Context, json.RawMessage) (json.RawMessage, error)) {
+	return NewHTTPRequestToolWithConfig(DefaultConfig())
+}
+
+// NewHTTPRequestToolWithConfig creates a new HTTP request tool that only
+// fetches URLs permitted by the configured policy
+func NewHTTPRequestToolWithConfig(config Config) (string, string, json.RawMessage, func(context.Context, json.RawMessage) (json.RawMessage, error)) {
+	policy := config.Policy
+
+	// Every connection, including those made while following redirects, is
+	// checked after DNS resolution. Proxies are not used since the policy
+	// would only see the proxy's address.
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: policy.dialControl,
+	}
+	// The transport, and with it the connection pool, is shared by every
+	// call of the tool
+	var transport http.RoundTripper = &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+		ForceAttemptHTTP2:   true,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: config.MaxIdleConnsPerHost,
+		IdleConnTimeout:     time.Duration(config.IdleConnTimeoutSeconds) * time.Second,
+	}
+	transport = &rateLimitTransport{base: transport, limiter: newRateLimiter(config.RateLimit)}
+
+	// Credentials are attached below the client so the model never sees
+	// them, and are matched again on every redirect
+	profiles := make([]CredentialProfile, len(config.Credentials))
+	var scopes []string
+	for i, profile := range config.Credentials {
+		profiles[i] = profile.resolve()
+		scopes = append(scopes, profile.URLPrefixes...)
+	}
+	if len(profiles) > 0 {
+		transport = &credentialTransport{base: transport, profiles: profiles}
+	}
+
+	// The timeout applies to each attempt; retries are bounded by
+	// Retry.MaxAttempts and the caller's context
+	client := &http.Client{
+		Timeout:       time.Duration(config.TimeoutSeconds) * time.Second,
+		Transport:     transport,
+		CheckRedirect: policy.checkRedirect,
+	}
+
+	description := "Makes HTTP requests to external services. Requests to private networks and hosts outside the configured policy are refused with a policyError"
+	if config.Retry.MaxAttempts > 1 {
+		description += ". Idempotent requests that fail with a network error, 429 or 5xx are retried automatically, so do not retry them yourself"
+	}
+	if len(scopes) > 0 {
+		description += ". Authentication is added automatically for URLs starting with " + strings.Join(scopes, ", ") +
+			"; do not add credentials for these yourself"
+	}
+
 	schema := map[string]interface{}{
 		"type": "object",
 		"properties": map[string]interface{}{
 			"url": map[string]interface{}{
-				"type": "string",
+				"type":        "string",
 				"description": "The URL to send the request to",
 			},
 			"method": map[string]interface{}{
-				"type": "string",
-				"enum": []string{"GET", "POST", "PUT", "DELETE", "PATCH", "HEAD", "OPTIONS"},
+				"type":        "string",
+				"enum":        []string{"GET", "POST", "PUT", "DELETE", "PATCH", "HEAD", "OPTIONS"},
 				"description": "The HTTP method to use",
 			},
 			"headers": map[string]interface{}{
@@ -47,9 +129,17 @@ func NewHTTPRequestTool() (string, string, json.RawMessage, func(context.Context
 				"description": "Optional headers to include in the request",
 			},
 			"body": map[string]interface{}{
-				"type": "string",
+				"type":        "string",
 				"description": "Optional body to include in the request",
 			},
+			"jsonPath": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional JSONPath to return only part of a JSON response, e.g. $.items[*].name, $.data[0] or $..id",
+			},
+			"select": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional CSS selector to return only matching parts of an HTML page, e.g. \"article\", \"#content p\" or \"table.infobox\"",
+			},
 		},
 		"required": []string{"url", "method"},
 	}
@@ -57,7 +147,7 @@ func NewHTTPRequestTool() (string, string, json.RawMessage, func(context.Context
 	schemaJSON, _ := json.Marshal(schema)
 
 	return "httpRequest",
-		"Makes HTTP requests to external services",
+		description,
 		schemaJSON,
 		func(ctx context.Context, input json.RawMessage) (json.RawMessage, error) {
 			var params HTTPRequestInput
@@ -65,34 +155,47 @@ func NewHTTPRequestTool() (string, string, json.RawMessage, func(context.Context
 				return nil, fmt.Errorf("invalid input: %w", err)
 			}
 
-			// Create HTTP client with timeout
-			client := &http.Client{
-				Timeout: 30 * time.Second,
-			}
-
 			// Create request
 			req, err := http.NewRequestWithContext(ctx, params.Method, params.URL, strings.NewReader(params.Body))
 			if err != nil {
 				return nil, fmt.Errorf("failed to create request: %w", err)
 			}
+			if err := policy.checkURL(req.URL); err != nil {
+				return policyErrorOutput(params.URL, err)
+			}
 
 			// Add headers
 			for key, value := range params.Headers {
 				req.Header.Add(key, value)
 			}
 
-			// Execute request
-			resp, err := client.Do(req)
+			// Execute request, retrying transient failures
+			resp, attempts, err := doWithRetries(ctx, client, req, config.Retry)
 			if err != nil {
+				if IsPolicyError(err) {
+					return policyErrorOutput(params.URL, err)
+				}
+				if attempts > 1 {
+					return nil, fmt.Errorf("request failed after %d attempts: %w", attempts, err)
+				}
 				return nil, fmt.Errorf("request failed: %w", err)
 			}
 			defer resp.Body.Close()
 
-			// Read response body
-			body, err := io.ReadAll(resp.Body)
+			// Read at most MaxResponseBytes; anything beyond is dropped
+			body, err := io.ReadAll(io.LimitReader(resp.Body, config.MaxResponseBytes+1))
 			if err != nil {
 				return nil, fmt.Errorf("failed to read response body: %w", err)
 			}
+			truncated := int64(len(body)) > config.MaxResponseBytes
+			size := int64(len(body))
+			if truncated {
+				body = body[:config.MaxResponseBytes]
+				size = config.MaxResponseBytes
+				if resp.ContentLength > size {
+					size = resp.ContentLength
+				}
+			}
 
 			// Convert response headers
 			headers := make(map[string]string)
@@ -104,7 +207,21 @@ func NewHTTPRequestTool() (string, string, json.RawMessage, func(context.Context
 			output := HTTPRequestOutput{
 				StatusCode: resp.StatusCode,
 				Headers:    headers,
-				Body:      string(body),
+				BodyBytes:  size,
+				Truncated:  truncated,
+				Attempts:   attempts,
+			}
+			if len(body) > 0 {
+				content, err := extractBody(resp.Header.Get("Content-Type"), resp.Request.URL, body, size, params.JSONPath, params.Select)
+				if err != nil {
+					return nil, err
+				}
+				text, cut := truncateText(content.text, config.MaxBodyBytes)
+				output.Body = text
+				output.Truncated = output.Truncated || cut
+				output.ContentType = content.mediaType
+				output.Title = content.title
+				output.Binary = content.binary
 			}
 
 			outputJSON, err := json.Marshal(output)
@@ -114,4 +231,13 @@ func NewHTTPRequestTool() (string, string, json.RawMessage, func(context.Context
 
 			return outputJSON, nil
 		}
-} 
\ No newline at end of file
+}
+
+// policyErrorOutput reports a denied request to the model
+func policyErrorOutput(url string, err error) (json.RawMessage, error) {
+	var policyErr *PolicyError
+	if !errors.As(err, &policyErr) {
+		return nil, err
+	}
+	return json.Marshal(PolicyErrorOutput{URL: url, PolicyError: policyErr.Error()})
+}
@@ -0,0 +1,229 @@
+package http
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonPathStep is one segment of a parsed JSONPath expression
+type jsonPathStep struct {
+	recursive bool // ..name
+	wildcard  bool // .* or [*]
+	name      string
+	index     *int
+	slice     *[2]*int // [start:end], either bound optional
+}
+
+// parseJSONPath parses the subset of JSONPath supported by the tool:
+// $.a.b, $['a'], $.a[0], $.a[-1], $.a[*], $.a[1:3], $..name and $..*
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "$")
+
+	var steps []jsonPathStep
+	for i := 0; i < len(path); {
+		switch {
+		case strings.HasPrefix(path[i:], ".."):
+			i += 2
+			name, n := readName(path[i:])
+			if name == "" {
+				return nil, fmt.Errorf("expected a name after '..' at offset %d", i)
+			}
+			steps = append(steps, jsonPathStep{recursive: true, wildcard: name == "*", name: name})
+			i += n
+
+		case path[i] == '.':
+			i++
+			name, n := readName(path[i:])
+			if name == "" {
+				return nil, fmt.Errorf("expected a name after '.' at offset %d", i)
+			}
+			steps = append(steps, jsonPathStep{wildcard: name == "*", name: name})
+			i += n
+
+		case path[i] == '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed '[' at offset %d", i)
+			}
+			step, err := parseBracket(path[i+1 : i+end])
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, step)
+			i += end + 1
+
+		default:
+			// Allow a leading bare name, e.g. "items[0].id"
+			if i != 0 {
+				return nil, fmt.Errorf("unexpected %q at offset %d", path[i], i)
+			}
+			name, n := readName(path)
+			steps = append(steps, jsonPathStep{wildcard: name == "*", name: name})
+			i += n
+		}
+	}
+	return steps, nil
+}
+
+func readName(s string) (string, int) {
+	n := 0
+	for n < len(s) && s[n] != '.' && s[n] != '[' {
+		n++
+	}
+	return s[:n], n
+}
+
+func parseBracket(inner string) (jsonPathStep, error) {
+	inner = strings.TrimSpace(inner)
+	switch {
+	case inner == "*":
+		return jsonPathStep{wildcard: true}, nil
+	case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+		return jsonPathStep{name: inner[1 : len(inner)-1]}, nil
+	case strings.Contains(inner, ":"):
+		parts := strings.SplitN(inner, ":", 2)
+		var bounds [2]*int
+		for i, part := range parts {
+			if part = strings.TrimSpace(part); part != "" {
+				n, err := strconv.Atoi(part)
+				if err != nil {
+					return jsonPathStep{}, fmt.Errorf("invalid slice bound %q", part)
+				}
+				bounds[i] = &n
+			}
+		}
+		return jsonPathStep{slice: &bounds}, nil
+	default:
+		n, err := strconv.Atoi(inner)
+		if err != nil {
+			return jsonPathStep{}, fmt.Errorf("invalid index %q", inner)
+		}
+		return jsonPathStep{index: &n}, nil
+	}
+}
+
+// evalJSONPath applies a JSONPath expression to a decoded JSON document.
+// Paths that can only match one value return it directly; paths with
+// wildcards, slices or recursive descent return a list of matches.
+func evalJSONPath(doc interface{}, path string) (interface{}, error) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jsonPath: %w", err)
+	}
+
+	nodes := []interface{}{doc}
+	multiple := false
+	for _, step := range steps {
+		if step.recursive || step.wildcard || step.slice != nil {
+			multiple = true
+		}
+		var next []interface{}
+		for _, node := range nodes {
+			if step.recursive {
+				for _, descendant := range descendants(node) {
+					next = append(next, applyStep(descendant, step)...)
+				}
+				continue
+			}
+			next = append(next, applyStep(node, step)...)
+		}
+		nodes = next
+	}
+
+	if multiple {
+		if nodes == nil {
+			nodes = []interface{}{}
+		}
+		return nodes, nil
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("jsonPath %s matched nothing", path)
+	}
+	return nodes[0], nil
+}
+
+// applyStep returns the children of node selected by a non-recursive step
+func applyStep(node interface{}, step jsonPathStep) []interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		if step.wildcard {
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			values := make([]interface{}, len(keys))
+			for i, key := range keys {
+				values[i] = v[key]
+			}
+			return values
+		}
+		if step.index == nil && step.slice == nil {
+			if value, ok := v[step.name]; ok {
+				return []interface{}{value}
+			}
+		}
+	case []interface{}:
+		switch {
+		case step.wildcard:
+			return v
+		case step.index != nil:
+			i := *step.index
+			if i < 0 {
+				i += len(v)
+			}
+			if i >= 0 && i < len(v) {
+				return []interface{}{v[i]}
+			}
+		case step.slice != nil:
+			start, end := 0, len(v)
+			if b := step.slice[0]; b != nil {
+				start = clampIndex(*b, len(v))
+			}
+			if b := step.slice[1]; b != nil {
+				end = clampIndex(*b, len(v))
+			}
+			if start < end {
+				return v[start:end]
+			}
+		}
+	}
+	return nil
+}
+
+func clampIndex(i, n int) int {
+	if i < 0 {
+		i += n
+	}
+	if i < 0 {
+		return 0
+	}
+	if i > n {
+		return n
+	}
+	return i
+}
+
+// descendants returns node and everything nested below it, depth first
+func descendants(node interface{}) []interface{} {
+	all := []interface{}{node}
+	switch v := node.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			all = append(all, descendants(v[key])...)
+		}
+	case []interface{}:
+		for _, item := range v {
+			all = append(all, descendants(item)...)
+		}
+	}
+	return all
+}
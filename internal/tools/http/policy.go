@@ -0,0 +1,187 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+// Policy restricts which URLs the HTTP tool may fetch
+type Policy struct {
+	// AllowedSchemes lists the URL schemes that may be requested
+	AllowedSchemes []string `json:"allowedSchemes"`
+	// AllowedHosts, when not empty, is the only set of hosts that may be
+	// requested. An entry matches the host itself and all its subdomains.
+	AllowedHosts []string `json:"allowedHosts"`
+	// DeniedHosts are never requested, even if they are also allowed
+	DeniedHosts []string `json:"deniedHosts"`
+	// BlockPrivateNetworks refuses connections to loopback, private,
+	// link-local and other non-public addresses. It is checked against the
+	// address actually dialled, after DNS resolution and on every redirect.
+	BlockPrivateNetworks bool `json:"blockPrivateNetworks"`
+	// AllowedNetworks are CIDR ranges exempt from BlockPrivateNetworks,
+	// e.g. an internal API the agent is meant to reach
+	AllowedNetworks []string `json:"allowedNetworks"`
+	// MaxRedirects is the number of redirects followed before giving up
+	MaxRedirects int `json:"maxRedirects"`
+}
+
+// DefaultPolicy allows public http and https URLs only
+func DefaultPolicy() Policy {
+	return Policy{
+		AllowedSchemes:       []string{"http", "https"},
+		BlockPrivateNetworks: true,
+		MaxRedirects:         5,
+	}
+}
+
+// PolicyError reports a request denied by the policy
+type PolicyError struct {
+	Reason string
+}
+
+func (e *PolicyError) Error() string {
+	return "request blocked by policy: " + e.Reason
+}
+
+// IsPolicyError reports whether err, or any error it wraps, is a PolicyError
+func IsPolicyError(err error) bool {
+	var policyErr *PolicyError
+	return errors.As(err, &policyErr)
+}
+
+// nonPublicNetworks are special-purpose ranges not covered by the net.IP
+// helpers used in blockedIP
+var nonPublicNetworks = mustParseCIDRs(
+	"0.0.0.0/8",     // "this" network
+	"100.64.0.0/10", // carrier-grade NAT
+	"192.0.0.0/24",  // IETF protocol assignments
+	"198.18.0.0/15", // benchmarking
+	"240.0.0.0/4",   // reserved
+	"64:ff9b::/96",  // NAT64, embeds IPv4 addresses
+	"2002::/16",     // 6to4, embeds IPv4 addresses
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
+
+// Validate checks that every network in the policy parses
+func (p Policy) Validate() error {
+	if p.MaxRedirects < 0 {
+		return fmt.Errorf("maxRedirects must not be negative")
+	}
+	for _, cidr := range p.AllowedNetworks {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("invalid allowed network %q: %w", cidr, err)
+		}
+	}
+	return nil
+}
+
+// checkURL applies the scheme and host rules to a URL
+func (p Policy) checkURL(u *url.URL) error {
+	scheme := strings.ToLower(u.Scheme)
+	if !containsFold(p.AllowedSchemes, scheme) {
+		return &PolicyError{Reason: fmt.Sprintf("scheme %q is not allowed", u.Scheme)}
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return &PolicyError{Reason: "URL has no host"}
+	}
+	for _, denied := range p.DeniedHosts {
+		if matchesHost(host, denied) {
+			return &PolicyError{Reason: fmt.Sprintf("host %s is denied", host)}
+		}
+	}
+	if len(p.AllowedHosts) > 0 {
+		for _, allowed := range p.AllowedHosts {
+			if matchesHost(host, allowed) {
+				return nil
+			}
+		}
+		return &PolicyError{Reason: fmt.Sprintf("host %s is not in the allowlist", host)}
+	}
+	return nil
+}
+
+// checkIP applies the private network rule to a resolved address
+func (p Policy) checkIP(ip net.IP) error {
+	if !p.BlockPrivateNetworks {
+		return nil
+	}
+	for _, cidr := range p.AllowedNetworks {
+		if _, network, err := net.ParseCIDR(cidr); err == nil && network.Contains(ip) {
+			return nil
+		}
+	}
+	if blockedIP(ip) {
+		return &PolicyError{Reason: fmt.Sprintf("address %s is not a public address", ip)}
+	}
+	return nil
+}
+
+func blockedIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// dialControl runs after DNS resolution, right before each connection is
+// made, so it sees the real address even when DNS answers change between
+// lookups
+func (p Policy) dialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return &PolicyError{Reason: fmt.Sprintf("invalid address %s", address)}
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return &PolicyError{Reason: fmt.Sprintf("unresolved address %s", address)}
+	}
+	return p.checkIP(ip)
+}
+
+// checkRedirect applies the URL rules and redirect limit to each redirect
+func (p Policy) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) > p.MaxRedirects {
+		return &PolicyError{Reason: fmt.Sprintf("stopped after %d redirects", p.MaxRedirects)}
+	}
+	return p.checkURL(req.URL)
+}
+
+// matchesHost reports whether host is pattern or one of its subdomains
+func matchesHost(host, pattern string) bool {
+	pattern = strings.TrimSuffix(strings.TrimPrefix(strings.ToLower(pattern), "*."), ".")
+	return host == pattern || strings.HasSuffix(host, "."+pattern)
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit is a token bucket: RequestsPerSecond tokens are added per
+// second up to Burst, and each request takes one
+type RateLimit struct {
+	RequestsPerSecond float64 `json:"requestsPerSecond"`
+	Burst             int     `json:"burst"`
+}
+
+// RateLimitConfig limits how fast the tool may call each host
+type RateLimitConfig struct {
+	// Default applies to every host without its own entry; a zero
+	// RequestsPerSecond means unlimited
+	Default RateLimit `json:"default"`
+	// Hosts overrides the default per host. An entry also matches the
+	// host's subdomains; the most specific entry wins.
+	Hosts map[string]RateLimit `json:"hosts"`
+}
+
+// Validate checks the rates
+func (c RateLimitConfig) Validate() error {
+	limits := map[string]RateLimit{"default": c.Default}
+	for host, limit := range c.Hosts {
+		limits[host] = limit
+	}
+	for name, limit := range limits {
+		if limit.RequestsPerSecond < 0 || limit.Burst < 0 {
+			return fmt.Errorf("rate limit for %s must not be negative", name)
+		}
+	}
+	return nil
+}
+
+// limitFor returns the limit for host and the key its bucket is kept
+// under, so that subdomains sharing an entry share a bucket
+func (c RateLimitConfig) limitFor(host string) (RateLimit, string) {
+	host = strings.ToLower(host)
+	best, key := c.Default, host
+	length := -1
+	for pattern, limit := range c.Hosts {
+		if matchesHost(host, pattern) && len(pattern) > length {
+			best, key, length = limit, strings.ToLower(pattern), len(pattern)
+		}
+	}
+	return best, key
+}
+
+// bucket is the token bucket for one host
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter hands out tokens per host
+type rateLimiter struct {
+	config  RateLimitConfig
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+func newRateLimiter(config RateLimitConfig) *rateLimiter {
+	return &rateLimiter{config: config, buckets: make(map[string]*bucket)}
+}
+
+// reserve takes a token for host and returns how long the caller must wait
+// before using it. Tokens may go negative so waiting callers queue up in
+// order.
+func (l *rateLimiter) reserve(host string, now time.Time) time.Duration {
+	limit, key := l.config.limitFor(host)
+	if limit.RequestsPerSecond <= 0 {
+		return 0
+	}
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * limit.RequestsPerSecond
+		if b.tokens > burst {
+			b.tokens = burst
+		}
+		b.last = now
+	}
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / limit.RequestsPerSecond * float64(time.Second))
+}
+
+// rateLimitTransport waits for a token before every request, including
+// retries and redirects
+type rateLimitTransport struct {
+	base    http.RoundTripper
+	limiter *rateLimiter
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if wait := t.limiter.reserve(req.URL.Hostname(), time.Now()); wait > 0 {
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,198 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryConfig controls how failed requests are retried
+type RetryConfig struct {
+	// MaxAttempts is the total number of attempts, including the first;
+	// 1 disables retries
+	MaxAttempts int `json:"maxAttempts"`
+	// InitialBackoffMs is the delay before the first retry. It doubles on
+	// every further retry up to MaxBackoffMs, with random jitter.
+	InitialBackoffMs int `json:"initialBackoffMs"`
+	MaxBackoffMs     int `json:"maxBackoffMs"`
+	// MaxRetryAfterSeconds is the longest Retry-After the tool waits for;
+	// a response asking for longer is returned as is
+	MaxRetryAfterSeconds int `json:"maxRetryAfterSeconds"`
+	// StatusCodes are the response codes that are retried
+	StatusCodes []int `json:"statusCodes"`
+}
+
+// DefaultRetryConfig retries rate limited and server error responses twice
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxAttempts:          3,
+		InitialBackoffMs:     500,
+		MaxBackoffMs:         10000,
+		MaxRetryAfterSeconds: 30,
+		StatusCodes:          []int{http.StatusTooManyRequests, 500, 502, 503, 504},
+	}
+}
+
+// Validate checks the retry limits
+func (c RetryConfig) Validate() error {
+	if c.MaxAttempts < 1 {
+		return fmt.Errorf("retry.maxAttempts must be at least 1")
+	}
+	if c.InitialBackoffMs < 0 || c.MaxBackoffMs < c.InitialBackoffMs {
+		return fmt.Errorf("retry.initialBackoffMs must not be negative or above retry.maxBackoffMs")
+	}
+	if c.MaxRetryAfterSeconds < 0 {
+		return fmt.Errorf("retry.maxRetryAfterSeconds must not be negative")
+	}
+	return nil
+}
+
+// retryable reports whether a request may be sent again. Only idempotent
+// methods are retried, unless the caller supplied an Idempotency-Key.
+func retryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// retryStatus reports whether a response status is worth retrying
+func (c RetryConfig) retryStatus(code int) bool {
+	for _, status := range c.StatusCodes {
+		if status == code {
+			return true
+		}
+	}
+	return false
+}
+
+// retryError reports whether a transport error is worth retrying: timeouts,
+// temporary DNS failures and connections reset by the server. Policy
+// denials, unknown hosts, TLS failures, cancellation and the caller's
+// deadline are final.
+func retryError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || IsPolicyError(err) {
+		return false
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return !dnsErr.IsNotFound && (dnsErr.IsTemporary || dnsErr.IsTimeout)
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	// Connections dropped by the server surface as resets or EOF errors
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// backoff returns the delay before retry number n (1 for the first retry):
+// exponential, capped, with the upper half randomized so that clients do
+// not retry in lockstep
+func (c RetryConfig) backoff(n int) time.Duration {
+	delay := time.Duration(c.InitialBackoffMs) * time.Millisecond
+	max := time.Duration(c.MaxBackoffMs) * time.Millisecond
+	for i := 1; i < n && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP
+// date
+func retryAfter(header string, now time.Time) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if when, err := http.ParseTime(header); err == nil {
+		if delay := when.Sub(now); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// doWithRetries sends req, retrying transport errors and retryable status
+// codes with backoff. It returns the final response or error together with
+// the number of attempts made.
+func doWithRetries(ctx context.Context, client *http.Client, req *http.Request, config RetryConfig) (*http.Response, int, error) {
+	canRetry := retryable(req)
+	for attempt := 1; ; attempt++ {
+		try := req.Clone(ctx)
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, attempt, fmt.Errorf("failed to rewind request body: %w", err)
+			}
+			try.Body = body
+		}
+
+		resp, err := client.Do(try)
+		last := !canRetry || attempt >= config.MaxAttempts
+
+		var wait time.Duration
+		switch {
+		case err != nil:
+			if last || !retryError(ctx, err) {
+				return nil, attempt, err
+			}
+			wait = config.backoff(attempt)
+		case !last && config.retryStatus(resp.StatusCode):
+			wait = config.backoff(attempt)
+			if delay, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				if delay > time.Duration(config.MaxRetryAfterSeconds)*time.Second {
+					// Waiting that long would stall the agent; let the
+					// model see the response instead
+					return resp, attempt, nil
+				}
+				wait = delay
+			}
+			// Drain a little of the body so the connection can be reused
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		default:
+			return resp, attempt, nil
+		}
+
+		if err := sleep(ctx, wait); err != nil {
+			return nil, attempt, fmt.Errorf("gave up waiting to retry: %w", err)
+		}
+	}
+}
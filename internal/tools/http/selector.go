@@ -0,0 +1,252 @@
+package http
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// selector is a parsed CSS selector group: a match of any alternative
+// selects the element
+type selector [][]selectorPart
+
+// selectorPart is a compound selector and how it relates to the part
+// before it
+type selectorPart struct {
+	child   bool // "a > b" rather than "a b"
+	tag     string
+	id      string
+	classes []string
+	attrs   []attrMatcher
+}
+
+type attrMatcher struct {
+	name  string
+	op    string // "", "=", "~=", "^=", "$=", "*="
+	value string
+}
+
+// maxSelectorParts caps the compound selectors in each alternative; the
+// selector comes from the model and is matched against untrusted pages
+const maxSelectorParts = 16
+
+// parseSelector parses the supported CSS subset: type, #id, .class and
+// [attr], [attr=value], [attr~=value], [attr^=value], [attr$=value] and
+// [attr*=value] selectors, combined with descendant and child combinators,
+// in comma separated groups
+func parseSelector(input string) (selector, error) {
+	var group selector
+	for _, alternative := range strings.Split(input, ",") {
+		tokens := strings.Fields(strings.ReplaceAll(alternative, ">", " > "))
+		if len(tokens) == 0 {
+			return nil, fmt.Errorf("empty selector in %q", input)
+		}
+
+		var parts []selectorPart
+		child := false
+		for _, token := range tokens {
+			if token == ">" {
+				if len(parts) == 0 || child {
+					return nil, fmt.Errorf("misplaced '>' in %q", alternative)
+				}
+				child = true
+				continue
+			}
+			part, err := parseCompound(token)
+			if err != nil {
+				return nil, err
+			}
+			part.child = child
+			child = false
+			parts = append(parts, part)
+		}
+		if child {
+			return nil, fmt.Errorf("selector %q ends with '>'", alternative)
+		}
+		if len(parts) > maxSelectorParts {
+			return nil, fmt.Errorf("selector %q has more than %d parts", alternative, maxSelectorParts)
+		}
+		group = append(group, parts)
+	}
+	return group, nil
+}
+
+func parseCompound(token string) (selectorPart, error) {
+	var part selectorPart
+	i := 0
+	readIdent := func() string {
+		start := i
+		for i < len(token) && !strings.ContainsRune("#.[", rune(token[i])) {
+			i++
+		}
+		return token[start:i]
+	}
+
+	part.tag = strings.ToLower(readIdent())
+	if part.tag == "*" {
+		part.tag = ""
+	}
+	for i < len(token) {
+		switch token[i] {
+		case '#':
+			i++
+			part.id = readIdent()
+		case '.':
+			i++
+			part.classes = append(part.classes, readIdent())
+		case '[':
+			end := strings.IndexByte(token[i:], ']')
+			if end < 0 {
+				return part, fmt.Errorf("unclosed '[' in %q", token)
+			}
+			part.attrs = append(part.attrs, parseAttrMatcher(token[i+1:i+end]))
+			i += end + 1
+		default:
+			return part, fmt.Errorf("unexpected %q in selector %q", token[i], token)
+		}
+	}
+	return part, nil
+}
+
+func parseAttrMatcher(inner string) attrMatcher {
+	for _, op := range []string{"~=", "^=", "$=", "*=", "="} {
+		if name, value, ok := strings.Cut(inner, op); ok {
+			return attrMatcher{
+				name:  strings.ToLower(strings.TrimSpace(name)),
+				op:    op,
+				value: strings.Trim(strings.TrimSpace(value), `"'`),
+			}
+		}
+	}
+	return attrMatcher{name: strings.ToLower(strings.TrimSpace(inner))}
+}
+
+// selectNodes returns the elements under root that match, in document order
+func (s selector) selectNodes(root *html.Node) []*html.Node {
+	m := &matcher{memo: make(map[matchKey]bool)}
+	var matches []*html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			for i, parts := range s {
+				if m.match(n, i, parts) {
+					matches = append(matches, n)
+					break
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+	return matches
+}
+
+// matcher matches selectors against elements, remembering the results for
+// each element and selector prefix. Without it descendant combinators
+// backtrack over every ancestor for every part, which takes exponential
+// time on deeply nested pages.
+type matcher struct {
+	memo map[matchKey]bool
+}
+
+// matchKey identifies the first parts of alternative alt matched at node,
+// or at node or any of its ancestors when within is set
+type matchKey struct {
+	node   *html.Node
+	alt    int
+	parts  int
+	within bool
+}
+
+// match matches the last compound against n and the rest against its
+// ancestors, right to left
+func (m *matcher) match(n *html.Node, alt int, parts []selectorPart) bool {
+	key := matchKey{node: n, alt: alt, parts: len(parts)}
+	if matched, ok := m.memo[key]; ok {
+		return matched
+	}
+	last := parts[len(parts)-1]
+	matched := last.matches(n)
+	if matched && len(parts) > 1 {
+		rest := parts[:len(parts)-1]
+		if parent := n.Parent; parent == nil || parent.Type != html.ElementNode {
+			matched = false
+		} else if last.child {
+			matched = m.match(parent, alt, rest)
+		} else {
+			matched = m.matchWithin(parent, alt, rest)
+		}
+	}
+	m.memo[key] = matched
+	return matched
+}
+
+// matchWithin reports whether n or one of its ancestors matches parts
+func (m *matcher) matchWithin(n *html.Node, alt int, parts []selectorPart) bool {
+	if n == nil || n.Type != html.ElementNode {
+		return false
+	}
+	key := matchKey{node: n, alt: alt, parts: len(parts), within: true}
+	if matched, ok := m.memo[key]; ok {
+		return matched
+	}
+	matched := m.match(n, alt, parts) || m.matchWithin(n.Parent, alt, parts)
+	m.memo[key] = matched
+	return matched
+}
+
+func (p selectorPart) matches(n *html.Node) bool {
+	if p.tag != "" && n.Data != p.tag {
+		return false
+	}
+	if p.id != "" && attr(n, "id") != p.id {
+		return false
+	}
+	classes := strings.Fields(attr(n, "class"))
+	for _, class := range p.classes {
+		if !containsString(classes, class) {
+			return false
+		}
+	}
+	for _, m := range p.attrs {
+		if !m.matches(n) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m attrMatcher) matches(n *html.Node) bool {
+	for _, a := range n.Attr {
+		if a.Key != m.name {
+			continue
+		}
+		switch m.op {
+		case "":
+			return true
+		case "=":
+			return a.Val == m.value
+		case "~=":
+			return containsString(strings.Fields(a.Val), m.value)
+		case "^=":
+			return strings.HasPrefix(a.Val, m.value)
+		case "$=":
+			return strings.HasSuffix(a.Val, m.value)
+		case "*=":
+			return strings.Contains(a.Val, m.value)
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package openapi
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// Config lists the APIs to generate tools for
+type Config struct {
+	APIs []APIConfig `json:"apis"`
+}
+
+// APIConfig selects operations of one OpenAPI 3 specification
+type APIConfig struct {
+	// Name identifies the API in tool names and errors
+	Name string `json:"name"`
+	// Spec is the path or http(s) URL of the specification, in JSON or YAML
+	Spec string `json:"spec"`
+	// BaseURL overrides the first server listed in the specification
+	BaseURL string `json:"baseURL,omitempty"`
+	// Tags selects operations carrying any of these tags
+	Tags []string `json:"tags,omitempty"`
+	// Operations selects operations by operationId
+	Operations []string `json:"operations,omitempty"`
+	// ToolPrefix is prepended to tool names, "<name>_" by default; set it
+	// to "" to use operation IDs as they are
+	ToolPrefix *string `json:"toolPrefix,omitempty"`
+}
+
+// Validate checks that every API has a name and a specification
+func (c Config) Validate() error {
+	names := make(map[string]bool)
+	for _, api := range c.APIs {
+		if api.Name == "" {
+			return fmt.Errorf("every API needs a name")
+		}
+		if names[api.Name] {
+			return fmt.Errorf("duplicate API name %q", api.Name)
+		}
+		names[api.Name] = true
+		if api.Spec == "" {
+			return fmt.Errorf("API %s: spec is required", api.Name)
+		}
+		if api.BaseURL != "" {
+			u, err := url.Parse(api.BaseURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("API %s: baseURL must be an http or https URL", api.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// prefix returns the tool name prefix for the API
+func (a APIConfig) prefix() string {
+	if a.ToolPrefix != nil {
+		return *a.ToolPrefix
+	}
+	return a.Name + "_"
+}
+
+// selects reports whether an operation is chosen by the tag and
+// operationId filters; without filters every operation is
+func (a APIConfig) selects(op *operation) bool {
+	if len(a.Tags) == 0 && len(a.Operations) == 0 {
+		return !op.Deprecated
+	}
+	for _, id := range a.Operations {
+		if id == op.OperationID {
+			return true
+		}
+	}
+	if op.Deprecated {
+		return false
+	}
+	for _, tag := range a.Tags {
+		for _, opTag := range op.Tags {
+			if tag == opTag {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,499 @@
+// Package openapi generates tools from OpenAPI 3 specifications, one per
+// selected operation
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-tools-agent/internal/agent"
+	httptool "github.com/go-tools-agent/internal/tools/http"
+)
+
+// Send performs a request given as input of the HTTP request tool
+type Send func(ctx context.Context, input json.RawMessage) (json.RawMessage, error)
+
+// boundParam maps a tool input property to an operation parameter
+type boundParam struct {
+	property string
+	parameter
+	// json is set for parameters described by content rather than a
+	// schema; their value is sent JSON encoded
+	json bool
+}
+
+// endpoint is a generated tool's operation
+type endpoint struct {
+	method  string
+	path    string
+	baseURL string
+	params  []boundParam
+	// bodyProperty is the input property holding the request body, and
+	// bodyType its media type; both are empty for operations without one
+	bodyProperty string
+	bodyType     string
+}
+
+// NewTools loads the configured specifications and returns a tool for every
+// selected operation. Requests are made through send, the HTTP request
+// tool's handler, so they follow its policy, credentials, retries and rate
+// limits.
+func NewTools(ctx context.Context, config Config, send Send) ([]agent.Tool, error) {
+	var tools []agent.Tool
+	names := make(map[string]bool)
+	for _, api := range config.APIs {
+		apiTools, err := newAPITools(ctx, api, send, names)
+		if err != nil {
+			return nil, fmt.Errorf("API %s: %w", api.Name, err)
+		}
+		log.Printf("📄 OpenAPI %s: %d tools", api.Name, len(apiTools))
+		tools = append(tools, apiTools...)
+	}
+	return tools, nil
+}
+
+func newAPITools(ctx context.Context, api APIConfig, send Send, names map[string]bool) ([]agent.Tool, error) {
+	s, err := loadSpec(ctx, api.Spec)
+	if err != nil {
+		return nil, err
+	}
+	baseURL := strings.TrimSuffix(api.BaseURL, "/")
+	if baseURL == "" {
+		if baseURL, err = s.baseURL(); err != nil {
+			return nil, err
+		}
+	}
+
+	var tools []agent.Tool
+	var failed error
+	s.operations(func(method, path string, raw map[string]interface{}, pathParams []interface{}) {
+		if failed != nil {
+			return
+		}
+		// Only selected operations are resolved, so references elsewhere
+		// in a large specification do not matter
+		if !api.selects(operationHeader(raw)) {
+			return
+		}
+		var op operation
+		var shared struct {
+			Parameters []parameter `json:"parameters"`
+		}
+		if err := s.decode(raw, &op); err != nil {
+			failed = fmt.Errorf("%s %s: %w", strings.ToUpper(method), path, err)
+			return
+		}
+		if err := s.decode(map[string]interface{}{"parameters": pathParams}, &shared); err != nil {
+			failed = fmt.Errorf("%s %s: %w", strings.ToUpper(method), path, err)
+			return
+		}
+		op.Parameters = mergeParameters(shared.Parameters, op.Parameters)
+
+		name := uniqueName(api.prefix()+operationName(method, path, op.OperationID), names)
+		tool, err := newTool(name, method, path, baseURL, op, send)
+		if err != nil {
+			failed = fmt.Errorf("%s %s: %w", strings.ToUpper(method), path, err)
+			return
+		}
+		tools = append(tools, tool)
+	})
+	if failed != nil {
+		return nil, failed
+	}
+
+	// Name missing operation IDs so typos in the config do not go unnoticed
+	for _, id := range api.Operations {
+		found := false
+		s.operations(func(method, path string, raw map[string]interface{}, pathParams []interface{}) {
+			if raw["operationId"] == id {
+				found = true
+			}
+		})
+		if !found {
+			return nil, fmt.Errorf("operation %q not found", id)
+		}
+	}
+	return tools, nil
+}
+
+// operationHeader reads the fields operations are selected by
+func operationHeader(raw map[string]interface{}) *operation {
+	op := &operation{}
+	op.OperationID, _ = raw["operationId"].(string)
+	op.Deprecated, _ = raw["deprecated"].(bool)
+	tags, _ := raw["tags"].([]interface{})
+	for _, tag := range tags {
+		op.Tags = append(op.Tags, fmt.Sprint(tag))
+	}
+	return op
+}
+
+// mergeParameters lets operation parameters override path-level ones
+func mergeParameters(shared, own []parameter) []parameter {
+	merged := append([]parameter(nil), own...)
+	for _, p := range shared {
+		overridden := false
+		for _, o := range own {
+			if o.Name == p.Name && o.In == p.In {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			merged = append(merged, p)
+		}
+	}
+	return merged
+}
+
+// invalidNameChars are characters not allowed in tool names
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// operationName derives a tool name from the operationId, or from the
+// method and path when there is none
+func operationName(method, path, operationID string) string {
+	if operationID != "" {
+		return strings.Trim(invalidNameChars.ReplaceAllString(operationID, "_"), "_")
+	}
+	parts := []string{method}
+	for _, segment := range strings.Split(path, "/") {
+		if segment = strings.Trim(invalidNameChars.ReplaceAllString(segment, "_"), "_"); segment != "" {
+			parts = append(parts, segment)
+		}
+	}
+	return strings.Join(parts, "_")
+}
+
+// uniqueName shortens a name to the 64 characters allowed and numbers
+// duplicates
+func uniqueName(name string, names map[string]bool) string {
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	unique := name
+	for i := 2; names[unique]; i++ {
+		suffix := fmt.Sprintf("_%d", i)
+		if len(name)+len(suffix) > 64 {
+			unique = name[:64-len(suffix)] + suffix
+		} else {
+			unique = name + suffix
+		}
+	}
+	names[unique] = true
+	return unique
+}
+
+// newTool builds the schema and handler for one operation
+func newTool(name, method, path, baseURL string, op operation, send Send) (agent.Tool, error) {
+	e := &endpoint{method: strings.ToUpper(method), path: path, baseURL: baseURL}
+	properties := make(map[string]interface{})
+	required := []string{}
+
+	for _, p := range op.Parameters {
+		if p.Name == "" || p.In == "" {
+			continue
+		}
+		bound := boundParam{property: p.Name, parameter: p}
+		// The same name may be used in several locations
+		if _, taken := properties[p.Name]; taken {
+			bound.property = p.In + "_" + p.Name
+		}
+		schema := p.Schema
+		if schema == nil {
+			for _, media := range p.Content {
+				schema, bound.json = media.Schema, true
+				break
+			}
+		}
+		property := cleanSchema(schema, true)
+		if p.Description != "" {
+			property["description"] = p.Description
+		}
+		properties[bound.property] = property
+		if p.Required || p.In == "path" {
+			required = append(required, bound.property)
+		}
+		e.params = append(e.params, bound)
+	}
+
+	if op.RequestBody != nil {
+		mediaType, schema := bodyMediaType(op.RequestBody.Content)
+		if mediaType == "" {
+			return agent.Tool{}, fmt.Errorf("no supported request body media type")
+		}
+		e.bodyType = mediaType
+		e.bodyProperty = "body"
+		if _, taken := properties["body"]; taken {
+			e.bodyProperty = "requestBody"
+		}
+		property := cleanSchema(schema, true)
+		if op.RequestBody.Description != "" {
+			property["description"] = op.RequestBody.Description
+		}
+		properties[e.bodyProperty] = property
+		if op.RequestBody.Required {
+			required = append(required, e.bodyProperty)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return agent.Tool{}, err
+	}
+
+	description := op.Summary
+	if description == "" {
+		description = firstSentence(op.Description)
+	}
+	description = strings.TrimSuffix(strings.TrimSpace(description), ".")
+	if description == "" {
+		description = fmt.Sprintf("Calls %s %s", e.method, path)
+	} else {
+		description += fmt.Sprintf(" (%s %s)", e.method, path)
+	}
+	description += ". Returns the HTTP response with statusCode, headers and body"
+
+	return agent.Tool{
+		Name:        name,
+		Description: description,
+		Schema:      schemaJSON,
+		Handler: func(ctx context.Context, input json.RawMessage) (json.RawMessage, error) {
+			request, err := e.request(input)
+			if err != nil {
+				return nil, err
+			}
+			return send(ctx, request)
+		},
+	}, nil
+}
+
+// bodyMediaType picks the request body encoding: JSON if offered, then
+// form data, then plain text
+func bodyMediaType(content map[string]mediaType) (string, map[string]interface{}) {
+	var form, text string
+	for _, mediaType := range sortedKeys(content) {
+		base, _, err := mime.ParseMediaType(mediaType)
+		if err != nil {
+			continue
+		}
+		switch {
+		case base == "application/json" || strings.HasSuffix(base, "+json"):
+			return mediaType, content[mediaType].Schema
+		case base == "application/x-www-form-urlencoded" && form == "":
+			form = mediaType
+		case strings.HasPrefix(base, "text/") && text == "":
+			text = mediaType
+		}
+	}
+	if form != "" {
+		return form, content[form].Schema
+	}
+	if text != "" {
+		return text, map[string]interface{}{"type": "string"}
+	}
+	return "", nil
+}
+
+// request builds the HTTP request tool input for a call
+func (e *endpoint) request(input json.RawMessage) (json.RawMessage, error) {
+	args := make(map[string]json.RawMessage)
+	if len(input) > 0 && string(input) != "null" {
+		if err := json.Unmarshal(input, &args); err != nil {
+			return nil, fmt.Errorf("invalid input: %w", err)
+		}
+	}
+
+	path := e.path
+	query := url.Values{}
+	headers := make(map[string]string)
+	var cookies []string
+	for _, p := range e.params {
+		raw, ok := args[p.property]
+		if !ok || string(raw) == "null" {
+			if p.Required || p.In == "path" {
+				return nil, fmt.Errorf("missing required parameter %s", p.property)
+			}
+			continue
+		}
+		if p.json {
+			raw, _ = json.Marshal(string(raw))
+		}
+		switch p.In {
+		case "path":
+			// Escaping leaves dots alone, and a . or .. segment would be
+			// resolved to another path on the server
+			value := joinValue(raw, ",")
+			if value == "." || value == ".." {
+				return nil, fmt.Errorf("invalid value %q for path parameter %s", value, p.property)
+			}
+			path = strings.ReplaceAll(path, "{"+p.Name+"}", url.PathEscape(value))
+		case "query":
+			addQuery(query, p.parameter, raw)
+		case "header":
+			headers[p.Name] = joinValue(raw, ",")
+		case "cookie":
+			cookies = append(cookies, p.Name+"="+url.QueryEscape(joinValue(raw, ",")))
+		}
+	}
+	if len(cookies) > 0 {
+		headers["Cookie"] = strings.Join(cookies, "; ")
+	}
+
+	target := e.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	request := httptool.HTTPRequestInput{URL: target, Method: e.method, Headers: headers}
+
+	if raw, ok := args[e.bodyProperty]; ok && e.bodyProperty != "" && string(raw) != "null" {
+		body, err := encodeBody(e.bodyType, raw)
+		if err != nil {
+			return nil, err
+		}
+		request.Body = body
+		headers["Content-Type"] = e.bodyType
+	}
+	return json.Marshal(request)
+}
+
+// addQuery adds a query parameter in its style: form (the default) repeats
+// exploded arrays and spreads exploded objects, deepObject uses
+// name[key]=value, and the delimited styles join arrays
+func addQuery(query url.Values, p parameter, raw json.RawMessage) {
+	explode := p.Explode == nil || *p.Explode
+	if p.Style != "" && p.Style != "form" {
+		explode = p.Explode != nil && *p.Explode
+	}
+
+	var object map[string]json.RawMessage
+	if json.Unmarshal(raw, &object) == nil {
+		for _, key := range sortedKeys(object) {
+			switch {
+			case p.Style == "deepObject":
+				query.Add(p.Name+"["+key+"]", scalar(object[key]))
+			case explode:
+				query.Add(key, scalar(object[key]))
+			}
+		}
+		if p.Style != "deepObject" && !explode {
+			query.Add(p.Name, joinValue(raw, ","))
+		}
+		return
+	}
+
+	var list []json.RawMessage
+	if json.Unmarshal(raw, &list) == nil && explode {
+		for _, item := range list {
+			query.Add(p.Name, scalar(item))
+		}
+		return
+	}
+	separator := ","
+	switch p.Style {
+	case "spaceDelimited":
+		separator = " "
+	case "pipeDelimited":
+		separator = "|"
+	}
+	query.Add(p.Name, joinValue(raw, separator))
+}
+
+// joinValue renders a value in the simple style: arrays and objects are
+// flattened into a separated list
+func joinValue(raw json.RawMessage, separator string) string {
+	var list []json.RawMessage
+	if json.Unmarshal(raw, &list) == nil {
+		parts := make([]string, len(list))
+		for i, item := range list {
+			parts[i] = scalar(item)
+		}
+		return strings.Join(parts, separator)
+	}
+	var object map[string]json.RawMessage
+	if json.Unmarshal(raw, &object) == nil {
+		var parts []string
+		for _, key := range sortedKeys(object) {
+			parts = append(parts, key, scalar(object[key]))
+		}
+		return strings.Join(parts, separator)
+	}
+	return scalar(raw)
+}
+
+// scalar renders a JSON value as text: strings without quotes, anything
+// else as JSON
+func scalar(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+// encodeBody encodes the request body for its media type
+func encodeBody(mediaType string, raw json.RawMessage) (string, error) {
+	base, _, _ := mime.ParseMediaType(mediaType)
+	switch {
+	case base == "application/x-www-form-urlencoded":
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return "", fmt.Errorf("form body must be an object: %w", err)
+		}
+		form := url.Values{}
+		for _, key := range sortedKeys(fields) {
+			var list []json.RawMessage
+			if json.Unmarshal(fields[key], &list) == nil {
+				for _, item := range list {
+					form.Add(key, scalar(item))
+				}
+				continue
+			}
+			form.Add(key, scalar(fields[key]))
+		}
+		return form.Encode(), nil
+	case strings.HasPrefix(base, "text/"):
+		return scalar(raw), nil
+	default:
+		return string(raw), nil
+	}
+}
+
+// firstSentence shortens a long description for the tool description
+func firstSentence(text string) string {
+	text = strings.TrimSpace(text)
+	if i := strings.Index(text, "\n\n"); i >= 0 {
+		text = text[:i]
+	}
+	if i := strings.Index(text, ". "); i >= 0 {
+		text = text[:i+1]
+	}
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	if len(runes) > 300 {
+		return string(runes[:300]) + "..."
+	}
+	return string(runes)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
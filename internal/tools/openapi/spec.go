@@ -0,0 +1,368 @@
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// maxSpecBytes caps the size of a specification
+const maxSpecBytes = 20 << 20
+
+// methods are the operations of a path item, in the order tools are listed
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// operation is the part of an OpenAPI operation object used to build a
+// tool, after references have been resolved
+type operation struct {
+	OperationID string       `json:"operationId"`
+	Summary     string       `json:"summary"`
+	Description string       `json:"description"`
+	Tags        []string     `json:"tags"`
+	Deprecated  bool         `json:"deprecated"`
+	Parameters  []parameter  `json:"parameters"`
+	RequestBody *requestBody `json:"requestBody"`
+}
+
+// parameter is an OpenAPI parameter object
+type parameter struct {
+	Name        string                 `json:"name"`
+	In          string                 `json:"in"`
+	Description string                 `json:"description"`
+	Required    bool                   `json:"required"`
+	Schema      map[string]interface{} `json:"schema"`
+	Style       string                 `json:"style"`
+	Explode     *bool                  `json:"explode"`
+	Content     map[string]mediaType   `json:"content"`
+}
+
+// requestBody is an OpenAPI request body object
+type requestBody struct {
+	Description string               `json:"description"`
+	Required    bool                 `json:"required"`
+	Content     map[string]mediaType `json:"content"`
+}
+
+type mediaType struct {
+	Schema map[string]interface{} `json:"schema"`
+}
+
+// spec is a loaded specification kept as a generic JSON tree, so
+// references can be resolved by JSON pointer
+type spec struct {
+	root   map[string]interface{}
+	source string
+}
+
+// loadSpec reads a specification from a file or URL
+func loadSpec(ctx context.Context, source string) (*spec, error) {
+	var content []byte
+	var err error
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		content, err = fetchSpec(ctx, source)
+	} else {
+		content, err = os.ReadFile(source)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var root interface{}
+	if trimmed := bytes.TrimSpace(content); len(trimmed) > 0 && trimmed[0] == '{' {
+		err = json.Unmarshal(trimmed, &root)
+	} else {
+		var doc interface{}
+		if err = yaml.Unmarshal(content, &doc); err == nil {
+			root = jsonValue(doc)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse specification: %w", err)
+	}
+	object, ok := root.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("specification is not an object")
+	}
+	version, _ := object["openapi"].(string)
+	if !strings.HasPrefix(version, "3.") {
+		return nil, fmt.Errorf("only OpenAPI 3 specifications are supported")
+	}
+	return &spec{root: object, source: source}, nil
+}
+
+func fetchSpec(ctx context.Context, source string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json, application/yaml, text/yaml")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch specification: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch specification: status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxSpecBytes))
+}
+
+// jsonValue converts a decoded YAML document to the types encoding/json
+// produces, so both formats are handled alike
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			object[fmt.Sprint(key)] = jsonValue(value)
+		}
+		return object
+	case []interface{}:
+		for i, value := range v {
+			v[i] = jsonValue(value)
+		}
+		return v
+	case int:
+		return float64(v)
+	default:
+		return v
+	}
+}
+
+// baseURL returns the URL of the first server, with variables set to their
+// defaults and relative URLs resolved against the specification's URL
+func (s *spec) baseURL() (string, error) {
+	servers, _ := s.root["servers"].([]interface{})
+	if len(servers) == 0 {
+		return "", fmt.Errorf("the specification lists no servers; set baseURL")
+	}
+	server, _ := servers[0].(map[string]interface{})
+	raw, _ := server["url"].(string)
+	variables, _ := server["variables"].(map[string]interface{})
+	for name, variable := range variables {
+		if v, ok := variable.(map[string]interface{}); ok {
+			raw = strings.ReplaceAll(raw, "{"+name+"}", fmt.Sprint(v["default"]))
+		}
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid server URL %q", raw)
+	}
+	if !u.IsAbs() {
+		base, err := url.Parse(s.source)
+		if err != nil || (base.Scheme != "http" && base.Scheme != "https") {
+			return "", fmt.Errorf("server URL %q is relative; set baseURL", raw)
+		}
+		u = base.ResolveReference(u)
+	}
+	return strings.TrimSuffix(u.String(), "/"), nil
+}
+
+// resolve returns node with every local $ref replaced by its target.
+// Recursive schemas are cut off where they refer back to themselves.
+func (s *spec) resolve(node interface{}, inProgress map[string]bool) (interface{}, error) {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		if ref, ok := v["$ref"].(string); ok {
+			if inProgress[ref] {
+				return map[string]interface{}{"type": "object"}, nil
+			}
+			target, err := s.pointer(ref)
+			if err != nil {
+				return nil, err
+			}
+			inProgress[ref] = true
+			resolved, err := s.resolve(target, inProgress)
+			delete(inProgress, ref)
+			if err != nil {
+				return nil, err
+			}
+			// Keys next to $ref, such as a description, take precedence
+			if object, ok := resolved.(map[string]interface{}); ok && len(v) > 1 {
+				merged := make(map[string]interface{}, len(object)+len(v))
+				for key, value := range object {
+					merged[key] = value
+				}
+				for key, value := range v {
+					if key != "$ref" {
+						merged[key] = value
+					}
+				}
+				return merged, nil
+			}
+			return resolved, nil
+		}
+		object := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			resolved, err := s.resolve(value, inProgress)
+			if err != nil {
+				return nil, err
+			}
+			object[key] = resolved
+		}
+		return object, nil
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, value := range v {
+			resolved, err := s.resolve(value, inProgress)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = resolved
+		}
+		return list, nil
+	default:
+		return v, nil
+	}
+}
+
+// pointer looks up a local reference such as #/components/schemas/Pet
+func (s *spec) pointer(ref string) (interface{}, error) {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("reference %q: only references within the specification are supported", ref)
+	}
+	var node interface{} = s.root
+	for _, token := range strings.Split(ref[2:], "/") {
+		if unescaped, err := url.PathUnescape(token); err == nil {
+			token = unescaped
+		}
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		object, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("reference %q not found", ref)
+		}
+		if node, ok = object[token]; !ok {
+			return nil, fmt.Errorf("reference %q not found", ref)
+		}
+	}
+	return node, nil
+}
+
+// decode resolves references in node and decodes it into target
+func (s *spec) decode(node interface{}, target interface{}) error {
+	resolved, err := s.resolve(node, make(map[string]bool))
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(resolved)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, target)
+}
+
+// operations calls fn for every operation in path order, with the
+// path-level parameters merged in; operation parameters override path
+// parameters of the same name and location
+func (s *spec) operations(fn func(method, path string, raw map[string]interface{}, pathParams []interface{})) {
+	paths, _ := s.root["paths"].(map[string]interface{})
+	for _, path := range sortedKeys(paths) {
+		item, _ := paths[path].(map[string]interface{})
+		if ref, ok := item["$ref"].(string); ok {
+			if target, err := s.pointer(ref); err == nil {
+				item, _ = target.(map[string]interface{})
+			}
+		}
+		pathParams, _ := item["parameters"].([]interface{})
+		for _, method := range methods {
+			if raw, ok := item[method].(map[string]interface{}); ok {
+				fn(method, path, raw, pathParams)
+			}
+		}
+	}
+}
+
+// schemaKeys are the JSON Schema keywords kept when converting OpenAPI 3.0
+// schemas; OpenAPI-only keywords such as discriminator, xml and example are
+// dropped
+var schemaKeys = map[string]bool{
+	"type": true, "format": true, "description": true, "title": true, "enum": true, "const": true, "default": true,
+	"properties": true, "required": true, "additionalProperties": true, "items": true,
+	"allOf": true, "anyOf": true, "oneOf": true, "not": true,
+	"minimum": true, "maximum": true, "exclusiveMinimum": true, "exclusiveMaximum": true, "multipleOf": true,
+	"minLength": true, "maxLength": true, "pattern": true,
+	"minItems": true, "maxItems": true, "uniqueItems": true, "minProperties": true, "maxProperties": true,
+}
+
+// cleanSchema converts an OpenAPI schema to plain JSON Schema. In request
+// schemas, readOnly properties are removed since servers ignore them.
+func cleanSchema(schema map[string]interface{}, request bool) map[string]interface{} {
+	if schema == nil {
+		return map[string]interface{}{}
+	}
+	clean := make(map[string]interface{}, len(schema))
+	for key, value := range schema {
+		if !schemaKeys[key] {
+			continue
+		}
+		switch key {
+		case "properties":
+			properties, _ := value.(map[string]interface{})
+			cleaned := make(map[string]interface{}, len(properties))
+			for name, property := range properties {
+				propertySchema, _ := property.(map[string]interface{})
+				if request && propertySchema["readOnly"] == true {
+					continue
+				}
+				cleaned[name] = cleanSchema(propertySchema, request)
+			}
+			clean[key] = cleaned
+		case "items", "not":
+			sub, _ := value.(map[string]interface{})
+			clean[key] = cleanSchema(sub, request)
+		case "additionalProperties":
+			if sub, ok := value.(map[string]interface{}); ok {
+				clean[key] = cleanSchema(sub, request)
+			} else {
+				clean[key] = value
+			}
+		case "allOf", "anyOf", "oneOf":
+			list, _ := value.([]interface{})
+			cleaned := make([]interface{}, len(list))
+			for i, item := range list {
+				sub, _ := item.(map[string]interface{})
+				cleaned[i] = cleanSchema(sub, request)
+			}
+			clean[key] = cleaned
+		default:
+			clean[key] = value
+		}
+	}
+
+	// Properties removed above cannot be required
+	if properties, ok := clean["properties"].(map[string]interface{}); ok {
+		if required, ok := clean["required"].([]interface{}); ok {
+			kept := []interface{}{}
+			for _, name := range required {
+				if _, ok := properties[fmt.Sprint(name)]; ok {
+					kept = append(kept, name)
+				}
+			}
+			if len(kept) > 0 {
+				clean["required"] = kept
+			} else {
+				delete(clean, "required")
+			}
+		}
+	}
+
+	// OpenAPI 3.0 marks null with nullable
+	if schema["nullable"] == true {
+		if t, ok := clean["type"].(string); ok {
+			clean["type"] = []interface{}{t, "null"}
+		}
+	}
+	return clean
+}
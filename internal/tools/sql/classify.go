@@ -0,0 +1,192 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// statement is what classify learned about a SQL statement
+type statement struct {
+	// keyword is the first keyword, upper-cased
+	keyword string
+	// readOnly is set for queries that cannot modify data
+	readOnly bool
+	// returning is set when the statement has a RETURNING clause, so a
+	// write still produces rows
+	returning bool
+}
+
+// readKeywords may start a read-only statement
+var readKeywords = map[string]bool{
+	"SELECT": true, "WITH": true, "VALUES": true, "EXPLAIN": true, "SHOW": true, "TABLE": true,
+}
+
+// writeKeywords make a statement a write wherever they appear: in
+// data-modifying common table expressions, SELECT INTO, or the statement an
+// EXPLAIN ANALYZE runs
+var writeKeywords = map[string]bool{
+	"INSERT": true, "UPDATE": true, "DELETE": true, "MERGE": true, "INTO": true, "UPSERT": true,
+	"CREATE": true, "DROP": true, "ALTER": true, "TRUNCATE": true, "GRANT": true, "REVOKE": true,
+}
+
+// classify checks that query is a single statement and decides whether it
+// only reads. String literals, quoted identifiers and comments are skipped,
+// so keywords inside them do not count.
+//
+// Classification is the first line of defence; read-only statements are
+// also run in read-only transactions, which the database enforces.
+func classify(query string) (statement, error) {
+	var stmt statement
+	sawWrite := false
+	ended := false
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += end + 1
+			}
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end, err := skipBlockComment(query, i)
+			if err != nil {
+				return stmt, err
+			}
+			i = end
+		case c == ';':
+			ended = true
+			i++
+		default:
+			if ended {
+				return stmt, fmt.Errorf("only one statement may be run per call")
+			}
+			next, word, err := scanToken(query, i)
+			if err != nil {
+				return stmt, err
+			}
+			i = next
+			if word == "" {
+				continue
+			}
+			upper := strings.ToUpper(word)
+			if stmt.keyword == "" {
+				stmt.keyword = upper
+			}
+			if writeKeywords[upper] {
+				sawWrite = true
+			}
+			if upper == "RETURNING" {
+				stmt.returning = true
+			}
+		}
+	}
+	if stmt.keyword == "" {
+		return stmt, fmt.Errorf("query is empty")
+	}
+	stmt.readOnly = readKeywords[stmt.keyword] && !sawWrite
+	return stmt, nil
+}
+
+// scanToken reads the token at i and returns the index after it. Words
+// are returned; literals, quoted identifiers and punctuation are skipped.
+func scanToken(query string, i int) (int, string, error) {
+	c := query[i]
+	switch {
+	case c == '\'':
+		return skipQuoted(query, i, '\'', false)
+	case (c == 'E' || c == 'e') && i+1 < len(query) && query[i+1] == '\'':
+		// Postgres escape strings allow backslash escapes
+		return skipQuoted(query, i+1, '\'', true)
+	case c == '"':
+		return skipQuoted(query, i, '"', false)
+	case c == '`':
+		return skipQuoted(query, i, '`', false)
+	case c == '[':
+		end := strings.IndexByte(query[i:], ']')
+		if end < 0 {
+			return 0, "", fmt.Errorf("unterminated [identifier]")
+		}
+		return i + end + 1, "", nil
+	case c == '$':
+		// Postgres dollar quoting: $$...$$ or $tag$...$tag$. $1 is a
+		// parameter.
+		j := i + 1
+		for j < len(query) && isWordByte(query[j]) && !(j == i+1 && query[j] >= '0' && query[j] <= '9') {
+			j++
+		}
+		if j < len(query) && query[j] == '$' {
+			tag := query[i : j+1]
+			end := strings.Index(query[j+1:], tag)
+			if end < 0 {
+				return 0, "", fmt.Errorf("unterminated dollar-quoted string")
+			}
+			return j + 1 + end + len(tag), "", nil
+		}
+		for j < len(query) && query[j] >= '0' && query[j] <= '9' {
+			j++
+		}
+		return max(j, i+1), "", nil
+	case isWordByte(c) && !(c >= '0' && c <= '9'):
+		j := i
+		for j < len(query) && (isWordByte(query[j]) || query[j] == '$') {
+			j++
+		}
+		return j, query[i:j], nil
+	case isWordByte(c):
+		j := i
+		for j < len(query) && (isWordByte(query[j]) || query[j] == '.') {
+			j++
+		}
+		return j, "", nil
+	default:
+		return i + 1, "", nil
+	}
+}
+
+// skipQuoted skips a quoted literal or identifier starting at i, where a
+// doubled quote stands for itself
+func skipQuoted(query string, i int, quote byte, backslash bool) (int, string, error) {
+	for j := i + 1; j < len(query); j++ {
+		switch query[j] {
+		case '\\':
+			if backslash {
+				j++
+			}
+		case quote:
+			if j+1 < len(query) && query[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1, "", nil
+		}
+	}
+	return 0, "", fmt.Errorf("unterminated %c", quote)
+}
+
+// skipBlockComment skips a /* */ comment, which may be nested in Postgres
+func skipBlockComment(query string, i int) (int, error) {
+	depth := 0
+	for j := i; j < len(query)-1; j++ {
+		switch {
+		case query[j] == '/' && query[j+1] == '*':
+			depth++
+			j++
+		case query[j] == '*' && query[j+1] == '/':
+			depth--
+			j++
+			if depth == 0 {
+				return j + 1, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unterminated comment")
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
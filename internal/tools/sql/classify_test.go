@@ -0,0 +1,75 @@
+package sql
+
+import "testing"
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		keyword   string
+		readOnly  bool
+		returning bool
+	}{
+		{"select", "SELECT * FROM users", "SELECT", true, false},
+		{"lower case", "select 1", "SELECT", true, false},
+		{"trailing semicolon", "SELECT 1;  ", "SELECT", true, false},
+		{"leading comment", "-- list users\n/* all */ SELECT * FROM users", "SELECT", true, false},
+		{"with", "WITH t AS (SELECT 1) SELECT * FROM t", "WITH", true, false},
+		{"values", "VALUES (1), (2)", "VALUES", true, false},
+		{"delete", "DELETE FROM users", "DELETE", false, false},
+		{"pragma", "PRAGMA writable_schema = ON", "PRAGMA", false, false},
+		{"attach", "ATTACH DATABASE 'other.db' AS other", "ATTACH", false, false},
+		{"data-modifying cte", "WITH gone AS (DELETE FROM users RETURNING *) SELECT * FROM gone", "WITH", false, true},
+		{"select into", "SELECT * INTO copy FROM users", "SELECT", false, false},
+		{"explain analyze", "EXPLAIN ANALYZE DELETE FROM users", "EXPLAIN", false, false},
+		{"returning", "INSERT INTO users (name) VALUES ('a') RETURNING id", "INSERT", false, true},
+
+		// Keywords inside literals, identifiers and comments do not count
+		{"keyword in string", "SELECT 'DELETE FROM users; DROP TABLE users'", "SELECT", true, false},
+		{"doubled quote", "SELECT 'it''s; DELETE'", "SELECT", true, false},
+		{"keyword in identifier", `SELECT "delete", [drop], ` + "`update`" + ` FROM t`, "SELECT", true, false},
+		{"keyword in line comment", "SELECT 1 -- DELETE FROM users", "SELECT", true, false},
+		{"keyword in block comment", "SELECT /* ; DROP TABLE users */ 1", "SELECT", true, false},
+		{"nested block comment", "SELECT /* a /* DELETE */ ; */ 1", "SELECT", true, false},
+		{"dollar quoted", "SELECT $body$ DELETE FROM users; $body$", "SELECT", true, false},
+		{"escape string", `SELECT E'\'; DELETE FROM users'`, "SELECT", true, false},
+		{"positional parameter", "SELECT * FROM users WHERE id = $1", "SELECT", true, false},
+		{"keyword as column prefix", "SELECT updated_at, deleted FROM users", "SELECT", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := classify(tt.query)
+			if err != nil {
+				t.Fatalf("classify(%q) failed: %v", tt.query, err)
+			}
+			if stmt.keyword != tt.keyword || stmt.readOnly != tt.readOnly || stmt.returning != tt.returning {
+				t.Errorf("classify(%q) = %+v, want keyword %s, readOnly %v, returning %v",
+					tt.query, stmt, tt.keyword, tt.readOnly, tt.returning)
+			}
+		})
+	}
+}
+
+func TestClassifyRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"empty", ""},
+		{"only comments", "-- nothing\n/* here */ ;"},
+		{"two statements", "SELECT 1; DELETE FROM users"},
+		{"two statements after comment", "SELECT 1; -- done\nSELECT 2"},
+		{"unterminated string", "SELECT 'abc"},
+		{"unterminated identifier", `SELECT "abc`},
+		{"unterminated bracket", "SELECT [abc"},
+		{"unterminated comment", "SELECT 1 /* DELETE"},
+		{"unterminated dollar quote", "SELECT $$abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if stmt, err := classify(tt.query); err == nil {
+				t.Errorf("classify(%q) = %+v, want an error", tt.query, stmt)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package sql
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// Config holds the settings for the SQL tool
+type Config struct {
+	// Connections are the databases the tool may query; the tool is
+	// disabled when there are none
+	Connections []ConnectionConfig `json:"connections"`
+	// MaxRows and MaxBytes cap a query result; rows beyond either limit
+	// are dropped and the result is marked truncated
+	MaxRows  int `json:"maxRows"`
+	MaxBytes int `json:"maxBytes"`
+	// TimeoutSeconds bounds each call, unless a connection sets its own
+	TimeoutSeconds int `json:"timeoutSeconds"`
+}
+
+// ConnectionConfig describes one database
+type ConnectionConfig struct {
+	// Name identifies the connection in the tool input
+	Name string `json:"name"`
+	// Description tells the model what the database holds
+	Description string `json:"description,omitempty"`
+	// Driver is "sqlite" or "postgres"
+	Driver string `json:"driver"`
+	// DSN is a file path or file: URI for SQLite, and a postgres:// URL or
+	// key=value string for Postgres. ${NAME} references are expanded.
+	DSN string `json:"dsn"`
+	// AllowWrites permits statements other than queries. Connections are
+	// read-only by default.
+	AllowWrites bool `json:"allowWrites,omitempty"`
+	// TimeoutSeconds overrides the default query timeout
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+}
+
+// Drivers supported by the SQL tool
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// DefaultConfig returns the default SQL tool settings, with no connections
+func DefaultConfig() Config {
+	return Config{
+		MaxRows:        200,
+		MaxBytes:       64 << 10,
+		TimeoutSeconds: 30,
+	}
+}
+
+// connectionNamePattern keeps connection names easy to type for the model
+var connectionNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// Validate checks the limits and connections
+func (c Config) Validate() error {
+	if c.MaxRows <= 0 || c.MaxBytes <= 0 || c.TimeoutSeconds <= 0 {
+		return fmt.Errorf("maxRows, maxBytes and timeoutSeconds must be positive")
+	}
+	seen := make(map[string]bool)
+	for _, conn := range c.Connections {
+		if !connectionNamePattern.MatchString(conn.Name) {
+			return fmt.Errorf("invalid connection name %q", conn.Name)
+		}
+		if seen[conn.Name] {
+			return fmt.Errorf("duplicate connection name %q", conn.Name)
+		}
+		seen[conn.Name] = true
+		if conn.Driver != DriverSQLite && conn.Driver != DriverPostgres {
+			return fmt.Errorf("connection %s: driver must be %s or %s", conn.Name, DriverSQLite, DriverPostgres)
+		}
+		if conn.DSN == "" {
+			return fmt.Errorf("connection %s: dsn is required", conn.Name)
+		}
+		if conn.TimeoutSeconds < 0 {
+			return fmt.Errorf("connection %s: timeoutSeconds must not be negative", conn.Name)
+		}
+	}
+	return nil
+}
+
+// Secrets returns the values of the environment variables connection
+// strings refer to, such as a password in ${PGPASSWORD}, so callers can
+// redact them
+func Secrets(config Config) []string {
+	var secrets []string
+	for _, conn := range config.Connections {
+		os.Expand(conn.DSN, func(name string) string {
+			if value := os.Getenv(name); value != "" {
+				secrets = append(secrets, value)
+			}
+			return ""
+		})
+	}
+	return secrets
+}
@@ -0,0 +1,150 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Catalog queries. Postgres tables outside the current schema are listed as
+// schema.table.
+const (
+	sqliteTables = `SELECT name, type FROM sqlite_master
+		WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite_%' ORDER BY name`
+	sqliteColumns     = `SELECT name, type, "notnull", COALESCE(dflt_value, ''), pk FROM pragma_table_info(?, ?) ORDER BY cid`
+	sqliteForeignKeys = `SELECT "from", "table" || '.' || COALESCE("to", '') FROM pragma_foreign_key_list(?, ?)`
+
+	postgresTables = `SELECT CASE WHEN table_schema = current_schema() THEN table_name ELSE table_schema || '.' || table_name END,
+			CASE WHEN table_type = 'VIEW' THEN 'view' ELSE 'table' END
+		FROM information_schema.tables
+		WHERE table_schema NOT IN ('pg_catalog', 'information_schema')
+		ORDER BY 1`
+	postgresColumns = `SELECT column_name, data_type, is_nullable = 'YES', COALESCE(column_default, '')
+		FROM information_schema.columns
+		WHERE table_schema = COALESCE(NULLIF($1, ''), current_schema()) AND table_name = $2
+		ORDER BY ordinal_position`
+	postgresKeys = `SELECT kcu.column_name, tc.constraint_type, COALESCE(ccu.table_name || '.' || ccu.column_name, '')
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage kcu
+			ON kcu.constraint_schema = tc.constraint_schema AND kcu.constraint_name = tc.constraint_name
+		LEFT JOIN information_schema.constraint_column_usage ccu
+			ON tc.constraint_type = 'FOREIGN KEY' AND ccu.constraint_schema = tc.constraint_schema AND ccu.constraint_name = tc.constraint_name
+		WHERE tc.constraint_type IN ('PRIMARY KEY', 'FOREIGN KEY')
+			AND tc.table_schema = COALESCE(NULLIF($1, ''), current_schema()) AND tc.table_name = $2`
+)
+
+// listTables returns the tables and views of a database
+func listTables(ctx context.Context, db *sql.DB, driver string, timeout time.Duration) ([]TableInfo, error) {
+	tx, err := begin(ctx, db, driver, timeout, true)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := sqliteTables
+	if driver == DriverPostgres {
+		query = postgresTables
+	}
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := []TableInfo{}
+	for rows.Next() {
+		var table TableInfo
+		if err := rows.Scan(&table.Name, &table.Type); err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
+
+// describeTable returns the columns of a table, with their primary and
+// foreign keys. The name may be qualified with a schema, or for SQLite with
+// an attached database.
+func describeTable(ctx context.Context, db *sql.DB, driver string, timeout time.Duration, name string) ([]ColumnInfo, error) {
+	schemaName, table := "", name
+	if dot := strings.LastIndexByte(name, '.'); dot >= 0 {
+		schemaName, table = name[:dot], name[dot+1:]
+	}
+	if driver == DriverSQLite && schemaName == "" {
+		schemaName = "main"
+	}
+
+	tx, err := begin(ctx, db, driver, timeout, true)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	columnsQuery, keysQuery := sqliteColumns, sqliteForeignKeys
+	args := []interface{}{table, schemaName}
+	if driver == DriverPostgres {
+		columnsQuery, keysQuery = postgresColumns, postgresKeys
+		args = []interface{}{schemaName, table}
+	}
+
+	rows, err := tx.QueryContext(ctx, columnsQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	columns := []ColumnInfo{}
+	index := make(map[string]int)
+	for rows.Next() {
+		var column ColumnInfo
+		if driver == DriverPostgres {
+			err = rows.Scan(&column.Name, &column.Type, &column.Nullable, &column.Default)
+		} else {
+			var notNull, pk int
+			err = rows.Scan(&column.Name, &column.Type, &notNull, &column.Default, &pk)
+			column.Nullable = notNull == 0 && pk == 0
+			column.PrimaryKey = pk > 0
+		}
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		index[column.Name] = len(columns)
+		columns = append(columns, column)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("table %s not found; use the tables operation to list them", name)
+	}
+
+	rows, err = tx.QueryContext(ctx, keysQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var column, kind, references string
+		if driver == DriverPostgres {
+			err = rows.Scan(&column, &kind, &references)
+		} else {
+			kind = "FOREIGN KEY"
+			err = rows.Scan(&column, &references)
+		}
+		if err != nil {
+			return nil, err
+		}
+		i, ok := index[column]
+		if !ok {
+			continue
+		}
+		if kind == "PRIMARY KEY" {
+			columns[i].PrimaryKey = true
+		} else {
+			columns[i].References = strings.TrimSuffix(references, ".")
+		}
+	}
+	return columns, rows.Err()
+}
@@ -0,0 +1,359 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	// Database drivers: SQLite compiled to WebAssembly, and pgx
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "github.com/ncruces/go-sqlite3/driver"
+	_ "github.com/ncruces/go-sqlite3/embed"
+)
+
+// SQLInput represents the input schema for the SQL tool
+type SQLInput struct {
+	// Connection names the database; it may be left out when only one is
+	// configured
+	Connection string `json:"connection,omitempty"`
+	// Operation is tables, describe or query
+	Operation string `json:"operation"`
+	// Table is the table to describe, optionally with a schema prefix
+	Table string `json:"table,omitempty"`
+	// Query is the SQL statement to run
+	Query string `json:"query,omitempty"`
+	// Params are bound to the query's placeholders
+	Params []interface{} `json:"params,omitempty"`
+}
+
+// Operations of the SQL tool
+const (
+	OpTables   = "tables"
+	OpDescribe = "describe"
+	OpQuery    = "query"
+)
+
+// SQLOutput represents the output schema for the SQL tool
+type SQLOutput struct {
+	Connection string `json:"connection"`
+	// Tables lists the tables and views for the tables operation
+	Tables []TableInfo `json:"tables,omitempty"`
+	// Table and Schema describe a table for the describe operation
+	Table  string       `json:"table,omitempty"`
+	Schema []ColumnInfo `json:"schema,omitempty"`
+	// Result is the result of the query operation
+	*Result
+}
+
+// Result is a query result
+type Result struct {
+	Columns []string        `json:"columns,omitempty"`
+	Rows    [][]interface{} `json:"rows,omitempty"`
+	// RowCount is the number of rows returned
+	RowCount int `json:"rowCount"`
+	// Truncated is set when rows were dropped at the row or byte limit
+	Truncated bool `json:"truncated,omitempty"`
+	// RowsAffected is reported for writes without a RETURNING clause
+	RowsAffected *int64 `json:"rowsAffected,omitempty"`
+}
+
+// TableInfo is a table or view
+type TableInfo struct {
+	Name string `json:"name"`
+	// Type is table or view
+	Type string `json:"type"`
+}
+
+// ColumnInfo describes a column
+type ColumnInfo struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Nullable   bool   `json:"nullable"`
+	Default    string `json:"default,omitempty"`
+	PrimaryKey bool   `json:"primaryKey,omitempty"`
+	// References is the table.column a foreign key points to
+	References string `json:"references,omitempty"`
+}
+
+// database is a configured connection, opened on first use
+type database struct {
+	config  ConnectionConfig
+	timeout time.Duration
+
+	once sync.Once
+	db   *sql.DB
+	err  error
+}
+
+// open returns the connection pool, opening it on first use
+func (d *database) open() (*sql.DB, error) {
+	d.once.Do(func() {
+		dsn := os.ExpandEnv(d.config.DSN)
+		driver := "pgx"
+		if d.config.Driver == DriverSQLite {
+			driver = "sqlite3"
+			dsn, d.err = sqliteDSN(dsn, !d.config.AllowWrites)
+			if d.err != nil {
+				return
+			}
+		}
+		if d.db, d.err = sql.Open(driver, dsn); d.err != nil {
+			return
+		}
+		d.db.SetMaxOpenConns(4)
+		d.db.SetConnMaxIdleTime(5 * time.Minute)
+	})
+	return d.db, d.err
+}
+
+// sqliteDSN turns a path or file: URI into a URI, opened read-only unless
+// writes are allowed, so even the file is protected from changes
+func sqliteDSN(dsn string, readOnly bool) (string, error) {
+	if !strings.HasPrefix(dsn, "file:") {
+		dsn = (&url.URL{Scheme: "file", Path: dsn}).String()
+	}
+	if !readOnly {
+		return dsn, nil
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", fmt.Errorf("invalid SQLite URI: %w", err)
+	}
+	query := u.Query()
+	query.Set("mode", "ro")
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// NewSQLToolWithConfig creates a SQL tool for the configured connections
+func NewSQLToolWithConfig(config Config) (string, string, json.RawMessage, func(context.Context, json.RawMessage) (json.RawMessage, error)) {
+	databases := make(map[string]*database, len(config.Connections))
+	names := make([]string, 0, len(config.Connections))
+	var descriptions []string
+	for _, conn := range config.Connections {
+		timeout := conn.TimeoutSeconds
+		if timeout == 0 {
+			timeout = config.TimeoutSeconds
+		}
+		databases[conn.Name] = &database{config: conn, timeout: time.Duration(timeout) * time.Second}
+		names = append(names, conn.Name)
+
+		mode := "read-only"
+		if conn.AllowWrites {
+			mode = "read-write"
+		}
+		line := fmt.Sprintf("%s (%s, %s)", conn.Name, conn.Driver, mode)
+		if conn.Description != "" {
+			line += ": " + conn.Description
+		}
+		descriptions = append(descriptions, line)
+	}
+
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"connection": map[string]interface{}{
+				"type":        "string",
+				"enum":        names,
+				"description": "The database to use; may be left out when there is only one",
+			},
+			"operation": map[string]interface{}{
+				"type": "string",
+				"enum": []string{OpTables, OpDescribe, OpQuery},
+				"description": "tables lists the tables and views; describe returns the columns, keys and references of table; " +
+					"query runs query",
+			},
+			"table": map[string]interface{}{
+				"type":        "string",
+				"description": "describe: the table name, optionally as schema.table for Postgres",
+			},
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "query: a single SQL statement",
+			},
+			"params": map[string]interface{}{
+				"type":        "array",
+				"description": "query: values for the placeholders, ? for SQLite and $1, $2, ... for Postgres",
+			},
+		},
+		"required": []string{"operation"},
+	}
+
+	schemaJSON, _ := json.Marshal(schema)
+
+	return "sql",
+		"Queries SQL databases. List the tables, describe a table's columns, then run a query; results come back as column names and rows, " +
+			fmt.Sprintf("at most %d rows. Read-only databases only accept SELECT and similar queries. Databases: %s", config.MaxRows, strings.Join(descriptions, "; ")),
+		schemaJSON,
+		func(ctx context.Context, input json.RawMessage) (json.RawMessage, error) {
+			var params SQLInput
+			if err := json.Unmarshal(input, &params); err != nil {
+				return nil, fmt.Errorf("invalid input: %w", err)
+			}
+			if params.Connection == "" && len(names) == 1 {
+				params.Connection = names[0]
+			}
+			d, ok := databases[params.Connection]
+			if !ok {
+				if params.Connection == "" {
+					return nil, fmt.Errorf("connection is required; use one of %s", strings.Join(names, ", "))
+				}
+				return nil, fmt.Errorf("unknown connection %q; use one of %s", params.Connection, strings.Join(names, ", "))
+			}
+			db, err := d.open()
+			if err != nil {
+				return nil, fmt.Errorf("failed to open %s: %w", d.config.Name, err)
+			}
+
+			ctx, cancel := context.WithTimeout(ctx, d.timeout)
+			defer cancel()
+
+			output := &SQLOutput{Connection: d.config.Name}
+			switch strings.ToLower(strings.TrimSpace(params.Operation)) {
+			case OpTables:
+				output.Tables, err = listTables(ctx, db, d.config.Driver, d.timeout)
+			case OpDescribe:
+				if strings.TrimSpace(params.Table) == "" {
+					return nil, fmt.Errorf("the describe operation needs a table")
+				}
+				output.Table = params.Table
+				output.Schema, err = describeTable(ctx, db, d.config.Driver, d.timeout, params.Table)
+			case OpQuery:
+				output.Result, err = runQuery(ctx, db, d, config, params)
+			case "":
+				return nil, fmt.Errorf("operation is required")
+			default:
+				return nil, fmt.Errorf("unknown operation %q", params.Operation)
+			}
+			if err != nil {
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					return nil, fmt.Errorf("query timed out after %s", d.timeout)
+				}
+				return nil, err
+			}
+			return json.Marshal(output)
+		}
+}
+
+// runQuery runs one statement. Reads run in a read-only transaction that is
+// rolled back; writes, where allowed, in a transaction that is committed.
+func runQuery(ctx context.Context, db *sql.DB, d *database, config Config, params SQLInput) (*Result, error) {
+	if strings.TrimSpace(params.Query) == "" {
+		return nil, fmt.Errorf("the query operation needs a query")
+	}
+	stmt, err := classify(params.Query)
+	if err != nil {
+		return nil, err
+	}
+	if !stmt.readOnly && !d.config.AllowWrites {
+		return nil, fmt.Errorf("connection %s is read-only and %s statements may modify data; only SELECT, WITH, VALUES, EXPLAIN, SHOW and TABLE queries are allowed", d.config.Name, stmt.keyword)
+	}
+
+	tx, err := begin(ctx, db, d.config.Driver, d.timeout, stmt.readOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result := &Result{}
+	if !stmt.readOnly && !stmt.returning {
+		execResult, err := tx.ExecContext(ctx, params.Query, params.Params...)
+		if err != nil {
+			return nil, err
+		}
+		if affected, err := execResult.RowsAffected(); err == nil {
+			result.RowsAffected = &affected
+		}
+		return result, tx.Commit()
+	}
+
+	rows, err := tx.QueryContext(ctx, params.Query, params.Params...)
+	if err != nil {
+		return nil, err
+	}
+	if err := collect(rows, config, result); err != nil {
+		return nil, err
+	}
+	if !stmt.readOnly {
+		return result, tx.Commit()
+	}
+	return result, nil
+}
+
+// begin starts a transaction. On Postgres the timeout is also set on the
+// server, so a query is stopped there even if the client goes away.
+func begin(ctx context.Context, db *sql.DB, driver string, timeout time.Duration, readOnly bool) (*sql.Tx, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: readOnly})
+	if err != nil {
+		return nil, err
+	}
+	if driver == DriverPostgres {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", timeout.Milliseconds())); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	return tx, nil
+}
+
+// collect reads rows into output until the row or byte limit is reached
+func collect(rows *sql.Rows, config Config, output *Result) error {
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	output.Columns = columns
+	output.Rows = [][]interface{}{}
+
+	size := 0
+	for rows.Next() {
+		if len(output.Rows) >= config.MaxRows {
+			output.Truncated = true
+			break
+		}
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return err
+		}
+		for i, value := range values {
+			values[i] = jsonValue(value)
+		}
+		encoded, _ := json.Marshal(values)
+		if size+len(encoded) > config.MaxBytes {
+			output.Truncated = true
+			break
+		}
+		size += len(encoded)
+		output.Rows = append(output.Rows, values)
+	}
+	output.RowCount = len(output.Rows)
+	return rows.Err()
+}
+
+// jsonValue converts a scanned value to one that encodes well as JSON
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []byte:
+		if utf8.Valid(v) {
+			return string(v)
+		}
+		return fmt.Sprintf("<%d bytes of binary data>", len(v))
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return v
+	}
+}
@@ -0,0 +1,170 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestDatabase creates a SQLite file with a users table of n rows
+func newTestDatabase(t *testing.T, n int) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL)`); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= n; i++ {
+		if _, err := db.Exec(`INSERT INTO users (name) VALUES (?)`, fmt.Sprintf("user%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+// newTestTool returns the handler of a SQL tool with one connection to path
+func newTestTool(config Config, conn ConnectionConfig) func(context.Context, json.RawMessage) (json.RawMessage, error) {
+	conn.Name = "test"
+	conn.Driver = DriverSQLite
+	config.Connections = []ConnectionConfig{conn}
+	_, _, _, handler := NewSQLToolWithConfig(config)
+	return handler
+}
+
+// runTestQuery runs query through handler and decodes the output
+func runTestQuery(t *testing.T, handler func(context.Context, json.RawMessage) (json.RawMessage, error), query string) (*SQLOutput, error) {
+	t.Helper()
+	input, _ := json.Marshal(SQLInput{Operation: OpQuery, Query: query})
+	raw, err := handler(context.Background(), input)
+	if err != nil {
+		return nil, err
+	}
+	var output SQLOutput
+	if err := json.Unmarshal(raw, &output); err != nil {
+		t.Fatalf("invalid output %s: %v", raw, err)
+	}
+	return &output, nil
+}
+
+func TestReadOnlyRejectsWrites(t *testing.T) {
+	path := newTestDatabase(t, 3)
+	handler := newTestTool(DefaultConfig(), ConnectionConfig{DSN: path})
+
+	for _, query := range []string{
+		"DELETE FROM users",
+		"PRAGMA user_version = 7",
+		"SELECT 1; DELETE FROM users",
+		"WITH gone AS (DELETE FROM users RETURNING *) SELECT * FROM gone",
+	} {
+		if output, err := runTestQuery(t, handler, query); err == nil {
+			t.Errorf("%q was run on a read-only connection: %+v", query, output.Result)
+		}
+	}
+
+	output, err := runTestQuery(t, handler, "SELECT count(*) FROM users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := output.Rows[0][0]; count != float64(3) {
+		t.Errorf("users has %v rows after rejected writes, want 3", count)
+	}
+}
+
+func TestReadOnlyAllowsKeywordsInLiterals(t *testing.T) {
+	path := newTestDatabase(t, 3)
+	handler := newTestTool(DefaultConfig(), ConnectionConfig{DSN: path})
+
+	output, err := runTestQuery(t, handler, "SELECT 'DELETE FROM users; DROP TABLE users' AS note /* ; UPDATE users */ -- INSERT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output.Rows) != 1 || output.Rows[0][0] != "DELETE FROM users; DROP TABLE users" {
+		t.Errorf("rows = %v, want the literal back", output.Rows)
+	}
+}
+
+func TestQueryTruncation(t *testing.T) {
+	path := newTestDatabase(t, 50)
+
+	config := DefaultConfig()
+	config.MaxRows = 10
+	output, err := runTestQuery(t, newTestTool(config, ConnectionConfig{DSN: path}), "SELECT id, name FROM users ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.RowCount != 10 || len(output.Rows) != 10 || !output.Truncated {
+		t.Errorf("maxRows 10: got %d rows, truncated %v; want 10 rows, truncated", len(output.Rows), output.Truncated)
+	}
+
+	// Each row encodes as [1,"user1"] and so on, 11 to 13 bytes
+	config = DefaultConfig()
+	config.MaxBytes = 40
+	output, err = runTestQuery(t, newTestTool(config, ConnectionConfig{DSN: path}), "SELECT id, name FROM users ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.RowCount != 3 || !output.Truncated {
+		t.Errorf("maxBytes 40: got %d rows, truncated %v; want 3 rows, truncated", output.RowCount, output.Truncated)
+	}
+
+	output, err = runTestQuery(t, newTestTool(DefaultConfig(), ConnectionConfig{DSN: path}), "SELECT id FROM users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.RowCount != 50 || output.Truncated {
+		t.Errorf("default limits: got %d rows, truncated %v; want 50 rows, not truncated", output.RowCount, output.Truncated)
+	}
+}
+
+func TestQueryTimeout(t *testing.T) {
+	path := newTestDatabase(t, 1)
+	handler := newTestTool(DefaultConfig(), ConnectionConfig{DSN: path, TimeoutSeconds: 1})
+
+	start := time.Now()
+	_, err := runTestQuery(t, handler, "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c) SELECT count(*) FROM c")
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("endless query returned %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("endless query was stopped after %s, want about 1s", elapsed)
+	}
+}
+
+func TestWritesWithReturning(t *testing.T) {
+	path := newTestDatabase(t, 2)
+	handler := newTestTool(DefaultConfig(), ConnectionConfig{DSN: path, AllowWrites: true})
+
+	output, err := runTestQuery(t, handler, "INSERT INTO users (name) VALUES ('carol') RETURNING id, name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output.Rows) != 1 || output.Rows[0][0] != float64(3) || output.Rows[0][1] != "carol" {
+		t.Errorf("RETURNING rows = %v, want [[3 carol]]", output.Rows)
+	}
+
+	output, err = runTestQuery(t, handler, "UPDATE users SET name = upper(name)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.RowsAffected == nil || *output.RowsAffected != 3 {
+		t.Errorf("rowsAffected = %v, want 3", output.RowsAffected)
+	}
+
+	// Writes are committed
+	output, err = runTestQuery(t, handler, "SELECT name FROM users WHERE id = 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output.Rows) != 1 || output.Rows[0][0] != "CAROL" {
+		t.Errorf("rows = %v, want [[CAROL]]", output.Rows)
+	}
+}
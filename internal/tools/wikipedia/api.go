@@ -0,0 +1,218 @@
+package wikipedia
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// apiClient calls the MediaWiki action API of one configured endpoint
+type apiClient struct {
+	config Config
+	client *http.Client
+}
+
+// wikiPage is a page as returned by prop=extracts|info|pageprops with
+// formatversion=2
+type wikiPage struct {
+	PageID    int    `json:"pageid"`
+	Title     string `json:"title"`
+	Missing   bool   `json:"missing"`
+	Invalid   bool   `json:"invalid"`
+	Extract   string `json:"extract"`
+	FullURL   string `json:"fullurl"`
+	PageProps struct {
+		Disambiguation *string `json:"disambiguation"`
+	} `json:"pageprops"`
+	Links []struct {
+		Title string `json:"title"`
+	} `json:"links"`
+}
+
+// apiError is the error object the API returns with status 200
+type apiError struct {
+	Error *struct {
+		Code string `json:"code"`
+		Info string `json:"info"`
+	} `json:"error"`
+}
+
+// queryResponse is the part of an action=query response the tool reads
+type queryResponse struct {
+	apiError
+	Query struct {
+		Search []struct {
+			Title string `json:"title"`
+		} `json:"search"`
+		Redirects []struct {
+			From string `json:"from"`
+			To   string `json:"to"`
+		} `json:"redirects"`
+		Pages []wikiPage `json:"pages"`
+	} `json:"query"`
+}
+
+// parseResponse is the part of an action=parse response the tool reads
+type parseResponse struct {
+	apiError
+	Parse struct {
+		Title    string `json:"title"`
+		Wikitext string `json:"wikitext"`
+	} `json:"parse"`
+}
+
+// get calls the API with the given parameters and decodes the response
+// into out
+func (c *apiClient) get(ctx context.Context, lang string, params url.Values, out interface{}) error {
+	params.Set("format", "json")
+	params.Set("formatversion", "2")
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.config.endpoint(lang)+"?"+params.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Add("User-Agent", c.config.UserAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("wikipedia API returned status %d", resp.StatusCode)
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// query runs an action=query request with the given parameters
+func (c *apiClient) query(ctx context.Context, lang string, params url.Values) (*queryResponse, error) {
+	params.Set("action", "query")
+	var resp queryResponse
+	if err := c.get(ctx, lang, params, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("wikipedia API error %s: %s", resp.Error.Code, resp.Error.Info)
+	}
+	return &resp, nil
+}
+
+// search returns the titles of the best matching articles
+func (c *apiClient) search(ctx context.Context, lang, query string) ([]string, error) {
+	resp, err := c.query(ctx, lang, url.Values{
+		"list":     {"search"},
+		"srsearch": {query},
+		"srlimit":  {fmt.Sprint(c.config.SearchLimit)},
+		"srprop":   {""},
+	})
+	if err != nil {
+		return nil, err
+	}
+	titles := make([]string, len(resp.Query.Search))
+	for i, result := range resp.Query.Search {
+		titles[i] = result.Title
+	}
+	return titles, nil
+}
+
+// page fetches the introduction of an article, following redirects. The
+// returned string is the title that was redirected from, if any.
+func (c *apiClient) page(ctx context.Context, lang, title string) (*wikiPage, string, error) {
+	resp, err := c.query(ctx, lang, url.Values{
+		"titles":      {title},
+		"redirects":   {"1"},
+		"prop":        {"extracts|info|pageprops"},
+		"exintro":     {"1"},
+		"explaintext": {"1"},
+		"inprop":      {"url"},
+		"ppprop":      {"disambiguation"},
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if len(resp.Query.Pages) == 0 {
+		return nil, "", nil
+	}
+	p := resp.Query.Pages[0]
+	if p.Missing || p.Invalid {
+		return nil, "", nil
+	}
+
+	var redirectedFrom string
+	if len(resp.Query.Redirects) > 0 {
+		redirectedFrom = resp.Query.Redirects[0].From
+	}
+	return &p, redirectedFrom, nil
+}
+
+// links returns the article links of a page, used to list the candidates
+// of a disambiguation page
+func (c *apiClient) links(ctx context.Context, lang, title string) ([]string, error) {
+	resp, err := c.query(ctx, lang, url.Values{
+		"titles":      {title},
+		"prop":        {"links"},
+		"plnamespace": {"0"},
+		"pllimit":     {fmt.Sprint(c.config.MaxCandidates)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var titles []string
+	for _, p := range resp.Query.Pages {
+		for _, link := range p.Links {
+			titles = append(titles, link.Title)
+		}
+	}
+	return titles, nil
+}
+
+// fullText fetches the whole article as plain text, with section headings
+// kept in wikitext form ("== Heading ==") so the text can be split up
+func (c *apiClient) fullText(ctx context.Context, lang, title string) (string, error) {
+	resp, err := c.query(ctx, lang, url.Values{
+		"titles":          {title},
+		"redirects":       {"1"},
+		"prop":            {"extracts"},
+		"explaintext":     {"1"},
+		"exsectionformat": {"wiki"},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Query.Pages) == 0 || resp.Query.Pages[0].Missing {
+		return "", fmt.Errorf("article not found: %s", title)
+	}
+	return resp.Query.Pages[0].Extract, nil
+}
+
+// leadWikitext fetches the wikitext of the lead section, where infoboxes
+// live
+func (c *apiClient) leadWikitext(ctx context.Context, lang, title string) (string, error) {
+	var resp parseResponse
+	err := c.get(ctx, lang, url.Values{
+		"action":    {"parse"},
+		"page":      {title},
+		"redirects": {"1"},
+		"prop":      {"wikitext"},
+		"section":   {"0"},
+	}, &resp)
+	if err != nil {
+		return "", err
+	}
+	if resp.Error != nil {
+		return "", fmt.Errorf("wikipedia API error %s: %s", resp.Error.Code, resp.Error.Info)
+	}
+	return resp.Parse.Wikitext, nil
+}
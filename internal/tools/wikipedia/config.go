@@ -0,0 +1,66 @@
+package wikipedia
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Config holds the settings for the Wikipedia tool
+type Config struct {
+	// BaseURL is the MediaWiki API endpoint. {lang} is replaced by the
+	// requested language, so a single setting serves every Wikipedia; point
+	// it at a local stub to test without network access.
+	BaseURL string `json:"baseURL"`
+	// DefaultLanguage is used when the model does not ask for one
+	DefaultLanguage string `json:"defaultLanguage"`
+	// SearchLimit is the number of search results considered
+	SearchLimit int `json:"searchLimit"`
+	// MaxCandidates caps the titles listed for a disambiguation page
+	MaxCandidates int `json:"maxCandidates"`
+	// MaxChars is the page size, in characters, for full articles and
+	// sections; longer text is paginated by offset
+	MaxChars int `json:"maxChars"`
+	// TimeoutSeconds bounds each API request
+	TimeoutSeconds int `json:"timeoutSeconds"`
+	// UserAgent identifies the agent to the API, as Wikimedia asks clients to
+	UserAgent string `json:"userAgent"`
+}
+
+// DefaultConfig returns the settings for the public Wikipedias
+func DefaultConfig() Config {
+	return Config{
+		BaseURL:         "https://{lang}.wikipedia.org/w/api.php",
+		DefaultLanguage: "en",
+		SearchLimit:     5,
+		MaxCandidates:   20,
+		MaxChars:        8000,
+		TimeoutSeconds:  15,
+		UserAgent:       "Go-Tools-Agent/1.0",
+	}
+}
+
+// languagePattern matches Wikipedia language codes such as en, pt, zh-yue
+// or simple
+var languagePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$|^simple$`)
+
+// Validate checks the endpoint and limits
+func (c Config) Validate() error {
+	u, err := url.Parse(strings.ReplaceAll(c.BaseURL, "{lang}", c.DefaultLanguage))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("baseURL must be an http or https URL")
+	}
+	if !languagePattern.MatchString(c.DefaultLanguage) {
+		return fmt.Errorf("defaultLanguage %q is not a Wikipedia language code", c.DefaultLanguage)
+	}
+	if c.SearchLimit <= 0 || c.MaxCandidates <= 0 || c.MaxChars <= 0 || c.TimeoutSeconds <= 0 {
+		return fmt.Errorf("searchLimit, maxCandidates, maxChars and timeoutSeconds must be positive")
+	}
+	return nil
+}
+
+// endpoint returns the API URL for a language
+func (c Config) endpoint(lang string) string {
+	return strings.ReplaceAll(c.BaseURL, "{lang}", lang)
+}
@@ -0,0 +1,137 @@
+package wikipedia
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	infoboxStart     = regexp.MustCompile(`(?i)\{\{\s*infobox`)
+	commentPattern   = regexp.MustCompile(`(?s)<!--.*?-->`)
+	refPattern       = regexp.MustCompile(`(?is)<ref[^>]*/>|<ref[^>]*>.*?</ref>`)
+	templatePattern  = regexp.MustCompile(`\{\{([^{}]*)\}\}`)
+	filePattern      = regexp.MustCompile(`(?i)\[\[(?:file|image):[^\[\]]*(?:\[\[[^\]]*\]\][^\[\]]*)*\]\]`)
+	linkPattern      = regexp.MustCompile(`\[\[(?:[^|\]]*\|)?([^\]]*)\]\]`)
+	namedExtPattern  = regexp.MustCompile(`\[(?:https?:)?//[^\s\]]+\s+([^\]]*)\]`)
+	bareExtPattern   = regexp.MustCompile(`\[(?:https?:)?//[^\s\]]+\]`)
+	breakPattern     = regexp.MustCompile(`(?i)<br\s*/?>|\n\s*[*#]\s*`)
+	tagPattern       = regexp.MustCompile(`<[^>]+>`)
+	emphasisPattern  = regexp.MustCompile(`'{2,}`)
+	separatorPattern = regexp.MustCompile(`^[,\s]+|[,\s]+$`)
+)
+
+// skippedInfoboxKeys are parameters that only lay out the box
+var skippedInfoboxKeys = map[string]bool{
+	"image": true, "image size": true, "imagesize": true, "image upright": true,
+	"alt": true, "logo": true, "logo size": true, "signature": true,
+	"module": true, "embed": true, "map image": true, "pushpin map": true,
+}
+
+// parseInfobox returns the parameters of the first infobox template in an
+// article's wikitext, with markup reduced to plain text. It returns nil
+// when the article has no infobox.
+func parseInfobox(wikitext string) map[string]string {
+	wikitext = commentPattern.ReplaceAllString(wikitext, "")
+	wikitext = refPattern.ReplaceAllString(wikitext, "")
+
+	loc := infoboxStart.FindStringIndex(wikitext)
+	if loc == nil {
+		return nil
+	}
+	body, ok := templateBody(wikitext[loc[0]:])
+	if !ok {
+		return nil
+	}
+
+	fields := make(map[string]string)
+	for _, param := range splitParams(body)[1:] {
+		key, value, ok := strings.Cut(param, "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.Join(strings.Fields(strings.ReplaceAll(key, "_", " ")), " "))
+		if key == "" || skippedInfoboxKeys[key] {
+			continue
+		}
+		if value = plainText(value); value != "" {
+			fields[key] = value
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+// templateBody returns the text between the braces of the template that
+// starts at the beginning of s
+func templateBody(s string) (string, bool) {
+	depth := 0
+	for i := 0; i+1 < len(s); i++ {
+		switch s[i : i+2] {
+		case "{{":
+			depth++
+			i++
+		case "}}":
+			depth--
+			i++
+			if depth == 0 {
+				return s[2 : i-1], true
+			}
+		}
+	}
+	return "", false
+}
+
+// splitParams splits a template body at the pipes that are not inside a
+// nested template or link
+func splitParams(body string) []string {
+	var params []string
+	depth, start := 0, 0
+	for i := 0; i < len(body); i++ {
+		switch {
+		case strings.HasPrefix(body[i:], "{{"), strings.HasPrefix(body[i:], "[["):
+			depth++
+			i++
+		case strings.HasPrefix(body[i:], "}}"), strings.HasPrefix(body[i:], "]]"):
+			depth--
+			i++
+		case body[i] == '|' && depth == 0:
+			params = append(params, body[start:i])
+			start = i + 1
+		}
+	}
+	return append(params, body[start:])
+}
+
+// plainText reduces a wikitext value to readable text. Nested templates
+// are replaced by their positional arguments, which covers list and date
+// templates well enough for the model.
+func plainText(value string) string {
+	value = filePattern.ReplaceAllString(value, "")
+	for {
+		replaced := templatePattern.ReplaceAllStringFunc(value, func(t string) string {
+			args := splitParams(t[2 : len(t)-2])[1:]
+			var kept []string
+			for _, arg := range args {
+				if arg = strings.TrimSpace(arg); arg != "" && !strings.Contains(arg, "=") {
+					kept = append(kept, arg)
+				}
+			}
+			return strings.Join(kept, ", ")
+		})
+		if replaced == value {
+			break
+		}
+		value = replaced
+	}
+	value = linkPattern.ReplaceAllString(value, "$1")
+	value = namedExtPattern.ReplaceAllString(value, "$1")
+	value = bareExtPattern.ReplaceAllString(value, "")
+	value = breakPattern.ReplaceAllString(value, ", ")
+	value = tagPattern.ReplaceAllString(value, "")
+	value = emphasisPattern.ReplaceAllString(value, "")
+	value = strings.NewReplacer("&nbsp;", " ", "&ndash;", "–", "&mdash;", "—", "&amp;", "&").Replace(value)
+	value = strings.Join(strings.Fields(value), " ")
+	return separatorPattern.ReplaceAllString(value, "")
+}
@@ -0,0 +1,116 @@
+package wikipedia
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Section is a heading of an article
+type Section struct {
+	Title string `json:"title"`
+	// Level is 1 for top-level sections, 2 for their subsections and so on
+	Level int `json:"level"`
+}
+
+// headingPattern matches the "== Heading ==" lines of a plain text extract
+// fetched with exsectionformat=wiki
+var headingPattern = regexp.MustCompile(`(?m)^(={2,6})\s*(.+?)\s*={2,6}\s*$`)
+
+// textSection is a section of an article's plain text
+type textSection struct {
+	Section
+	// start is where the heading line begins, body where the text after it
+	// begins and end where the section, including subsections, ends
+	start, body, end int
+}
+
+// splitSections finds the sections of a plain text extract. The lead, the
+// text before the first heading, is not included.
+func splitSections(text string) []textSection {
+	matches := headingPattern.FindAllStringSubmatchIndex(text, -1)
+	sections := make([]textSection, len(matches))
+	for i, m := range matches {
+		sections[i] = textSection{
+			Section: Section{
+				Title: text[m[4]:m[5]],
+				Level: m[3] - m[2] - 1,
+			},
+			start: m[0],
+			body:  m[1],
+			end:   len(text),
+		}
+	}
+	// A section runs until the next heading at the same or a higher level
+	for i := range sections {
+		for _, next := range sections[i+1:] {
+			if next.Level <= sections[i].Level {
+				sections[i].end = next.start
+				break
+			}
+		}
+	}
+	return sections
+}
+
+// findSection returns the text of the named section, including its
+// subsections. Names match case-insensitively; "lead" or "introduction"
+// selects the text before the first heading.
+func findSection(text, name string) (string, string, error) {
+	sections := splitSections(text)
+	wanted := strings.TrimSpace(name)
+	if strings.EqualFold(wanted, "lead") || strings.EqualFold(wanted, "introduction") {
+		end := len(text)
+		if len(sections) > 0 {
+			end = sections[0].start
+		}
+		return "Introduction", strings.TrimSpace(text[:end]), nil
+	}
+
+	for _, section := range sections {
+		if strings.EqualFold(section.Title, wanted) {
+			return section.Title, strings.TrimSpace(text[section.body:section.end]), nil
+		}
+	}
+	titles := make([]string, len(sections))
+	for i, section := range sections {
+		titles[i] = section.Title
+	}
+	return "", "", fmt.Errorf("section %q not found; available sections: %s", name, strings.Join(titles, ", "))
+}
+
+// sectionList returns the headings of an article in order
+func sectionList(text string) []Section {
+	split := splitSections(text)
+	sections := make([]Section, len(split))
+	for i, section := range split {
+		sections[i] = section.Section
+	}
+	return sections
+}
+
+// paginate returns at most limit characters of text starting at the given
+// character offset, and the offset of the next page or 0 when the text
+// ends within this page
+func paginate(text string, offset, limit int) (string, int, error) {
+	total := utf8.RuneCountInString(text)
+	if offset < 0 || (offset > 0 && offset >= total) {
+		return "", 0, fmt.Errorf("offset %d is outside the text, which has %d characters", offset, total)
+	}
+
+	runes := []rune(text)
+	end := offset + limit
+	if end >= total {
+		return string(runes[offset:]), 0, nil
+	}
+	// Prefer to break at the end of a paragraph or line in the last fifth
+	// of the page so pages do not end mid-sentence
+	for cut := end; cut > offset+limit*4/5; cut-- {
+		if runes[cut-1] == '\n' {
+			end = cut
+			break
+		}
+	}
+	return string(runes[offset:end]), end, nil
+}
@@ -4,44 +4,104 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 // WikipediaInput represents the input schema for the Wikipedia tool
 type WikipediaInput struct {
 	Query string `json:"query"`
+	// Lang is the Wikipedia language code, e.g. en, de or ja
+	Lang string `json:"lang,omitempty"`
+	// Mode selects what to return: summary (default), sections, section
+	// or full
+	Mode string `json:"mode,omitempty"`
+	// Section names the section to return in section mode
+	Section string `json:"section,omitempty"`
+	// Offset is the character offset to continue from in section and full
+	// mode
+	Offset int `json:"offset,omitempty"`
 }
 
+// Modes of the Wikipedia tool
+const (
+	ModeSummary  = "summary"
+	ModeSections = "sections"
+	ModeSection  = "section"
+	ModeFull     = "full"
+)
+
 // WikipediaOutput represents the output schema for the Wikipedia tool
 type WikipediaOutput struct {
-	Title    string `json:"title"`
-	Extract  string `json:"extract"`
-	URL      string `json:"url"`
-	PageID   int    `json:"pageId"`
+	Title   string `json:"title"`
+	Extract string `json:"extract"`
+	URL     string `json:"url"`
+	PageID  int    `json:"pageId"`
+	// Language is the Wikipedia the article was found in
+	Language string `json:"language"`
+	// RedirectedFrom is the title that redirected to this article
+	RedirectedFrom string `json:"redirectedFrom,omitempty"`
+	// Disambiguation is set when the title names several topics; the
+	// model should pick one of Candidates and search again
+	Disambiguation bool     `json:"disambiguation,omitempty"`
+	Candidates     []string `json:"candidates,omitempty"`
+	// OtherResults lists further search matches when the query was not an
+	// exact title
+	OtherResults []string `json:"otherResults,omitempty"`
+	// Infobox holds the fields of the article's infobox, if it has one
+	Infobox map[string]string `json:"infobox,omitempty"`
+	// Sections lists the article's headings in sections mode
+	Sections []Section `json:"sections,omitempty"`
+	// Section is the heading of the text returned in section mode
+	Section string `json:"section,omitempty"`
+	// Offset, NextOffset and TotalChars page through long text in section
+	// and full mode. NextOffset is omitted on the last page.
+	Offset     int `json:"offset,omitempty"`
+	NextOffset int `json:"nextOffset,omitempty"`
+	TotalChars int `json:"totalChars,omitempty"`
 }
 
-// wikipediaAPIResponse represents the Wikipedia API response
-type wikipediaAPIResponse struct {
-	Query struct {
-		Pages map[string]struct {
-			PageID  int    `json:"pageid"`
-			Title   string `json:"title"`
-			Extract string `json:"extract"`
-		} `json:"pages"`
-	} `json:"query"`
+// NewWikipediaTool creates a new Wikipedia search tool for the public
+// Wikipedias
+func NewWikipediaTool() (string, string, json.RawMessage, func(context.Context, json.RawMessage) (json.RawMessage, error)) {
+	return NewWikipediaToolWithConfig(DefaultConfig())
 }
 
-// NewWikipediaTool creates a new Wikipedia search tool
-func NewWikipediaTool() (string, string, json.RawMessage, func(context.Context, json.RawMessage) (json.RawMessage, error)) {
+// NewWikipediaToolWithConfig creates a new Wikipedia search tool that
+// queries the configured API endpoint
+func NewWikipediaToolWithConfig(config Config) (string, string, json.RawMessage, func(context.Context, json.RawMessage) (json.RawMessage, error)) {
+	api := &apiClient{
+		config: config,
+		client: &http.Client{Timeout: time.Duration(config.TimeoutSeconds) * time.Second},
+	}
+
 	schema := map[string]interface{}{
 		"type": "object",
 		"properties": map[string]interface{}{
 			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "An article title or search terms",
+			},
+			"lang": map[string]interface{}{
+				"type":        "string",
+				"description": fmt.Sprintf("Wikipedia language code such as en, de, fr or ja (default %s)", config.DefaultLanguage),
+			},
+			"mode": map[string]interface{}{
 				"type": "string",
-				"description": "The search query for Wikipedia",
+				"enum": []string{ModeSummary, ModeSections, ModeSection, ModeFull},
+				"description": "summary returns the introduction and infobox (default); sections lists the headings; " +
+					"section returns the section named in section; full returns the whole article",
+			},
+			"section": map[string]interface{}{
+				"type":        "string",
+				"description": "Heading of the section to return in section mode, as listed by sections mode",
+			},
+			"offset": map[string]interface{}{
+				"type":        "integer",
+				"description": "Character offset to continue long text from in section and full mode; use nextOffset from the previous result",
 			},
 		},
 		"required": []string{"query"},
@@ -50,63 +110,85 @@ func NewWikipediaTool() (string, string, json.RawMessage, func(context.Context,
 	schemaJSON, _ := json.Marshal(schema)
 
 	return "wikipedia",
-		"Searches Wikipedia for information about a topic",
+		"Searches Wikipedia for information about a topic and returns the introduction and infobox of the best matching article. " +
+			"For details, list the article's sections and fetch one, or read the full article page by page. " +
+			"Ambiguous titles return a list of candidate articles to choose from",
 		schemaJSON,
 		func(ctx context.Context, input json.RawMessage) (json.RawMessage, error) {
 			var params WikipediaInput
 			if err := json.Unmarshal(input, &params); err != nil {
 				return nil, fmt.Errorf("invalid input: %w", err)
 			}
-
-			// Create Wikipedia API URL
-			apiURL := fmt.Sprintf(
-				"https://en.wikipedia.org/w/api.php?action=query&format=json&prop=extracts&exintro=true&explaintext=true&titles=%s",
-				url.QueryEscape(params.Query),
-			)
-
-			// Create request
-			req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create request: %w", err)
+			params.Query = strings.TrimSpace(params.Query)
+			if params.Query == "" {
+				return nil, fmt.Errorf("query is required")
 			}
-
-			// Add headers
-			req.Header.Add("User-Agent", "Go-Tools-Agent/1.0")
-
-			// Execute request
-			client := &http.Client{}
-			resp, err := client.Do(req)
-			if err != nil {
-				return nil, fmt.Errorf("request failed: %w", err)
+			lang := strings.ToLower(strings.TrimSpace(params.Lang))
+			if lang == "" {
+				lang = config.DefaultLanguage
 			}
-			defer resp.Body.Close()
-
-			// Read response body
-			body, err := io.ReadAll(resp.Body)
-			if err != nil {
-				return nil, fmt.Errorf("failed to read response body: %w", err)
+			if !languagePattern.MatchString(lang) {
+				return nil, fmt.Errorf("invalid language code: %s", params.Lang)
 			}
-
-			// Parse response
-			var apiResp wikipediaAPIResponse
-			if err := json.Unmarshal(body, &apiResp); err != nil {
-				return nil, fmt.Errorf("failed to parse response: %w", err)
+			mode := params.Mode
+			switch mode {
+			case "":
+				mode = ModeSummary
+			case ModeSummary, ModeSections, ModeFull:
+			case ModeSection:
+				if strings.TrimSpace(params.Section) == "" {
+					return nil, fmt.Errorf("section mode needs the section name")
+				}
+			default:
+				return nil, fmt.Errorf("unknown mode: %s", params.Mode)
 			}
 
-			// Get first page from response
-			var output WikipediaOutput
-			for _, page := range apiResp.Query.Pages {
-				output = WikipediaOutput{
-					Title:    page.Title,
-					Extract:  strings.TrimSpace(page.Extract),
-					URL:      fmt.Sprintf("https://en.wikipedia.org/?curid=%d", page.PageID),
-					PageID:   page.PageID,
+			// An exact title, or a redirect to one, wins; otherwise fall back
+			// to full text search and take the best match
+			article, redirectedFrom, err := api.page(ctx, lang, params.Query)
+			if err != nil {
+				return nil, err
+			}
+			var others []string
+			if article == nil {
+				titles, err := api.search(ctx, lang, params.Query)
+				if err != nil {
+					return nil, err
 				}
-				break
+				if len(titles) == 0 {
+					return nil, fmt.Errorf("no results found for query: %s", params.Query)
+				}
+				article, redirectedFrom, err = api.page(ctx, lang, titles[0])
+				if err != nil {
+					return nil, err
+				}
+				if article == nil {
+					return nil, fmt.Errorf("no results found for query: %s", params.Query)
+				}
+				others = titles[1:]
 			}
 
-			if output.Title == "" {
-				return nil, fmt.Errorf("no results found for query: %s", params.Query)
+			output := WikipediaOutput{
+				Title:          article.Title,
+				Extract:        strings.TrimSpace(article.Extract),
+				URL:            article.FullURL,
+				PageID:         article.PageID,
+				Language:       lang,
+				RedirectedFrom: redirectedFrom,
+				OtherResults:   others,
+			}
+			if output.URL == "" {
+				output.URL = pageURL(config.endpoint(lang), article.PageID)
+			}
+			if article.PageProps.Disambiguation != nil {
+				candidates, err := api.links(ctx, lang, article.Title)
+				if err != nil {
+					return nil, err
+				}
+				output.Disambiguation = true
+				output.Candidates = candidates
+			} else if err := fillMode(ctx, api, lang, mode, params, &output); err != nil {
+				return nil, err
 			}
 
 			outputJSON, err := json.Marshal(output)
@@ -116,4 +198,55 @@ func NewWikipediaTool() (string, string, json.RawMessage, func(context.Context,
 
 			return outputJSON, nil
 		}
-} 
\ No newline at end of file
+}
+
+// fillMode adds what the requested mode asks for to output, which already
+// holds the article's summary
+func fillMode(ctx context.Context, api *apiClient, lang, mode string, params WikipediaInput, output *WikipediaOutput) error {
+	if mode == ModeSummary || (mode == ModeFull && params.Offset == 0) {
+		// The infobox is a bonus; an article without one, or a wiki
+		// without the parse API, still gets its text
+		if wikitext, err := api.leadWikitext(ctx, lang, output.Title); err == nil {
+			output.Infobox = parseInfobox(wikitext)
+		}
+	}
+	if mode == ModeSummary {
+		return nil
+	}
+
+	text, err := api.fullText(ctx, lang, output.Title)
+	if err != nil {
+		return err
+	}
+	switch mode {
+	case ModeSections:
+		output.Extract = ""
+		output.Sections = sectionList(text)
+		return nil
+	case ModeSection:
+		output.Section, text, err = findSection(text, params.Section)
+		if err != nil {
+			return err
+		}
+	}
+
+	page, next, err := paginate(text, params.Offset, api.config.MaxChars)
+	if err != nil {
+		return err
+	}
+	output.Extract = page
+	output.Offset = params.Offset
+	output.NextOffset = next
+	output.TotalChars = utf8.RuneCountInString(text)
+	return nil
+}
+
+// pageURL links to a page by ID on the wiki serving endpoint, for APIs that
+// do not report full URLs
+func pageURL(endpoint string, pageID int) string {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%s://%s/?curid=%d", u.Scheme, u.Host, pageID)
+}
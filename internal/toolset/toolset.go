@@ -0,0 +1,223 @@
+// Package toolset builds the tools described by the tools configuration,
+// so the CLI, the HTTP server and the MCP server offer the same ones.
+package toolset
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/go-tools-agent/internal/agent"
+	"github.com/go-tools-agent/internal/artifacts"
+	"github.com/go-tools-agent/internal/cache"
+	"github.com/go-tools-agent/internal/config"
+	"github.com/go-tools-agent/internal/mcp"
+	"github.com/go-tools-agent/internal/plugin"
+	calculator "github.com/go-tools-agent/internal/tools/calculator"
+	"github.com/go-tools-agent/internal/tools/code"
+	"github.com/go-tools-agent/internal/tools/data"
+	"github.com/go-tools-agent/internal/tools/datetime"
+	"github.com/go-tools-agent/internal/tools/docsearch"
+	"github.com/go-tools-agent/internal/tools/filesystem"
+	httpTool "github.com/go-tools-agent/internal/tools/http"
+	"github.com/go-tools-agent/internal/tools/openapi"
+	"github.com/go-tools-agent/internal/tools/sql"
+	"github.com/go-tools-agent/internal/tools/wikipedia"
+	"github.com/go-tools-agent/internal/wasm"
+)
+
+// Toolset is the tools built from a configuration
+type Toolset struct {
+	Tools []agent.Tool
+	// Sessions keeps code execution kernels; its EndRun must be registered
+	// as a run or session end hook
+	Sessions *code.SessionManager
+
+	closers []func()
+	// sources records which kind of tool took each name
+	sources map[string]string
+}
+
+// Secrets returns the credentials in the configuration, to be redacted
+// from logs and tool results
+func Secrets(cfg config.ToolsConfig) []string {
+	secrets := append(httpTool.Secrets(cfg.HTTP), mcp.Secrets(cfg.MCP)...)
+	secrets = append(secrets, plugin.Secrets(cfg.Plugins)...)
+	secrets = append(secrets, wasm.Secrets(cfg.WASM)...)
+	secrets = append(secrets, sql.Secrets(cfg.SQL)...)
+	secrets = append(secrets, docsearch.Secrets(cfg.DocSearch)...)
+	return secrets
+}
+
+// Build creates the built-in tools and those from OpenAPI specifications,
+// plugins and WebAssembly modules, with cacheable tools answered from the
+// cache. Files written by code execution are only kept as artifacts when
+// store is set. Tools imported from MCP servers are not included.
+func Build(cfg config.ToolsConfig, store *artifacts.Store) (*Toolset, error) {
+	t := &Toolset{}
+	if err := t.build(cfg, store); err != nil {
+		t.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *Toolset) build(cfg config.ToolsConfig, store *artifacts.Store) error {
+	// Add calculator tool
+	name, desc, schema, handler := calculator.NewCalculatorTool()
+	t.add("built-in", agent.Tool{
+		Name:        name,
+		Description: desc,
+		Schema:      schema,
+		Handler:     handler,
+	})
+
+	// Add datetime tool
+	name, desc, schema, handler = datetime.NewDatetimeToolWithConfig(cfg.Datetime)
+	t.add("built-in", agent.Tool{
+		Name:        name,
+		Description: desc,
+		Schema:      schema,
+		Handler:     handler,
+	})
+
+	// Add data tool for JSON and CSV wrangling
+	name, desc, schema, handler = data.NewDataToolWithConfig(cfg.Data)
+	t.add("built-in", agent.Tool{
+		Name:        name,
+		Description: desc,
+		Schema:      schema,
+		Handler:     handler,
+	})
+
+	// Add HTTP request tool; GET responses are cached as their headers allow
+	name, desc, schema, handler = httpTool.NewHTTPRequestToolWithConfig(cfg.HTTP)
+	t.add("built-in", agent.Tool{
+		Name:        name,
+		Description: desc,
+		Schema:      schema,
+		Handler:     handler,
+		Cacheable:   true,
+		CacheTTL:    httpTool.CacheTTLWithConfig(cfg.HTTP),
+	})
+
+	// OpenAPI and WebAssembly tools send their requests through the HTTP
+	// tool, under its policy and with its credentials
+	sendHTTP := handler
+
+	// Add Wikipedia tool
+	name, desc, schema, handler = wikipedia.NewWikipediaToolWithConfig(cfg.Wikipedia)
+	t.add("built-in", agent.Tool{
+		Name:        name,
+		Description: desc,
+		Schema:      schema,
+		Handler:     handler,
+		Cacheable:   true,
+	})
+
+	// Add code execution tool with persistent sessions scoped to each run
+	t.Sessions = code.NewSessionManager(cfg.Code)
+	t.closers = append(t.closers, t.Sessions.Close)
+	name, desc, schema, handler = code.NewCodeExecutionToolWithConfig(cfg.Code, t.Sessions, store)
+	t.add("built-in", agent.Tool{
+		Name:        name,
+		Description: desc,
+		Schema:      schema,
+		Handler:     handler,
+	})
+
+	// Add filesystem tool when a workspace directory is configured
+	if cfg.Filesystem.Root != "" {
+		name, desc, schema, handler = filesystem.NewFilesystemToolWithConfig(cfg.Filesystem)
+		t.add("built-in", agent.Tool{
+			Name:        name,
+			Description: desc,
+			Schema:      schema,
+			Handler:     handler,
+		})
+	}
+
+	// Add SQL tool when databases are configured
+	if len(cfg.SQL.Connections) > 0 {
+		name, desc, schema, handler = sql.NewSQLToolWithConfig(cfg.SQL)
+		t.add("built-in", agent.Tool{
+			Name:        name,
+			Description: desc,
+			Schema:      schema,
+			Handler:     handler,
+		})
+	}
+
+	// Add document search tool when an index is configured
+	if cfg.DocSearch.Index != "" {
+		embedder, err := docsearch.NewEmbedder(cfg.DocSearch.Embedder)
+		if err != nil {
+			return fmt.Errorf("failed to create document search embedder: %w", err)
+		}
+		name, desc, schema, handler = docsearch.NewDocSearchToolWithConfig(cfg.DocSearch, embedder)
+		t.add("built-in", agent.Tool{
+			Name:        name,
+			Description: desc,
+			Schema:      schema,
+			Handler:     handler,
+		})
+	}
+
+	// Add tools generated from OpenAPI specifications
+	apiTools, err := openapi.NewTools(context.Background(), cfg.OpenAPI, sendHTTP)
+	if err != nil {
+		return fmt.Errorf("failed to load OpenAPI tools: %w", err)
+	}
+	t.add("OpenAPI", apiTools...)
+
+	// Add tools compiled to WebAssembly
+	wasmPlugins, err := wasm.Load(context.Background(), cfg.WASM, wasm.Send(sendHTTP))
+	if err != nil {
+		return fmt.Errorf("failed to load WebAssembly plugins: %w", err)
+	}
+	t.closers = append(t.closers, wasmPlugins.Close)
+	t.add("WebAssembly", wasmPlugins.Tools()...)
+
+	// Add tools provided by plugin executables. They come last, so a plugin
+	// cannot take the name of any other tool
+	plugins, err := plugin.Load(cfg.Plugins)
+	if err != nil {
+		return fmt.Errorf("failed to load plugins: %w", err)
+	}
+	t.closers = append(t.closers, plugins.Close)
+	t.add("plugin", plugins.Tools()...)
+
+	// Answer repeated calls of cacheable tools from the cache
+	toolCache, err := cache.New(cfg.Cache)
+	if err != nil {
+		return fmt.Errorf("failed to create tool cache: %w", err)
+	}
+	for i := range t.Tools {
+		t.Tools[i] = toolCache.Wrap(t.Tools[i])
+	}
+	return nil
+}
+
+// add appends tools whose names are free. A tool never replaces an earlier
+// one: a tool whose name is taken is skipped with a warning.
+func (t *Toolset) add(source string, tools ...agent.Tool) {
+	if t.sources == nil {
+		t.sources = make(map[string]string)
+	}
+	for _, tool := range tools {
+		if other, taken := t.sources[tool.Name]; taken {
+			log.Printf("⚠️  Skipping %s tool %s: the name is already used by the %s tools", source, tool.Name, other)
+			continue
+		}
+		t.sources[tool.Name] = source
+		t.Tools = append(t.Tools, tool)
+	}
+}
+
+// Close stops code sessions, plugin processes and WebAssembly modules
+func (t *Toolset) Close() {
+	for i := len(t.closers) - 1; i >= 0; i-- {
+		t.closers[i]()
+	}
+	t.closers = nil
+}
@@ -0,0 +1,116 @@
+package wasm
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-tools-agent/internal/redact"
+)
+
+// Config holds the settings for WebAssembly plugins
+type Config struct {
+	// Dir is searched for .wasm modules; WebAssembly plugins are disabled
+	// when it is empty
+	Dir string `json:"dir"`
+	// MemoryLimitMB caps the linear memory of each instance
+	MemoryLimitMB int `json:"memoryLimitMB"`
+	// TimeoutSeconds bounds each call. The runtime has no instruction
+	// metering, so a module that runs too long is stopped by this deadline.
+	TimeoutSeconds int `json:"timeoutSeconds"`
+	// MaxOutputBytes caps what a call may write to stdout
+	MaxOutputBytes int `json:"maxOutputBytes"`
+	// MaxConcurrency limits the instances of one module running at once
+	MaxConcurrency int `json:"maxConcurrency"`
+	// Plugins grants access and overrides limits per module, keyed by the
+	// file name without .wasm. Modules not listed get no filesystem, no
+	// network and no environment.
+	Plugins map[string]Grant `json:"plugins,omitempty"`
+}
+
+// Grant is what a single module may access
+type Grant struct {
+	// Mounts exposes host directories to the module
+	Mounts []Mount `json:"mounts,omitempty"`
+	// Network allows HTTP requests through the HTTP tool, under its policy
+	// and with its credentials
+	Network bool `json:"network,omitempty"`
+	// Env sets environment variables; ${NAME} references are expanded
+	Env map[string]string `json:"env,omitempty"`
+	// MemoryLimitMB and TimeoutSeconds override the defaults when set
+	MemoryLimitMB  int `json:"memoryLimitMB,omitempty"`
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+}
+
+// Mount maps a host directory into the module's filesystem. Mounts are
+// read-only unless Writable is set.
+type Mount struct {
+	HostPath  string `json:"hostPath"`
+	GuestPath string `json:"guestPath"`
+	Writable  bool   `json:"writable,omitempty"`
+}
+
+// DefaultConfig returns the default WebAssembly plugin settings, with
+// plugins disabled
+func DefaultConfig() Config {
+	return Config{
+		MemoryLimitMB:  64,
+		TimeoutSeconds: 10,
+		MaxOutputBytes: 1 << 20,
+		MaxConcurrency: 4,
+	}
+}
+
+// maxMemoryMB is the most a 32-bit module can address
+const maxMemoryMB = 4096
+
+// Validate checks the limits and grants
+func (c Config) Validate() error {
+	if c.MemoryLimitMB <= 0 || c.MemoryLimitMB > maxMemoryMB {
+		return fmt.Errorf("memoryLimitMB must be between 1 and %d", maxMemoryMB)
+	}
+	if c.TimeoutSeconds <= 0 {
+		return fmt.Errorf("timeoutSeconds must be positive")
+	}
+	if c.MaxOutputBytes <= 0 || c.MaxConcurrency <= 0 {
+		return fmt.Errorf("maxOutputBytes and maxConcurrency must be positive")
+	}
+	for name, grant := range c.Plugins {
+		if grant.MemoryLimitMB < 0 || grant.MemoryLimitMB > maxMemoryMB || grant.TimeoutSeconds < 0 {
+			return fmt.Errorf("plugin %s: invalid memoryLimitMB or timeoutSeconds", name)
+		}
+		for _, mount := range grant.Mounts {
+			if !filepath.IsAbs(mount.HostPath) {
+				return fmt.Errorf("plugin %s: hostPath %q must be absolute", name, mount.HostPath)
+			}
+			if !strings.HasPrefix(mount.GuestPath, "/") {
+				return fmt.Errorf("plugin %s: guestPath %q must be absolute", name, mount.GuestPath)
+			}
+		}
+	}
+	return nil
+}
+
+// grant returns the grant for a module, with the limits filled in
+func (c Config) grant(name string) Grant {
+	grant := c.Plugins[name]
+	if grant.MemoryLimitMB == 0 {
+		grant.MemoryLimitMB = c.MemoryLimitMB
+	}
+	if grant.TimeoutSeconds == 0 {
+		grant.TimeoutSeconds = c.TimeoutSeconds
+	}
+	return grant
+}
+
+// Secrets returns the values the grants pass to modules from the agent's
+// environment through $NAME or ${NAME} references in env
+func Secrets(config Config) []string {
+	var secrets []string
+	for _, grant := range config.Plugins {
+		for _, value := range grant.Env {
+			secrets = append(secrets, redact.EnvValues(value)...)
+		}
+	}
+	return secrets
+}
@@ -0,0 +1,185 @@
+package wasm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-tools-agent/internal/agent"
+	"github.com/go-tools-agent/internal/plugin"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// Set is the WebAssembly plugins found in the configured directory
+type Set struct {
+	plugins []*Plugin
+	cache   wazero.CompilationCache
+}
+
+// Load compiles every .wasm module in config.Dir and returns the valid
+// plugins. Modules that fail to compile, lack a manifest, need access they
+// were not granted, or whose tool name is taken are skipped with a warning.
+// Network requests of granted modules are made with send. A nil set is
+// returned when WebAssembly plugins are disabled.
+func Load(ctx context.Context, config Config, send Send) (*Set, error) {
+	if config.Dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(config.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read WebAssembly plugin directory: %w", err)
+	}
+
+	set := &Set{cache: wazero.NewCompilationCache()}
+	names := make(map[string]string)
+	found := make(map[string]bool)
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") || filepath.Ext(entry.Name()) != ".wasm" {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), ".wasm")
+		found[name] = true
+
+		p, err := load(ctx, filepath.Join(config.Dir, entry.Name()), name, config, send, set.cache)
+		if err != nil {
+			log.Printf("⚠️  Skipping WebAssembly plugin %s: %v", entry.Name(), err)
+			continue
+		}
+		if other, taken := names[p.manifest.Name]; taken {
+			log.Printf("⚠️  Skipping WebAssembly plugin %s: tool %s is already provided by %s", entry.Name(), p.manifest.Name, other)
+			p.runtime.Close(ctx)
+			continue
+		}
+		names[p.manifest.Name] = entry.Name()
+		set.plugins = append(set.plugins, p)
+	}
+	for name := range config.Plugins {
+		if !found[name] {
+			log.Printf("⚠️  WebAssembly plugin %s is configured but %s.wasm was not found", name, name)
+		}
+	}
+
+	sort.Slice(set.plugins, func(i, j int) bool { return set.plugins[i].manifest.Name < set.plugins[j].manifest.Name })
+	log.Printf("🧩 Loaded %d WebAssembly plugins from %s", len(set.plugins), config.Dir)
+	return set, nil
+}
+
+// load compiles one module in a runtime of its own, so it gets its own
+// memory limit and only the host functions it was granted
+func load(ctx context.Context, path, name string, config Config, send Send, cache wazero.CompilationCache) (_ *Plugin, err error) {
+	grant := config.grant(name)
+	for _, mount := range grant.Mounts {
+		if info, err := os.Stat(mount.HostPath); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("mount %s is not a directory", mount.HostPath)
+		}
+	}
+	binary, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	runtimeConfig := wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(uint32(grant.MemoryLimitMB) * 16). // 64 KiB pages
+		WithCloseOnContextDone(true).
+		WithCompilationCache(cache).
+		WithCustomSections(true)
+	runtime := wazero.NewRuntimeWithConfig(ctx, runtimeConfig)
+	defer func() {
+		if err != nil {
+			runtime.Close(ctx)
+		}
+	}()
+
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		return nil, err
+	}
+	compiled, err := runtime.CompileModule(ctx, binary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile: %w", err)
+	}
+	for _, fn := range compiled.ImportedFunctions() {
+		module, function, _ := fn.Import()
+		if module == hostModule && !grant.Network {
+			return nil, fmt.Errorf("imports %s.%s but network access is not granted", module, function)
+		}
+	}
+	if grant.Network {
+		if err := instantiateHost(ctx, runtime, send); err != nil {
+			return nil, err
+		}
+	}
+
+	manifest, err := readManifest(path, compiled)
+	if err != nil {
+		return nil, err
+	}
+	return &Plugin{
+		name:     name,
+		manifest: manifest,
+		grant:    grant,
+		config:   config,
+		runtime:  runtime,
+		compiled: compiled,
+		slots:    make(chan struct{}, config.MaxConcurrency),
+	}, nil
+}
+
+// readManifest reads the sidecar manifest or, failing that, the embedded one
+func readManifest(path string, compiled wazero.CompiledModule) (plugin.Manifest, error) {
+	var content []byte
+	sidecar := strings.TrimSuffix(path, ".wasm") + ".json"
+	if data, err := os.ReadFile(sidecar); err == nil {
+		content = data
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return plugin.Manifest{}, fmt.Errorf("failed to read manifest: %w", err)
+	} else {
+		for _, section := range compiled.CustomSections() {
+			if section.Name() == manifestSection {
+				content = section.Data()
+			}
+		}
+	}
+	if content == nil {
+		return plugin.Manifest{}, fmt.Errorf("no %s file and no %s custom section", filepath.Base(sidecar), manifestSection)
+	}
+
+	var manifest plugin.Manifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return plugin.Manifest{}, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if err := manifest.Validate(); err != nil {
+		return plugin.Manifest{}, err
+	}
+	return manifest, nil
+}
+
+// Tools returns a tool for every plugin
+func (s *Set) Tools() []agent.Tool {
+	if s == nil {
+		return nil
+	}
+	tools := make([]agent.Tool, len(s.plugins))
+	for i, p := range s.plugins {
+		tools[i] = p.Tool()
+	}
+	return tools
+}
+
+// Close releases the compiled modules
+func (s *Set) Close() {
+	if s == nil {
+		return
+	}
+	ctx := context.Background()
+	for _, p := range s.plugins {
+		p.runtime.Close(ctx)
+	}
+	s.cache.Close(ctx)
+}
@@ -0,0 +1,208 @@
+// Package wasm runs tools compiled to WebAssembly, using a pure-Go runtime.
+//
+// A plugin is a WASI command module. Each call runs a fresh instance: the
+// tool input is written to stdin as JSON, and the module writes its JSON
+// output to stdout and exits. A non-zero exit code fails the call, with
+// stderr in the error message.
+//
+// The manifest, in the format executable plugins print for --describe, is
+// read from a sidecar file next to the module (weather.wasm, weather.json)
+// or from a custom section named tool_manifest embedded in the module. The
+// sidecar takes precedence, so descriptions can be changed without a
+// rebuild.
+//
+// Modules have no filesystem, network or environment unless granted in the
+// configuration. A module granted network access may import two functions
+// from the go_tools_agent module:
+//
+//	http_request(ptr, len u32) u32   sends an HTTP tool request and returns
+//	                                 the length of the JSON response
+//	http_response(ptr u32)           copies that response to ptr
+package wasm
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/go-tools-agent/internal/agent"
+	"github.com/go-tools-agent/internal/capped"
+	"github.com/go-tools-agent/internal/plugin"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// hostModule is the import module providing network access
+const hostModule = "go_tools_agent"
+
+// manifestSection is the custom section holding an embedded manifest
+const manifestSection = "tool_manifest"
+
+// Send sends a request through the HTTP request tool
+type Send func(ctx context.Context, input json.RawMessage) (json.RawMessage, error)
+
+// Plugin is one compiled module. Calls may run concurrently, each in its
+// own instance.
+type Plugin struct {
+	name     string
+	manifest plugin.Manifest
+	grant    Grant
+	config   Config
+
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+	slots    chan struct{}
+}
+
+// Tool returns the plugin as an agent tool
+func (p *Plugin) Tool() agent.Tool {
+	return agent.Tool{
+		Name:        p.manifest.Name,
+		Description: p.manifest.Description,
+		Schema:      p.manifest.Schema,
+		Handler:     p.call,
+		Cacheable:   p.manifest.Cacheable,
+	}
+}
+
+// callState is the per-call state host functions reach through the context
+type callState struct {
+	pending []byte
+}
+
+type callStateKey struct{}
+
+// call runs the module once with input on stdin
+func (p *Plugin) call(ctx context.Context, input json.RawMessage) (json.RawMessage, error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-p.slots }()
+
+	if len(input) == 0 {
+		input = json.RawMessage("{}")
+	}
+	timeout := time.Duration(p.grant.TimeoutSeconds) * time.Second
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	callCtx = context.WithValue(callCtx, callStateKey{}, &callState{})
+
+	stdout := capped.NewBuffer(p.config.MaxOutputBytes)
+	stderr := capped.NewBuffer(4 << 10)
+	moduleConfig := wazero.NewModuleConfig().
+		WithName("").
+		WithArgs(p.name).
+		WithStdin(bytes.NewReader(input)).
+		WithStdout(stdout).
+		WithStderr(stderr).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithRandSource(rand.Reader)
+	for key, value := range p.grant.Env {
+		moduleConfig = moduleConfig.WithEnv(key, os.ExpandEnv(value))
+	}
+	if runID := agent.RunIDFromContext(ctx); runID != "" {
+		moduleConfig = moduleConfig.WithEnv("RUN_ID", runID)
+	}
+	if len(p.grant.Mounts) > 0 {
+		fsConfig := wazero.NewFSConfig()
+		for _, mount := range p.grant.Mounts {
+			if mount.Writable {
+				fsConfig = fsConfig.WithDirMount(mount.HostPath, mount.GuestPath)
+			} else {
+				fsConfig = fsConfig.WithReadOnlyDirMount(mount.HostPath, mount.GuestPath)
+			}
+		}
+		moduleConfig = moduleConfig.WithFSConfig(fsConfig)
+	}
+
+	mod, err := p.runtime.InstantiateModule(callCtx, p.compiled, moduleConfig)
+	if mod != nil {
+		mod.Close(context.Background())
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if callCtx.Err() != nil {
+			return nil, fmt.Errorf("plugin %s timed out after %s", p.manifest.Name, timeout)
+		}
+		var exitErr *sys.ExitError
+		if errors.As(err, &exitErr) {
+			if message := bytes.TrimSpace(stderr.Bytes()); len(message) > 0 {
+				return nil, fmt.Errorf("plugin %s exited with code %d: %s", p.manifest.Name, exitErr.ExitCode(), message)
+			}
+			return nil, fmt.Errorf("plugin %s exited with code %d", p.manifest.Name, exitErr.ExitCode())
+		}
+		return nil, fmt.Errorf("plugin %s failed: %w", p.manifest.Name, err)
+	}
+
+	if message := bytes.TrimSpace(stderr.Bytes()); len(message) > 0 {
+		log.Printf("🧩 %s: %s", p.manifest.Name, message)
+	}
+	if stdout.Truncated() {
+		return nil, fmt.Errorf("plugin %s output exceeds %d bytes", p.manifest.Name, p.config.MaxOutputBytes)
+	}
+	output := bytes.TrimSpace(stdout.Bytes())
+	if len(output) == 0 {
+		return nil, fmt.Errorf("plugin %s produced no output", p.manifest.Name)
+	}
+	if !json.Valid(output) {
+		return nil, fmt.Errorf("plugin %s wrote invalid JSON to stdout", p.manifest.Name)
+	}
+	return json.RawMessage(output), nil
+}
+
+// instantiateHost adds the network functions to a runtime. Requests are
+// made with the context of the tool call, so cancellation and the run ID
+// carry over, and the HTTP tool's response size limit applies.
+func instantiateHost(ctx context.Context, runtime wazero.Runtime, send Send) error {
+	_, err := runtime.NewHostModuleBuilder(hostModule).
+		NewFunctionBuilder().
+		WithFunc(func(ctx context.Context, mod api.Module, ptr, length uint32) uint32 {
+			state, _ := ctx.Value(callStateKey{}).(*callState)
+			if state == nil {
+				return 0
+			}
+			request, ok := mod.Memory().Read(ptr, length)
+			if !ok {
+				state.pending = errorJSON("request is outside the module's memory")
+				return uint32(len(state.pending))
+			}
+			response, err := send(ctx, append(json.RawMessage(nil), request...))
+			if err != nil {
+				response = errorJSON(err.Error())
+			}
+			state.pending = response
+			return uint32(len(state.pending))
+		}).
+		Export("http_request").
+		NewFunctionBuilder().
+		WithFunc(func(ctx context.Context, mod api.Module, ptr uint32) {
+			state, _ := ctx.Value(callStateKey{}).(*callState)
+			if state == nil {
+				return
+			}
+			if !mod.Memory().Write(ptr, state.pending) {
+				panic(fmt.Errorf("http_response: buffer is outside the module's memory"))
+			}
+			state.pending = nil
+		}).
+		Export("http_response").
+		Instantiate(ctx)
+	return err
+}
+
+func errorJSON(message string) []byte {
+	content, _ := json.Marshal(map[string]string{"error": message})
+	return content
+}